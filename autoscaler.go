@@ -0,0 +1,159 @@
+package kubemap
+
+import (
+	"fmt"
+
+	autoscaling_v1 "k8s.io/api/autoscaling/v1"
+	autoscaling_v2beta2 "k8s.io/api/autoscaling/v2beta2"
+	core_v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+//autoscalerKind attaches HorizontalPodAutoscalers to group of their scale target.
+//autoscaling/v1 and autoscaling/v2beta2 objects are accepted and attached as autoscaling/v2beta2. autoscaling/v2 has
+//no type of its own in client of this module, so it is accepted as unstructured object of informers of that version.
+var autoscalerKind = relatedKind{
+	normalize: normalizeAutoscaler,
+	attach:    attachAutoscalers,
+	attached: func(mappedResource MappedResource) []interface{} {
+		var objects []interface{}
+		for i := range mappedResource.Kube.HorizontalPodAutoscalers {
+			objects = append(objects, mappedResource.Kube.HorizontalPodAutoscalers[i].DeepCopy())
+		}
+		return objects
+	},
+}
+
+func normalizeAutoscaler(obj interface{}) (interface{}, error) {
+	switch hpa := obj.(type) {
+	case *autoscaling_v2beta2.HorizontalPodAutoscaler:
+		return hpa.DeepCopy(), nil
+	case *autoscaling_v1.HorizontalPodAutoscaler:
+		return convertAutoscalerV1(hpa), nil
+	case *unstructured.Unstructured:
+		switch hpa.GetAPIVersion() {
+		case "autoscaling/v2", "autoscaling/v2beta2":
+			var converted autoscaling_v2beta2.HorizontalPodAutoscaler
+			if err := fromUnstructured(hpa, &converted); err != nil {
+				return nil, err
+			}
+			return &converted, nil
+		case "autoscaling/v1":
+			var converted autoscaling_v1.HorizontalPodAutoscaler
+			if err := fromUnstructured(hpa, &converted); err != nil {
+				return nil, err
+			}
+			return convertAutoscalerV1(&converted), nil
+		}
+		return nil, fmt.Errorf("HorizontalPodAutoscaler of %s is not supported, only autoscaling/v2, autoscaling/v2beta2 and autoscaling/v1 are", hpa.GetAPIVersion())
+	}
+
+	return nil, fmt.Errorf("Object of type %T is not a HorizontalPodAutoscaler", obj)
+}
+
+//attachAutoscalers attaches autoscalers whose scale target is a deployment, replica set or stateful set of mapped
+//resource. Stateful sets are not mapped themselves, so those are recognised by owner references of pods.
+func attachAutoscalers(mappedResource *MappedResource, related *relatedObjects) {
+	mappedResource.Kube.HorizontalPodAutoscalers = nil
+
+	for _, obj := range related.list("hpa", mappedResource.Namespace) {
+		hpa := obj.(*autoscaling_v2beta2.HorizontalPodAutoscaler)
+		if autoscalerTargets(*hpa, *mappedResource) {
+			mappedResource.Kube.HorizontalPodAutoscalers = append(mappedResource.Kube.HorizontalPodAutoscalers, *hpa.DeepCopy())
+		}
+	}
+}
+
+func autoscalerTargets(hpa autoscaling_v2beta2.HorizontalPodAutoscaler, mappedResource MappedResource) bool {
+	target := hpa.Spec.ScaleTargetRef
+
+	switch target.Kind {
+	case "Deployment":
+		for _, deployment := range mappedResource.Kube.Deployments {
+			if deployment.Name == target.Name {
+				return true
+			}
+		}
+	case "ReplicaSet":
+		for _, replicaSet := range mappedResource.Kube.ReplicaSets {
+			if replicaSet.Name == target.Name {
+				return true
+			}
+		}
+	case "StatefulSet":
+		for _, pod := range mappedResource.Kube.Pods {
+			for _, owner := range pod.OwnerReferences {
+				if owner.Kind == "StatefulSet" && owner.Name == target.Name {
+					return true
+				}
+			}
+		}
+	}
+
+	return false
+}
+
+//convertAutoscalerV1 converts autoscaling/v1 HorizontalPodAutoscaler to autoscaling/v2beta2.
+//CPU utilization target and current CPU utilization become resource metrics.
+func convertAutoscalerV1(hpa *autoscaling_v1.HorizontalPodAutoscaler) *autoscaling_v2beta2.HorizontalPodAutoscaler {
+	converted := &autoscaling_v2beta2.HorizontalPodAutoscaler{
+		TypeMeta:   hpa.TypeMeta,
+		ObjectMeta: *hpa.ObjectMeta.DeepCopy(),
+		Spec: autoscaling_v2beta2.HorizontalPodAutoscalerSpec{
+			ScaleTargetRef: autoscaling_v2beta2.CrossVersionObjectReference{
+				Kind:       hpa.Spec.ScaleTargetRef.Kind,
+				Name:       hpa.Spec.ScaleTargetRef.Name,
+				APIVersion: hpa.Spec.ScaleTargetRef.APIVersion,
+			},
+			MaxReplicas: hpa.Spec.MaxReplicas,
+		},
+		Status: autoscaling_v2beta2.HorizontalPodAutoscalerStatus{
+			CurrentReplicas: hpa.Status.CurrentReplicas,
+			DesiredReplicas: hpa.Status.DesiredReplicas,
+		},
+	}
+	converted.APIVersion = autoscaling_v2beta2.SchemeGroupVersion.String()
+
+	if hpa.Spec.MinReplicas != nil {
+		minReplicas := *hpa.Spec.MinReplicas
+		converted.Spec.MinReplicas = &minReplicas
+	}
+
+	if hpa.Spec.TargetCPUUtilizationPercentage != nil {
+		targetUtilization := *hpa.Spec.TargetCPUUtilizationPercentage
+		converted.Spec.Metrics = []autoscaling_v2beta2.MetricSpec{{
+			Type: autoscaling_v2beta2.ResourceMetricSourceType,
+			Resource: &autoscaling_v2beta2.ResourceMetricSource{
+				Name: core_v1.ResourceCPU,
+				Target: autoscaling_v2beta2.MetricTarget{
+					Type:               autoscaling_v2beta2.UtilizationMetricType,
+					AverageUtilization: &targetUtilization,
+				},
+			},
+		}}
+	}
+
+	if hpa.Status.ObservedGeneration != nil {
+		observedGeneration := *hpa.Status.ObservedGeneration
+		converted.Status.ObservedGeneration = &observedGeneration
+	}
+
+	if hpa.Status.LastScaleTime != nil {
+		converted.Status.LastScaleTime = hpa.Status.LastScaleTime.DeepCopy()
+	}
+
+	if hpa.Status.CurrentCPUUtilizationPercentage != nil {
+		currentUtilization := *hpa.Status.CurrentCPUUtilizationPercentage
+		converted.Status.CurrentMetrics = []autoscaling_v2beta2.MetricStatus{{
+			Type: autoscaling_v2beta2.ResourceMetricSourceType,
+			Resource: &autoscaling_v2beta2.ResourceMetricStatus{
+				Name: core_v1.ResourceCPU,
+				Current: autoscaling_v2beta2.MetricValueStatus{
+					AverageUtilization: &currentUtilization,
+				},
+			},
+		}}
+	}
+
+	return converted
+}
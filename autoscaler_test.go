@@ -0,0 +1,235 @@
+package kubemap
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	autoscaling_v1 "k8s.io/api/autoscaling/v1"
+	autoscaling_v2beta2 "k8s.io/api/autoscaling/v2beta2"
+	core_v1 "k8s.io/api/core/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func TestAutoscalerArrivingBeforeTarget(t *testing.T) {
+	resources := helperGetK8sResources()
+	hpa := helperGetAutoscaler(resources.Deployments[0].Namespace, resources.Deployments[0].Name, "Deployment")
+
+	mapper := NewMapper()
+
+	results, err := mapper.StoreMap(gerResourceEvent(hpa.DeepCopy(), "hpa"))
+	assert.Nil(t, err)
+	assert.False(t, results[0].IsMapped)
+
+	for _, event := range resourceEventsForMapping(resources) {
+		_, err := mapper.StoreMap(event)
+		assert.Nil(t, err)
+	}
+
+	mappedResources := getAllMappedResources(mapper.store)
+	assert.Len(t, mappedResources.MappedResource, 1)
+	assert.Len(t, mappedResources.MappedResource[0].Kube.HorizontalPodAutoscalers, 1)
+	assert.Equal(t, int32(3), mappedResources.MappedResource[0].Kube.HorizontalPodAutoscalers[0].Status.DesiredReplicas)
+}
+
+func TestAutoscalerArrivingAfterTarget(t *testing.T) {
+	resources := helperGetK8sResources()
+	hpa := helperGetAutoscaler(resources.Deployments[0].Namespace, resources.Deployments[0].Name, "Deployment")
+
+	mapper := NewMapper()
+	for _, event := range resourceEventsForMapping(resources) {
+		_, err := mapper.StoreMap(event)
+		assert.Nil(t, err)
+	}
+
+	results, err := mapper.StoreMap(gerResourceEvent(hpa.DeepCopy(), "hpa"))
+	assert.Nil(t, err)
+	assert.Len(t, results, 1)
+	assert.Equal(t, "Updated", results[0].Action)
+	assert.True(t, results[0].IsMapped)
+	assert.Equal(t, hpa.Name, results[0].MappedResource.Kube.HorizontalPodAutoscalers[0].Name)
+
+	results, err = mapper.StoreMap(ResourceEvent{
+		EventType:    "DELETED",
+		ResourceType: "hpa",
+		Namespace:    hpa.Namespace,
+		Name:         hpa.Name,
+		Key:          fmt.Sprintf("%s/%s", hpa.Namespace, hpa.Name),
+	})
+	assert.Nil(t, err)
+	assert.True(t, results[0].IsMapped)
+	assert.Empty(t, results[0].MappedResource.Kube.HorizontalPodAutoscalers)
+	assert.Empty(t, getAllMappedResources(mapper.store).MappedResource[0].Kube.HorizontalPodAutoscalers)
+}
+
+func TestAutoscalerV1IsConverted(t *testing.T) {
+	resources := helperGetK8sResources()
+	targetUtilization, currentUtilization := int32(70), int32(55)
+	resources.HorizontalPodAutoscalersV1 = []autoscaling_v1.HorizontalPodAutoscaler{{
+		ObjectMeta: meta_v1.ObjectMeta{Name: "v1-hpa", Namespace: resources.Deployments[0].Namespace},
+		Spec: autoscaling_v1.HorizontalPodAutoscalerSpec{
+			ScaleTargetRef:                 autoscaling_v1.CrossVersionObjectReference{Kind: "Deployment", Name: resources.Deployments[0].Name},
+			MaxReplicas:                    5,
+			TargetCPUUtilizationPercentage: &targetUtilization,
+		},
+		Status: autoscaling_v1.HorizontalPodAutoscalerStatus{
+			CurrentReplicas:                 2,
+			DesiredReplicas:                 2,
+			CurrentCPUUtilizationPercentage: &currentUtilization,
+		},
+	}}
+
+	mappedResources, err := NewMapper().Map(resources)
+	assert.Nil(t, err)
+
+	hpas := mappedResources.MappedResource[0].Kube.HorizontalPodAutoscalers
+	assert.Len(t, hpas, 1)
+	assert.Equal(t, int32(5), hpas[0].Spec.MaxReplicas)
+	assert.Equal(t, core_v1.ResourceCPU, hpas[0].Spec.Metrics[0].Resource.Name)
+	assert.Equal(t, int32(70), *hpas[0].Spec.Metrics[0].Resource.Target.AverageUtilization)
+	assert.Equal(t, int32(55), *hpas[0].Status.CurrentMetrics[0].Resource.Current.AverageUtilization)
+	assert.Equal(t, int32(2), hpas[0].Status.CurrentReplicas)
+}
+
+func TestAutoscalerV2IsAcceptedAsUnstructured(t *testing.T) {
+	resources := helperGetK8sResources()
+	hpa := helperGetAutoscaler(resources.Deployments[0].Namespace, resources.Deployments[0].Name, "Deployment")
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&hpa)
+	assert.Nil(t, err)
+	object := &unstructured.Unstructured{Object: content}
+	object.SetAPIVersion("autoscaling/v2")
+	object.SetKind("HorizontalPodAutoscaler")
+
+	mapper := NewMapper()
+	for _, event := range resourceEventsForMapping(resources) {
+		_, err := mapper.StoreMap(event)
+		assert.Nil(t, err)
+	}
+
+	results, err := mapper.StoreMap(gerResourceEvent(object, "hpa"))
+	assert.Nil(t, err)
+	assert.Len(t, results, 1)
+	assert.True(t, results[0].IsMapped)
+	hpas := results[0].MappedResource.Kube.HorizontalPodAutoscalers
+	assert.Len(t, hpas, 1)
+	assert.Equal(t, hpa.Name, hpas[0].Name)
+	assert.Equal(t, int32(80), *hpas[0].Spec.Metrics[0].Resource.Target.AverageUtilization)
+
+	object.SetAPIVersion("autoscaling/v2beta1")
+	_, err = normalizeAutoscaler(object)
+	assert.NotNil(t, err)
+}
+
+func TestAutoscalerOfUnmappedTarget(t *testing.T) {
+	resources := helperGetK8sResources()
+	resources.HorizontalPodAutoscalers = []autoscaling_v2beta2.HorizontalPodAutoscaler{
+		helperGetAutoscaler(resources.Deployments[0].Namespace, "database", "StatefulSet"),
+	}
+
+	mappedResources, err := NewMapper().Map(resources)
+	assert.Nil(t, err)
+	assert.Len(t, mappedResources.MappedResource, 1)
+	assert.Empty(t, mappedResources.MappedResource[0].Kube.HorizontalPodAutoscalers)
+}
+
+func TestAutoscalerOfStatefulSet(t *testing.T) {
+	resources := helperGetK8sResources()
+	resources.Pods[0].OwnerReferences = []meta_v1.OwnerReference{{APIVersion: "apps/v1", Kind: "StatefulSet", Name: "database"}}
+	hpa := helperGetAutoscaler(resources.Deployments[0].Namespace, "database", "StatefulSet")
+
+	mapper := NewMapper()
+	for _, event := range resourceEventsForMapping(resources) {
+		_, err := mapper.StoreMap(event)
+		assert.Nil(t, err)
+	}
+
+	results, err := mapper.StoreMap(gerResourceEvent(hpa.DeepCopy(), "hpa"))
+	assert.Nil(t, err)
+	assert.Len(t, results, 1)
+	assert.True(t, results[0].IsMapped)
+	assert.Len(t, results[0].MappedResource.Kube.HorizontalPodAutoscalers, 1)
+	assert.Equal(t, hpa.Name, results[0].MappedResource.Kube.HorizontalPodAutoscalers[0].Name)
+
+	//Autoscaler of another stateful set is not attached.
+	other := helperGetAutoscaler(resources.Deployments[0].Namespace, "cache", "StatefulSet")
+	results, err = mapper.StoreMap(gerResourceEvent(other.DeepCopy(), "hpa"))
+	assert.Nil(t, err)
+	assert.False(t, results[0].IsMapped)
+}
+
+func TestAutoscalerConsistency(t *testing.T) {
+	random := rand.New(rand.NewSource(35))
+
+	for i := 0; i < 30; i++ {
+		resources := helperGenerateTopology(random, 1+random.Intn(3))
+		for _, deployment := range resources.Deployments {
+			resources.HorizontalPodAutoscalers = append(resources.HorizontalPodAutoscalers, helperGetAutoscaler(deployment.Namespace, deployment.Name, "Deployment"))
+		}
+
+		events := resourceEventsForMapping(resources)
+		random.Shuffle(len(events), func(a, b int) {
+			events[a], events[b] = events[b], events[a]
+		})
+
+		report, err := CheckConsistency(events, MapOptions{})
+		assert.Nil(t, err)
+		assert.True(t, report.Consistent, "order %s diverged - %+v", helperEventOrder(events), report.Divergences)
+
+		for _, mappedResource := range report.Incremental.MappedResource {
+			assert.Len(t, mappedResource.Kube.HorizontalPodAutoscalers, 1)
+		}
+	}
+}
+
+func TestAutoscalerSurvivesRestore(t *testing.T) {
+	resources := helperGetK8sResources()
+	resources.HorizontalPodAutoscalers = []autoscaling_v2beta2.HorizontalPodAutoscaler{
+		helperGetAutoscaler(resources.Deployments[0].Namespace, resources.Deployments[0].Name, "Deployment"),
+	}
+
+	mapper := NewMapper()
+	_, err := mapper.Map(resources)
+	assert.Nil(t, err)
+
+	var buffer bytes.Buffer
+	assert.Nil(t, mapper.Snapshot(&buffer))
+
+	restored, err := RestoreMapper(&buffer, MapOptions{})
+	assert.Nil(t, err)
+
+	results, err := restored.StoreMap(gerResourceEvent(resources.Deployments[0].DeepCopy(), "deployment"))
+	assert.Nil(t, err)
+	assert.Len(t, results[0].MappedResource.Kube.HorizontalPodAutoscalers, 1)
+}
+
+func helperGetAutoscaler(namespace, targetName, targetKind string) autoscaling_v2beta2.HorizontalPodAutoscaler {
+	minReplicas, targetUtilization := int32(1), int32(80)
+
+	return autoscaling_v2beta2.HorizontalPodAutoscaler{
+		ObjectMeta: meta_v1.ObjectMeta{Name: targetName + "-hpa", Namespace: namespace, UID: helperUID(targetName, "hpa")},
+		Spec: autoscaling_v2beta2.HorizontalPodAutoscalerSpec{
+			ScaleTargetRef: autoscaling_v2beta2.CrossVersionObjectReference{Kind: targetKind, Name: targetName, APIVersion: "apps/v1"},
+			MinReplicas:    &minReplicas,
+			MaxReplicas:    10,
+			Metrics: []autoscaling_v2beta2.MetricSpec{{
+				Type: autoscaling_v2beta2.ResourceMetricSourceType,
+				Resource: &autoscaling_v2beta2.ResourceMetricSource{
+					Name: core_v1.ResourceCPU,
+					Target: autoscaling_v2beta2.MetricTarget{
+						Type:               autoscaling_v2beta2.UtilizationMetricType,
+						AverageUtilization: &targetUtilization,
+					},
+				},
+			}},
+		},
+		Status: autoscaling_v2beta2.HorizontalPodAutoscalerStatus{
+			CurrentReplicas: 2,
+			DesiredReplicas: 3,
+		},
+	}
+}
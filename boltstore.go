@@ -0,0 +1,249 @@
+package kubemap
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+	"k8s.io/client-go/tools/cache"
+)
+
+//boltBucket holds all mapped resources in database.
+var boltBucket = []byte("mappedResources")
+
+//BoltStore is a cache.Store which persists mapped resources in an embedded bbolt database.
+//Only store keys are kept in memory; mapped resources are read from disk on every Get and List. Mapper still keeps
+//objects of related kinds e.g. config maps, endpoints and nodes in memory in order to attach them to groups, so memory
+//saved is that of groups themselves.
+//Use it with NewStoreMapper or NewStoreMapperWithOptions.
+type BoltStore struct {
+	db      *bolt.DB
+	keyFunc cache.KeyFunc
+	lock    sync.RWMutex
+	//index maps store key to database key. Store keys can be larger than bbolt allows for keys.
+	index map[string][]byte
+}
+
+//boltRecord is value persisted for each mapped resource.
+type boltRecord struct {
+	Key            string          `json:"key"`
+	MappedResource json.RawMessage `json:"mappedResource"`
+}
+
+//NewBoltStore opens or creates bbolt database at path and loads index of mapped resources already in it.
+func NewBoltStore(path string) (*BoltStore, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("Cannot open bolt store %s - %v", path, err)
+	}
+
+	store := &BoltStore{
+		db:      db,
+		keyFunc: metaResourceKeyFunc,
+		index:   make(map[string][]byte),
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		bucket, err := tx.CreateBucketIfNotExists(boltBucket)
+		if err != nil {
+			return err
+		}
+
+		return bucket.ForEach(func(dbKey, value []byte) error {
+			var record boltRecord
+			if err := json.Unmarshal(value, &record); err != nil {
+				return err
+			}
+			store.index[record.Key] = append([]byte{}, dbKey...)
+			return nil
+		})
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("Cannot load bolt store %s - %v", path, err)
+	}
+
+	return store, nil
+}
+
+//Close closes underlying database.
+func (s *BoltStore) Close() error {
+	return s.db.Close()
+}
+
+//Add inserts mapped resource in store.
+func (s *BoltStore) Add(obj interface{}) error {
+	key, err := s.keyFunc(obj)
+	if err != nil {
+		return cache.KeyError{Obj: obj, Err: err}
+	}
+
+	mappedResource, err := json.Marshal(obj)
+	if err != nil {
+		return err
+	}
+
+	value, err := json.Marshal(boltRecord{
+		Key:            key,
+		MappedResource: mappedResource,
+	})
+	if err != nil {
+		return err
+	}
+
+	dbKey := boltKey(key)
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	err = s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(boltBucket).Put(dbKey, value)
+	})
+	if err != nil {
+		return err
+	}
+
+	s.index[key] = dbKey
+	return nil
+}
+
+//Update sets mapped resource in store.
+func (s *BoltStore) Update(obj interface{}) error {
+	return s.Add(obj)
+}
+
+//Delete removes mapped resource from store.
+func (s *BoltStore) Delete(obj interface{}) error {
+	key, err := s.keyFunc(obj)
+	if err != nil {
+		return cache.KeyError{Obj: obj, Err: err}
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	dbKey, exists := s.index[key]
+	if !exists {
+		return nil
+	}
+
+	err = s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(boltBucket).Delete(dbKey)
+	})
+	if err != nil {
+		return err
+	}
+
+	delete(s.index, key)
+	return nil
+}
+
+//List returns all mapped resources in store.
+func (s *BoltStore) List() []interface{} {
+	var list []interface{}
+
+	for _, key := range s.ListKeys() {
+		item, exists, err := s.GetByKey(key)
+		if err == nil && exists {
+			list = append(list, item)
+		}
+	}
+
+	return list
+}
+
+//ListKeys returns keys of all mapped resources in store.
+func (s *BoltStore) ListKeys() []string {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	keys := make([]string, 0, len(s.index))
+	for key := range s.index {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+//Get returns mapped resource with same key as obj.
+func (s *BoltStore) Get(obj interface{}) (item interface{}, exists bool, err error) {
+	key, err := s.keyFunc(obj)
+	if err != nil {
+		return nil, false, cache.KeyError{Obj: obj, Err: err}
+	}
+
+	return s.GetByKey(key)
+}
+
+//GetByKey returns mapped resource with given key.
+func (s *BoltStore) GetByKey(key string) (item interface{}, exists bool, err error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	dbKey, exists := s.index[key]
+	if !exists {
+		return nil, false, nil
+	}
+
+	var record boltRecord
+	err = s.db.View(func(tx *bolt.Tx) error {
+		value := tx.Bucket(boltBucket).Get(dbKey)
+		if value == nil {
+			return fmt.Errorf("Object with key %s is indexed but missing in bolt store", key)
+		}
+		return json.Unmarshal(value, &record)
+	})
+	if err != nil {
+		return nil, false, err
+	}
+
+	var mappedResource MappedResource
+	if err := json.Unmarshal(record.MappedResource, &mappedResource); err != nil {
+		return nil, false, err
+	}
+
+	return mappedResource, true, nil
+}
+
+//Replace deletes contents of store and adds given list of mapped resources.
+func (s *BoltStore) Replace(list []interface{}, resourceVersion string) error {
+	s.lock.Lock()
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		if err := tx.DeleteBucket(boltBucket); err != nil {
+			return err
+		}
+		_, err := tx.CreateBucket(boltBucket)
+		return err
+	})
+	if err == nil {
+		s.index = make(map[string][]byte)
+	}
+	s.lock.Unlock()
+
+	if err != nil {
+		return err
+	}
+
+	for _, item := range list {
+		if err := s.Add(item); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+//Resync is a no-op as mapped resources are always read from disk.
+func (s *BoltStore) Resync() error {
+	return nil
+}
+
+func boltKey(key string) []byte {
+	sum := sha256.Sum256([]byte(key))
+	return sum[:]
+}
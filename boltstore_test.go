@@ -0,0 +1,75 @@
+package kubemap
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBoltStoreMapping(t *testing.T) {
+	directory, err := ioutil.TempDir("", "kubemap")
+	assert.Nil(t, err)
+	defer os.RemoveAll(directory)
+	path := filepath.Join(directory, "kubemap.db")
+
+	store, err := NewBoltStore(path)
+	assert.Nil(t, err)
+
+	mapper, err := NewStoreMapperWithOptions(store, MapOptions{})
+	assert.Nil(t, err)
+
+	for _, event := range resourceEventsForMapping(helperGetK8sResources()) {
+		_, err := mapper.StoreMap(event)
+		assert.Nil(t, err)
+	}
+
+	mappedResources := getAllMappedResources(store)
+	assert.Len(t, mappedResources.MappedResource, 1)
+	assert.Len(t, mappedResources.MappedResource[0].Kube.Pods, 1)
+	assert.Nil(t, store.Close())
+
+	//Reopen and check mapping survived
+	reopenedStore, err := NewBoltStore(path)
+	assert.Nil(t, err)
+	defer reopenedStore.Close()
+
+	reopenedResources := getAllMappedResources(reopenedStore)
+	assert.Len(t, reopenedResources.MappedResource, 1)
+	assert.Equal(t, groupMembers(mappedResources.MappedResource[0]), groupMembers(reopenedResources.MappedResource[0]))
+}
+
+func TestBoltStoreOperations(t *testing.T) {
+	directory, err := ioutil.TempDir("", "kubemap")
+	assert.Nil(t, err)
+	defer os.RemoveAll(directory)
+
+	store, err := NewBoltStore(filepath.Join(directory, "kubemap.db"))
+	assert.Nil(t, err)
+	defer store.Close()
+
+	resources := helperGetK8sResources()
+	serviceResource := MappedResource{CommonLabel: "svc", Namespace: "test-namespace", Kube: Kube{Services: resources.Services}}
+	podResource := MappedResource{CommonLabel: "pod", Namespace: "test-namespace", Kube: Kube{Pods: resources.Pods}}
+
+	assert.Nil(t, store.Add(serviceResource))
+	assert.Nil(t, store.Add(podResource))
+	assert.Len(t, store.ListKeys(), 2)
+	assert.Len(t, store.List(), 2)
+
+	item, exists, err := store.Get(serviceResource)
+	assert.Nil(t, err)
+	assert.True(t, exists)
+	assert.Equal(t, "svc", item.(MappedResource).CommonLabel)
+
+	assert.Nil(t, store.Delete(serviceResource))
+	_, exists, _ = store.Get(serviceResource)
+	assert.False(t, exists)
+
+	assert.Nil(t, store.Replace([]interface{}{serviceResource}, ""))
+	assert.Len(t, store.ListKeys(), 1)
+	_, exists, _ = store.Get(podResource)
+	assert.False(t, exists)
+}
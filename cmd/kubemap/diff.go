@@ -0,0 +1,136 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+
+	"github.com/apollocse/kubemap"
+)
+
+//runDiff compares groups of two mappings. Like diff, exit code is 0 if they are same, 1 if they differ and 2 on
+//errors.
+func runDiff(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("diff", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: kubemap diff [flags] FROM TO")
+		fmt.Fprintln(stderr, "\nFROM and TO are snapshots written by Mapper.Snapshot or JSON or YAML files of k8s objects.")
+		flags.PrintDefaults()
+	}
+
+	var namespaces, ignore stringsFlag
+	rules := flags.String("rules", "", "YAML file of custom resource rules.")
+	flags.Var(&namespaces, "namespace", "Namespace of FROM and namespace of TO it is compared with e.g. 'shop-staging=shop'. May be repeated.")
+	flags.Var(&ignore, "ignore", "Field path of members which is not compared e.g. 'spec.replicas'. May be repeated.")
+	output := flags.String("o", "text", "Output format, either 'text' or 'json'.")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if !validOutput(*output, stderr) {
+		return 2
+	}
+	if flags.NArg() != 2 {
+		flags.Usage()
+		return 2
+	}
+
+	namespaceMap, err := namespaceMapping(namespaces)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+
+	var mappings [2]kubemap.MappedResources
+	for i, name := range flags.Args() {
+		mappedResources, err := loadMapping(name, *rules)
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return 2
+		}
+		mappings[i] = mappedResources
+	}
+
+	diff, err := kubemap.DiffMappings(mappings[0], mappings[1], kubemap.DiffOptions{Namespaces: namespaceMap, IgnoreFields: ignore})
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+
+	if *output == "json" {
+		if code := writeJSON(diff, stdout, stderr); code != 0 {
+			return 2
+		}
+	} else if err := diff.WriteText(stdout); err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+
+	if diff.Empty() {
+		return 0
+	}
+	return 1
+}
+
+//namespaceMapping parses values of 'from=to' into map of namespaces.
+func namespaceMapping(values []string) (map[string]string, error) {
+	namespaces := make(map[string]string)
+	for _, value := range values {
+		parts := strings.SplitN(value, "=", 2)
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return nil, fmt.Errorf("Invalid namespace %q. Expected 'from=to'", value)
+		}
+		namespaces[parts[0]] = parts[1]
+	}
+
+	return namespaces, nil
+}
+
+//loadMapping reads mapped resources of a snapshot, or maps k8s objects of file if it is not a snapshot.
+func loadMapping(name, rules string) (kubemap.MappedResources, error) {
+	content, err := ioutil.ReadFile(name)
+	if err != nil {
+		return kubemap.MappedResources{}, fmt.Errorf("Cannot open %s - %v", name, err)
+	}
+
+	if isSnapshot(content) {
+		mappedResources, err := kubemap.LoadSnapshot(bytes.NewReader(content))
+		if err != nil {
+			return kubemap.MappedResources{}, fmt.Errorf("Cannot read %s - %v", name, err)
+		}
+		return mappedResources, nil
+	}
+
+	resources, err := kubemap.LoadKubeResources(bytes.NewReader(content))
+	if err != nil {
+		return kubemap.MappedResources{}, fmt.Errorf("Cannot read %s - %v", name, err)
+	}
+
+	mapper, err := newMapper(rules)
+	if err != nil {
+		return kubemap.MappedResources{}, err
+	}
+
+	return mapper.Map(resources)
+}
+
+//isSnapshot checks if content is a snapshot envelope, which is either gzip compressed or a JSON object with a version.
+//Manifests are neither, so errors of snapshots are reported as such rather than as errors of k8s objects.
+func isSnapshot(content []byte) bool {
+	if len(content) >= 2 && content[0] == 0x1f && content[1] == 0x8b {
+		return true
+	}
+
+	var envelope struct {
+		Version *int `json:"version"`
+	}
+	if err := json.Unmarshal(content, &envelope); err != nil {
+		return false
+	}
+
+	return envelope.Version != nil
+}
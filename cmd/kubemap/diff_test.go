@@ -0,0 +1,101 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/apollocse/kubemap"
+)
+
+func TestDiffText(t *testing.T) {
+	stdout, stderr, code := helperRun(t, "diff", helperTestdata("shop.yaml"), helperTestdata("shop-v2.yaml"))
+	assert.Equal(t, 1, code, stderr)
+	assert.Equal(t, strings.Join([]string{
+		"~ group shop/api",
+		"    ~ deployment/api spec.replicas: 2 -> 3",
+		`    ~ deployment/api spec.template.spec.containers[0].image: "example.com/shop/api:1.4.0" -> "example.com/shop/api:1.5.0"`,
+		"",
+	}, "\n"), stdout)
+}
+
+func TestDiffSame(t *testing.T) {
+	stdout, stderr, code := helperRun(t, "diff", "-ignore", "spec.replicas", helperTestdata("shop.yaml"), helperTestdata("shop.yaml"))
+	assert.Equal(t, 0, code, stderr)
+	assert.Empty(t, stdout)
+}
+
+func TestDiffJSONWithSnapshot(t *testing.T) {
+	directory, err := ioutil.TempDir("", "kubemap")
+	assert.Nil(t, err)
+	defer os.RemoveAll(directory)
+
+	content, err := ioutil.ReadFile(helperTestdata("shop.yaml"))
+	assert.Nil(t, err)
+	resources, err := kubemap.LoadKubeResources(strings.NewReader(string(content)))
+	assert.Nil(t, err)
+
+	mapper := kubemap.NewMapper()
+	_, err = mapper.Map(resources)
+	assert.Nil(t, err)
+
+	snapshot := filepath.Join(directory, "snapshot.json")
+	file, err := os.Create(snapshot)
+	assert.Nil(t, err)
+	assert.Nil(t, mapper.Snapshot(file))
+	assert.Nil(t, file.Close())
+
+	stdout, stderr, code := helperRun(t, "diff", "-o", "json", "-ignore", "spec.template", snapshot, helperTestdata("shop-v2.yaml"))
+	assert.Equal(t, 1, code, stderr)
+
+	var diff kubemap.MappingDiff
+	assert.Nil(t, json.Unmarshal([]byte(stdout), &diff))
+	assert.Len(t, diff.Changed, 1)
+	assert.Equal(t, []kubemap.FieldDiff{{Path: "spec.replicas", From: "2", To: "3"}}, diff.Changed[0].Members[0].Fields)
+}
+
+func TestDiffErrors(t *testing.T) {
+	tests := []struct {
+		args   []string
+		stderr string
+	}{
+		{[]string{"diff", helperTestdata("shop.yaml")}, "Usage: kubemap diff"},
+		{[]string{"diff", "-namespace", "staging", helperTestdata("shop.yaml"), helperTestdata("shop.yaml")}, "Invalid namespace"},
+		{[]string{"diff", "-o", "yaml", helperTestdata("shop.yaml"), helperTestdata("shop.yaml")}, "Unknown output format"},
+		{[]string{"diff", helperTestdata("shop.yaml"), helperTestdata("missing.yaml")}, "Cannot open"},
+	}
+
+	for _, tt := range tests {
+		_, stderr, code := helperRun(t, tt.args...)
+		assert.Equal(t, 2, code, tt.args)
+		assert.Contains(t, stderr, tt.stderr)
+	}
+}
+
+func TestDiffSnapshotErrors(t *testing.T) {
+	directory, err := ioutil.TempDir("", "kubemap")
+	assert.Nil(t, err)
+	defer os.RemoveAll(directory)
+
+	tests := map[string]struct {
+		content []byte
+		stderr  string
+	}{
+		"newer.json":   {[]byte(`{"version": 99, "mappedResources": []}`), "newer than supported version"},
+		"corrupt.json": {[]byte{0x1f, 0x8b, 0x00}, "Cannot read gzip snapshot"},
+	}
+
+	for name, tt := range tests {
+		snapshot := filepath.Join(directory, name)
+		assert.Nil(t, ioutil.WriteFile(snapshot, tt.content, 0644))
+
+		_, stderr, code := helperRun(t, "diff", snapshot, helperTestdata("shop.yaml"))
+		assert.Equal(t, 2, code, name)
+		assert.Contains(t, stderr, tt.stderr, name)
+	}
+}
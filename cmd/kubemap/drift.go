@@ -0,0 +1,75 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/apollocse/kubemap"
+)
+
+//runDrift compares manifests with live state of cluster. Exit code is 0 if they match, 1 on drift and 2 on errors, so
+//it can gate a deploy pipeline.
+func runDrift(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("drift", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+
+	var input inputFlags
+	var namespaces stringsFlag
+	input.register(flags)
+	live := flags.String("live", "", "Snapshot written by Mapper.Snapshot or dump of cluster e.g. output of 'kubectl get -o yaml'. Required.")
+	namespace := flags.String("n", "", "Namespace of manifests without one.")
+	flags.Var(&namespaces, "namespace", "Namespace of manifests and namespace of cluster it is compared with e.g. 'shop=shop-production'. May be repeated.")
+	output := flags.String("o", "text", "Output format, either 'text' or 'json'.")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if !validOutput(*output, stderr) {
+		return 2
+	}
+	if *live == "" {
+		fmt.Fprintln(stderr, "Flag -live is required")
+		return 2
+	}
+
+	namespaceMap, err := namespaceMapping(namespaces)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+	if *namespace != "" {
+		namespaceMap[""] = *namespace
+	}
+
+	desired, err := input.mapResources(stdin)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+
+	liveMapping, err := loadMapping(*live, input.rules)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+
+	drift, err := kubemap.CompareManifests(desired, liveMapping, kubemap.DiffOptions{Namespaces: namespaceMap})
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+
+	if *output == "json" {
+		if code := writeJSON(drift, stdout, stderr); code != 0 {
+			return 2
+		}
+	} else if err := drift.WriteText(stdout); err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+
+	if drift.Empty() {
+		return 0
+	}
+	return 1
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/apollocse/kubemap"
+)
+
+func TestDriftText(t *testing.T) {
+	stdout, stderr, code := helperRun(t, "drift", "-f", helperTestdata("manifests.yaml"), "-n", "shop", "-live", helperTestdata("shop.yaml"))
+	assert.Equal(t, 1, code, stderr)
+	assert.Equal(t, strings.Join([]string{
+		"shop/api ExtraLive ingress/shop",
+		"shop/api ImageMismatch deployment/api api: example.com/shop/api:1.5.0 -> example.com/shop/api:1.4.0",
+		"",
+	}, "\n"), stdout)
+}
+
+func TestDriftInSync(t *testing.T) {
+	stdout, stderr, code := helperRun(t, "drift", "-f", helperTestdata("shop.yaml"), "-live", helperTestdata("shop.yaml"))
+	assert.Equal(t, 0, code, stderr)
+	assert.Empty(t, stdout)
+}
+
+func TestDriftJSON(t *testing.T) {
+	stdout, stderr, code := helperRun(t, "drift", "-o", "json", "-f", helperTestdata("shop-v2.yaml"), "-live", helperTestdata("shop.yaml"))
+	assert.Equal(t, 1, code, stderr)
+
+	var drift kubemap.ManifestDrift
+	assert.Nil(t, json.Unmarshal([]byte(stdout), &drift))
+	assert.Len(t, drift.Groups, 1)
+	assert.Equal(t, kubemap.Discrepancy{Type: kubemap.DriftReplicas, Member: "deployment/api", Desired: "3", Live: "2"}, drift.Groups[0].Discrepancies[0])
+}
+
+func TestDriftErrors(t *testing.T) {
+	tests := []struct {
+		args   []string
+		stderr string
+	}{
+		{[]string{"drift", "-f", helperTestdata("manifests.yaml")}, "Flag -live is required"},
+		{[]string{"drift", "-f", helperTestdata("manifests.yaml"), "-live", helperTestdata("missing.yaml")}, "Cannot open"},
+		{[]string{"drift", "-f", helperTestdata("manifests.yaml"), "-live", helperTestdata("shop.yaml"), "-namespace", "shop"}, "Invalid namespace"},
+		{[]string{"drift", "-o", "yaml", "-live", helperTestdata("shop.yaml")}, "Unknown output format"},
+	}
+
+	for _, tt := range tests {
+		_, stderr, code := helperRun(t, tt.args...)
+		assert.Equal(t, 2, code, tt.args)
+		assert.Contains(t, stderr, tt.stderr)
+	}
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/apollocse/kubemap"
+)
+
+func runImages(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("images", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+
+	var input inputFlags
+	input.register(flags)
+	drift := flags.Bool("drift", false, "Print only drift of pods and replica sets from their templates.")
+	output := flags.String("o", "text", "Output format, either 'text' or 'json'.")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if !validOutput(*output, stderr) {
+		return 2
+	}
+
+	mappedResources, err := input.mapResources(stdin)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+
+	inventories := []kubemap.ImageInventory{}
+	for _, inventory := range kubemap.ImageInventories(mappedResources) {
+		if !*drift || len(inventory.Drift) > 0 {
+			inventories = append(inventories, inventory)
+		}
+	}
+
+	if *output == "json" {
+		return writeJSON(inventories, stdout, stderr)
+	}
+
+	writer := newTable(stdout)
+	if *drift {
+		fmt.Fprintln(writer, "NAMESPACE\tGROUP\tREASON\tKIND\tNAME\tCONTAINER\tEXPECTED\tACTUAL")
+		for _, inventory := range inventories {
+			for _, drift := range inventory.Drift {
+				fmt.Fprintf(writer, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+					inventory.Namespace, inventory.CommonLabel, drift.Reason, drift.Kind, drift.Name, drift.Container, drift.Expected, drift.Actual)
+			}
+		}
+		return flushTable(writer, stderr)
+	}
+
+	fmt.Fprintln(writer, "NAMESPACE\tGROUP\tKIND\tNAME\tCONTAINER\tIMAGE\tDIGEST")
+	for _, inventory := range inventories {
+		for _, image := range inventory.Images {
+			container := image.Container
+			if image.Init {
+				container += " (init)"
+			}
+			fmt.Fprintf(writer, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+				inventory.Namespace, inventory.CommonLabel, image.Kind, image.Name, container, image.Image, orDash(image.Digest))
+		}
+	}
+
+	return flushTable(writer, stderr)
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/apollocse/kubemap"
+)
+
+func TestImagesText(t *testing.T) {
+	stdout, stderr, code := helperRun(t, "images", "-f", helperTestdata("rollout.yaml"))
+	assert.Equal(t, 0, code, stderr)
+
+	lines := strings.Split(strings.TrimSpace(stdout), "\n")
+	assert.Len(t, lines, 19)
+	assert.Equal(t, []string{"NAMESPACE", "GROUP", "KIND", "NAME", "CONTAINER", "IMAGE", "DIGEST"}, strings.Fields(lines[0]))
+	assert.Equal(t, []string{"jobs", "worker", "deployment", "worker", "migrate", "(init)", "example.com/jobs/migrate:2.0.0", "-"}, strings.Fields(lines[1]))
+	assert.Equal(t, []string{"jobs", "worker", "pod", "worker-2-b", "worker", "example.com/jobs/worker:2.0.1", "sha256:bbbb"}, strings.Fields(lines[14]))
+}
+
+func TestImagesDrift(t *testing.T) {
+	stdout, stderr, code := helperRun(t, "images", "-drift", "-f", helperTestdata("rollout.yaml"))
+	assert.Equal(t, 0, code, stderr)
+
+	lines := strings.Split(strings.TrimSpace(stdout), "\n")
+	assert.Len(t, lines, 4)
+	assert.Equal(t, []string{"jobs", "worker", "RolloutInProgress", "replicaset", "worker-1", "worker", "example.com/jobs/worker:2.0.0", "example.com/jobs/worker:1.0.0"}, strings.Fields(lines[1]))
+	assert.Equal(t, []string{"jobs", "worker", "DigestMismatch", "pod", "worker-2-b", "proxy", "sha256:1111", "sha256:2222"}, strings.Fields(lines[2]))
+	assert.Equal(t, []string{"jobs", "worker", "TemplateMismatch", "pod", "worker-2-b", "worker", "example.com/jobs/worker:2.0.0", "example.com/jobs/worker:2.0.1"}, strings.Fields(lines[3]))
+
+	stdout, stderr, code = helperRun(t, "images", "-drift", "-o", "json", "-f", helperTestdata("shop.yaml"))
+	assert.Equal(t, 0, code, stderr)
+	assert.Equal(t, "[]", strings.TrimSpace(stdout))
+}
+
+func TestImagesJSON(t *testing.T) {
+	stdout, stderr, code := helperRun(t, "images", "-o", "json", "-f", helperTestdata("rollout.yaml"), "-f", helperTestdata("shop.yaml"))
+	assert.Equal(t, 0, code, stderr)
+
+	var inventories []kubemap.ImageInventory
+	assert.Nil(t, json.Unmarshal([]byte(stdout), &inventories))
+	assert.Len(t, inventories, 2)
+	assert.Equal(t, "jobs", inventories[0].Namespace)
+	assert.Len(t, inventories[0].Drift, 3)
+	assert.Equal(t, "shop", inventories[1].Namespace)
+	assert.Empty(t, inventories[1].Drift)
+}
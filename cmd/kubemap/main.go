@@ -0,0 +1,191 @@
+//Command kubemap maps k8s objects read from files or stdin, e.g. output of 'kubectl get -o yaml', and prints reports
+//of mapped groups.
+//
+//Usage:
+//
+//	kubectl get ingress,service,deployment,replicaset,pod -n shop -o yaml | kubemap routes -path /api
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/apollocse/kubemap"
+)
+
+//command runs a subcommand with its arguments and returns exit code.
+type command struct {
+	summary string
+	run     func(args []string, stdin io.Reader, stdout, stderr io.Writer) int
+}
+
+var commands = map[string]command{
+	"diff":   {summary: "Compare groups of two snapshots or manifests", run: runDiff},
+	"drift":  {summary: "Compare manifests with live state of cluster", run: runDrift},
+	"images": {summary: "Print container images of groups and their drift", run: runImages},
+	"routes": {summary: "Print routing table from host and path to pods", run: runRoutes},
+	"usage":  {summary: "Print CPU and memory requests, limits and cost of groups", run: runUsage},
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		usage(stderr)
+		return 2
+	}
+
+	cmd, ok := commands[args[0]]
+	if !ok {
+		fmt.Fprintf(stderr, "Unknown command %q\n\n", args[0])
+		usage(stderr)
+		return 2
+	}
+
+	return cmd.run(args[1:], stdin, stdout, stderr)
+}
+
+func usage(w io.Writer) {
+	var names []string
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fmt.Fprintln(w, "Usage: kubemap <command> [flags]")
+	fmt.Fprintln(w, "\nCommands:")
+	for _, name := range names {
+		fmt.Fprintf(w, "  %-10s %s\n", name, commands[name].summary)
+	}
+	fmt.Fprintln(w, "\nRun 'kubemap <command> -h' for flags of a command.")
+}
+
+//stringsFlag is a flag which may be repeated.
+type stringsFlag []string
+
+func (s *stringsFlag) String() string {
+	return strings.Join(*s, ",")
+}
+
+func (s *stringsFlag) Set(value string) error {
+	*s = append(*s, value)
+	return nil
+}
+
+//inputFlags are flags of every command which maps resources.
+type inputFlags struct {
+	files stringsFlag
+	rules string
+}
+
+func (f *inputFlags) register(flags *flag.FlagSet) {
+	flags.Var(&f.files, "f", "JSON or YAML file of k8s objects, '-' for stdin. May be repeated. Defaults to stdin.")
+	flags.StringVar(&f.rules, "rules", "", "YAML file of custom resource rules.")
+}
+
+//mapResources reads and maps all input files.
+func (f *inputFlags) mapResources(stdin io.Reader) (kubemap.MappedResources, error) {
+	resources, err := readResources(f.files, stdin)
+	if err != nil {
+		return kubemap.MappedResources{}, err
+	}
+
+	mapper, err := newMapper(f.rules)
+	if err != nil {
+		return kubemap.MappedResources{}, err
+	}
+
+	return mapper.Map(resources)
+}
+
+//newMapper creates a mapper with custom resource rules of given file, if any.
+func newMapper(rules string) (*kubemap.Mapper, error) {
+	var options kubemap.MapOptions
+	if rules != "" {
+		file, err := os.Open(rules)
+		if err != nil {
+			return nil, fmt.Errorf("Cannot open rules - %v", err)
+		}
+		defer file.Close()
+
+		if options.CustomResources, err = kubemap.LoadCustomResourceRules(file); err != nil {
+			return nil, err
+		}
+	}
+
+	return kubemap.NewMapperWithOptions(options)
+}
+
+//readResources reads k8s objects of all files into one set of resources.
+func readResources(files []string, stdin io.Reader) (kubemap.KubeResources, error) {
+	if len(files) == 0 {
+		files = []string{"-"}
+	}
+
+	var readers []io.Reader
+	for _, name := range files {
+		if name == "-" {
+			readers = append(readers, stdin)
+			continue
+		}
+
+		file, err := os.Open(name)
+		if err != nil {
+			return kubemap.KubeResources{}, fmt.Errorf("Cannot open %s - %v", name, err)
+		}
+		defer file.Close()
+		//Documents of files are separated so that last document of a file does not run into next file.
+		readers = append(readers, file, strings.NewReader("\n---\n"))
+	}
+
+	return kubemap.LoadKubeResources(io.MultiReader(readers...))
+}
+
+//validOutput checks output format flag shared by commands.
+func validOutput(output string, stderr io.Writer) bool {
+	if output != "text" && output != "json" {
+		fmt.Fprintf(stderr, "Unknown output format %q\n", output)
+		return false
+	}
+
+	return true
+}
+
+func writeJSON(value interface{}, stdout, stderr io.Writer) int {
+	encoder := json.NewEncoder(stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(value); err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+
+	return 0
+}
+
+func newTable(stdout io.Writer) *tabwriter.Writer {
+	return tabwriter.NewWriter(stdout, 0, 4, 2, ' ', 0)
+}
+
+func flushTable(writer *tabwriter.Writer, stderr io.Writer) int {
+	if err := writer.Flush(); err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+
+	return 0
+}
+
+func orDash(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunUnknownCommand(t *testing.T) {
+	for _, args := range [][]string{nil, {"unknown"}} {
+		var stdout, stderr bytes.Buffer
+		assert.Equal(t, 2, run(args, strings.NewReader(""), &stdout, &stderr))
+		assert.Contains(t, stderr.String(), "Usage: kubemap")
+	}
+}
+
+func TestRoutesText(t *testing.T) {
+	stdout, stderr, code := helperRun(t, "routes", "-f", helperTestdata("shop.yaml"))
+	assert.Equal(t, 0, code, stderr)
+
+	lines := strings.Split(strings.TrimSpace(stdout), "\n")
+	assert.Len(t, lines, 3)
+	assert.Equal(t, []string{"NAMESPACE", "HOST", "PATH", "SOURCE", "SERVICE", "PORT", "TARGET", "PODS", "PROBLEMS"}, strings.Fields(lines[0]))
+	assert.Equal(t, []string{"shop", "shop.example.com", "/api", "ingress/shop", "api", "http(80)", "web", "1/2", "-"}, strings.Fields(lines[1]))
+	assert.Equal(t, []string{"shop", "shop.example.com", "/static", "ingress/shop", "static", "80", "-", "0/0", "ServiceNotFound"}, strings.Fields(lines[2]))
+}
+
+func TestRoutesJSONFromStdin(t *testing.T) {
+	content, err := ioutil.ReadFile(helperTestdata("shop.yaml"))
+	assert.Nil(t, err)
+
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"routes", "-o", "json", "-host", "shop.example.com", "-path", "/api/orders"}, bytes.NewReader(content), &stdout, &stderr)
+	assert.Equal(t, 0, code, stderr.String())
+
+	var rows []routeRow
+	assert.Nil(t, json.Unmarshal(stdout.Bytes(), &rows))
+	assert.Len(t, rows, 1)
+	assert.Equal(t, "shop", rows[0].Namespace)
+	assert.Equal(t, "/api", rows[0].Route.Path)
+	assert.Equal(t, int32(80), rows[0].Route.Port)
+	assert.Len(t, rows[0].Route.Pods, 2)
+	for _, pod := range rows[0].Route.Pods {
+		assert.Equal(t, int32(8080), pod.Port)
+	}
+}
+
+func TestRoutesErrors(t *testing.T) {
+	tests := []struct {
+		args []string
+		code int
+	}{
+		{[]string{"routes", "-o", "yaml", "-f", helperTestdata("shop.yaml")}, 2},
+		{[]string{"routes", "-unknown"}, 2},
+		{[]string{"routes", "-f", helperTestdata("missing.yaml")}, 1},
+		{[]string{"routes", "-f", helperTestdata("shop.yaml"), "-rules", helperTestdata("missing.yaml")}, 1},
+	}
+
+	for _, tt := range tests {
+		_, _, code := helperRun(t, tt.args...)
+		assert.Equal(t, tt.code, code, "%v", tt.args)
+	}
+}
+
+func helperRun(t *testing.T, args ...string) (string, string, int) {
+	var stdout, stderr bytes.Buffer
+	code := run(args, strings.NewReader(""), &stdout, &stderr)
+	return stdout.String(), stderr.String(), code
+}
+
+func helperTestdata(name string) string {
+	return filepath.Join("testdata", name)
+}
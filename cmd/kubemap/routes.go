@@ -0,0 +1,103 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/apollocse/kubemap"
+)
+
+//routeRow is a route of a mapped group.
+type routeRow struct {
+	Namespace   string        `json:"namespace"`
+	CommonLabel string        `json:"commonLabel"`
+	Route       kubemap.Route `json:"route"`
+}
+
+func runRoutes(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("routes", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+
+	var input inputFlags
+	input.register(flags)
+	host := flags.String("host", "", "Print only routes serving this host.")
+	path := flags.String("path", "", "Print only routes serving this path.")
+	output := flags.String("o", "text", "Output format, either 'text' or 'json'.")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if !validOutput(*output, stderr) {
+		return 2
+	}
+
+	mappedResources, err := input.mapResources(stdin)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+
+	var rows []routeRow
+	for _, mappedResource := range mappedResources.MappedResource {
+		for _, route := range mappedResource.Routes {
+			if route.MatchesRequest(*host, *path) {
+				rows = append(rows, routeRow{Namespace: mappedResource.Namespace, CommonLabel: mappedResource.CommonLabel, Route: route})
+			}
+		}
+	}
+	sort.SliceStable(rows, func(i, j int) bool {
+		if rows[i].Namespace != rows[j].Namespace {
+			return rows[i].Namespace < rows[j].Namespace
+		}
+		if rows[i].Route.Host != rows[j].Route.Host {
+			return rows[i].Route.Host < rows[j].Route.Host
+		}
+		return rows[i].Route.Path < rows[j].Route.Path
+	})
+
+	if *output == "json" {
+		if rows == nil {
+			rows = []routeRow{}
+		}
+		return writeJSON(rows, stdout, stderr)
+	}
+
+	writer := newTable(stdout)
+	fmt.Fprintln(writer, "NAMESPACE\tHOST\tPATH\tSOURCE\tSERVICE\tPORT\tTARGET\tPODS\tPROBLEMS")
+	for _, row := range rows {
+		route := row.Route
+		ready := 0
+		for _, pod := range route.Pods {
+			if pod.Ready {
+				ready++
+			}
+		}
+
+		fmt.Fprintf(writer, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%d/%d\t%s\n",
+			row.Namespace,
+			route.Host,
+			orDash(route.Path),
+			route.Source+"/"+route.Name,
+			route.Service,
+			routePort(route),
+			orDash(route.TargetPort),
+			ready, len(route.Pods),
+			orDash(strings.Join(route.Problems, ",")))
+	}
+
+	return flushTable(writer, stderr)
+}
+
+//routePort prints resolved port of service along with port name given by rule.
+func routePort(route kubemap.Route) string {
+	switch {
+	case route.Port == 0:
+		return orDash(route.ServicePort)
+	case route.ServicePort != "" && route.ServicePort != fmt.Sprint(route.Port):
+		return fmt.Sprintf("%s(%d)", route.ServicePort, route.Port)
+	default:
+		return fmt.Sprint(route.Port)
+	}
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+
+	"github.com/apollocse/kubemap"
+)
+
+func runUsage(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("usage", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+
+	var input inputFlags
+	input.register(flags)
+	pricesFile := flags.String("prices", "", "YAML file of prices per CPU core hour and memory GiB hour to estimate cost.")
+	output := flags.String("o", "text", "Output format, either 'text', 'json' or 'csv'.")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if *output != "csv" && !validOutput(*output, stderr) {
+		return 2
+	}
+
+	var prices *kubemap.PriceTable
+	if *pricesFile != "" {
+		file, err := os.Open(*pricesFile)
+		if err != nil {
+			fmt.Fprintf(stderr, "Cannot open prices - %v\n", err)
+			return 1
+		}
+		defer file.Close()
+
+		priceTable, err := kubemap.LoadPriceTable(file)
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return 1
+		}
+		prices = &priceTable
+	}
+
+	mappedResources, err := input.mapResources(stdin)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+
+	report := kubemap.AggregateUsage(mappedResources, prices)
+	switch *output {
+	case "json":
+		return writeJSON(report, stdout, stderr)
+	case "csv":
+		if err := report.WriteCSV(stdout); err != nil {
+			fmt.Fprintln(stderr, err)
+			return 1
+		}
+		return 0
+	}
+
+	writer := newTable(stdout)
+	fmt.Fprintln(writer, "NAMESPACE\tGROUP\tWORKLOAD\tPODS\tCPU REQUESTS\tCPU LIMITS\tMEMORY REQUESTS\tMEMORY LIMITS\tCOST/HOUR")
+	row := func(namespace, commonLabel, workload string, totals kubemap.ResourceTotals) {
+		cost := "-"
+		if prices != nil {
+			cost = strconv.FormatFloat(totals.Cost, 'f', 4, 64)
+			if report.Currency != "" {
+				cost += " " + report.Currency
+			}
+		}
+		fmt.Fprintf(writer, "%s\t%s\t%s\t%d\t%s\t%s\t%s\t%s\t%s\n",
+			namespace, commonLabel, workload, totals.Pods,
+			totals.CPURequests.String(), totals.CPULimits.String(), totals.MemoryRequests.String(), totals.MemoryLimits.String(), cost)
+	}
+
+	for _, group := range report.Groups {
+		for _, workload := range group.Workloads {
+			row(group.Namespace, group.CommonLabel, workload.Workload, workload.ResourceTotals)
+		}
+	}
+	for _, namespace := range report.Namespaces {
+		row(namespace.Namespace, "*", "*", namespace.ResourceTotals)
+	}
+	row("*", "*", "*", report.Total)
+
+	return flushTable(writer, stderr)
+}
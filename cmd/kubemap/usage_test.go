@@ -0,0 +1,55 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/apollocse/kubemap"
+)
+
+func TestUsageText(t *testing.T) {
+	stdout, stderr, code := helperRun(t, "usage", "-f", helperTestdata("shop.yaml"), "-prices", helperTestdata("prices.yaml"))
+	assert.Equal(t, 0, code, stderr)
+
+	lines := strings.Split(strings.TrimSpace(stdout), "\n")
+	assert.Len(t, lines, 4)
+	assert.Equal(t, []string{"shop", "api", "replicaset/api-7d4b9c", "2", "500m", "1", "512Mi", "1Gi", "0.0225", "USD"}, strings.Fields(lines[1]))
+	assert.Equal(t, []string{"*", "*", "*", "2", "500m", "1", "512Mi", "1Gi", "0.0225", "USD"}, strings.Fields(lines[3]))
+}
+
+func TestUsageCSV(t *testing.T) {
+	stdout, stderr, code := helperRun(t, "usage", "-o", "csv", "-f", helperTestdata("shop.yaml"))
+	assert.Equal(t, 0, code, stderr)
+	assert.Equal(t, strings.Join([]string{
+		"namespace,common_label,workload,pods,cpu_requests_cores,cpu_limits_cores,memory_requests_bytes,memory_limits_bytes,cost_per_hour",
+		"shop,api,replicaset/api-7d4b9c,2,0.5,1,536870912,1073741824,0.0000",
+		"shop,api,total,2,0.5,1,536870912,1073741824,0.0000",
+		"",
+	}, "\n"), stdout)
+}
+
+func TestUsageJSON(t *testing.T) {
+	stdout, stderr, code := helperRun(t, "usage", "-o", "json", "-f", helperTestdata("shop.yaml"), "-f", helperTestdata("rollout.yaml"), "-prices", helperTestdata("prices.yaml"))
+	assert.Equal(t, 0, code, stderr)
+
+	var report kubemap.UsageReport
+	assert.Nil(t, json.Unmarshal([]byte(stdout), &report))
+	assert.Equal(t, "USD", report.Currency)
+	assert.Len(t, report.Groups, 2)
+	assert.Equal(t, 5, report.Total.Pods)
+	assert.Equal(t, "512Mi", report.Total.MemoryRequests.String())
+}
+
+func TestUsageErrors(t *testing.T) {
+	for _, args := range [][]string{
+		{"usage", "-o", "yaml", "-f", helperTestdata("shop.yaml")},
+		{"usage", "-prices", helperTestdata("missing.yaml"), "-f", helperTestdata("shop.yaml")},
+		{"usage", "-prices", helperTestdata("shop.yaml"), "-f", helperTestdata("shop.yaml")},
+	} {
+		_, _, code := helperRun(t, args...)
+		assert.NotEqual(t, 0, code, "%v", args)
+	}
+}
@@ -0,0 +1,306 @@
+package kubemap
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	apps_v1 "k8s.io/api/apps/v1"
+	autoscaling_v1 "k8s.io/api/autoscaling/v1"
+	autoscaling_v2beta2 "k8s.io/api/autoscaling/v2beta2"
+	core_v1 "k8s.io/api/core/v1"
+	network_v1 "k8s.io/api/networking/v1"
+	network_v1beta1 "k8s.io/api/networking/v1beta1"
+	policy_v1beta1 "k8s.io/api/policy/v1beta1"
+	rbac_v1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/tools/cache"
+)
+
+//ConsistencyReport is result of comparing incremental mapping with batch mapping of same resources.
+type ConsistencyReport struct {
+	Consistent  bool                    `json:"consistent"`
+	Incremental MappedResources         `json:"incremental,omitempty"`
+	Batch       MappedResources         `json:"batch,omitempty"`
+	Divergences []ConsistencyDivergence `json:"divergences,omitempty"`
+}
+
+//ConsistencyDivergence is a group which is present in only one of the mapping modes.
+type ConsistencyDivergence struct {
+	//Source is either 'incremental' or 'batch' and tells which mapping produced this group.
+	Source      string   `json:"source"`
+	Namespace   string   `json:"namespace,omitempty"`
+	CommonLabel string   `json:"commonLabel,omitempty"`
+	Members     []string `json:"members,omitempty"`
+}
+
+//CheckConsistency replays events in given order through StoreMap and separately maps final state of same resources with Map.
+//Any group which is not produced by both of them is reported as divergence.
+func CheckConsistency(events []ResourceEvent, options MapOptions) (ConsistencyReport, error) {
+	var report ConsistencyReport
+
+	incrementalMapper, err := NewStoreMapperWithOptions(cache.NewStore(metaResourceKeyFunc), options)
+	if err != nil {
+		return report, err
+	}
+
+	for _, event := range events {
+		if _, err := incrementalMapper.StoreMap(event); err != nil {
+			return report, fmt.Errorf("Cannot replay event %s for %s %s/%s - %v", event.EventType, event.ResourceType, event.Namespace, event.Name, err)
+		}
+	}
+	report.Incremental = getAllMappedResources(incrementalMapper.store)
+
+	finalResources, err := finalStateOfEvents(events)
+	if err != nil {
+		return report, err
+	}
+
+	batchMapper, err := NewMapperWithOptions(options)
+	if err != nil {
+		return report, err
+	}
+
+	report.Batch, err = batchMapper.Map(finalResources)
+	if err != nil {
+		return report, err
+	}
+
+	report.Divergences = append(report.Divergences, groupsMissingIn(report.Incremental, report.Batch, "incremental")...)
+	report.Divergences = append(report.Divergences, groupsMissingIn(report.Batch, report.Incremental, "batch")...)
+	report.Consistent = len(report.Divergences) == 0
+
+	return report, nil
+}
+
+//finalStateOfEvents folds events into resources which would exist after all of them are applied.
+func finalStateOfEvents(events []ResourceEvent) (KubeResources, error) {
+	var resources KubeResources
+	var order []string
+	finalState := make(map[string]ResourceEvent)
+
+	for _, event := range events {
+		key := fmt.Sprintf("%s/%s/%s", event.ResourceType, event.Namespace, event.Name)
+
+		if event.EventType == "DELETED" {
+			delete(finalState, key)
+			continue
+		}
+
+		if _, exists := finalState[key]; !exists {
+			order = append(order, key)
+		}
+		finalState[key] = event
+	}
+
+	for _, key := range order {
+		event, exists := finalState[key]
+		if !exists {
+			continue
+		}
+
+		switch object := event.Event.(type) {
+		case *network_v1beta1.Ingress:
+			resources.Ingresses = append(resources.Ingresses, *object.DeepCopy())
+		case *core_v1.Service:
+			resources.Services = append(resources.Services, *object.DeepCopy())
+		case *apps_v1.Deployment:
+			resources.Deployments = append(resources.Deployments, *object.DeepCopy())
+		case *apps_v1.ReplicaSet:
+			resources.ReplicaSets = append(resources.ReplicaSets, *object.DeepCopy())
+		case *core_v1.Pod:
+			resources.Pods = append(resources.Pods, *object.DeepCopy())
+		case *autoscaling_v2beta2.HorizontalPodAutoscaler:
+			resources.HorizontalPodAutoscalers = append(resources.HorizontalPodAutoscalers, *object.DeepCopy())
+		case *autoscaling_v1.HorizontalPodAutoscaler:
+			resources.HorizontalPodAutoscalersV1 = append(resources.HorizontalPodAutoscalersV1, *object.DeepCopy())
+		case *core_v1.ConfigMap:
+			resources.ConfigMaps = append(resources.ConfigMaps, *object.DeepCopy())
+		case *core_v1.Secret:
+			resources.Secrets = append(resources.Secrets, *object.DeepCopy())
+		case *core_v1.PersistentVolumeClaim:
+			resources.PersistentVolumeClaims = append(resources.PersistentVolumeClaims, *object.DeepCopy())
+		case *core_v1.PersistentVolume:
+			resources.PersistentVolumes = append(resources.PersistentVolumes, *object.DeepCopy())
+		case *core_v1.Node:
+			resources.Nodes = append(resources.Nodes, *object.DeepCopy())
+		case *core_v1.Endpoints:
+			resources.Endpoints = append(resources.Endpoints, *object.DeepCopy())
+		case *policy_v1beta1.PodDisruptionBudget:
+			resources.PodDisruptionBudgets = append(resources.PodDisruptionBudgets, *object.DeepCopy())
+		case *network_v1.NetworkPolicy:
+			resources.NetworkPolicies = append(resources.NetworkPolicies, *object.DeepCopy())
+		case *core_v1.ServiceAccount:
+			resources.ServiceAccounts = append(resources.ServiceAccounts, *object.DeepCopy())
+		case *rbac_v1.Role:
+			resources.Roles = append(resources.Roles, *object.DeepCopy())
+		case *rbac_v1.RoleBinding:
+			resources.RoleBindings = append(resources.RoleBindings, *object.DeepCopy())
+		case *rbac_v1.ClusterRole:
+			resources.ClusterRoles = append(resources.ClusterRoles, *object.DeepCopy())
+		case *rbac_v1.ClusterRoleBinding:
+			resources.ClusterRoleBindings = append(resources.ClusterRoleBindings, *object.DeepCopy())
+		case *unstructured.Unstructured:
+			switch event.ResourceType {
+			case "endpointslice":
+				resources.EndpointSlices = append(resources.EndpointSlices, *object.DeepCopy())
+			case "gateway":
+				resources.Gateways = append(resources.Gateways, *object.DeepCopy())
+			case "httproute":
+				resources.HTTPRoutes = append(resources.HTTPRoutes, *object.DeepCopy())
+			case "grpcroute":
+				resources.GRPCRoutes = append(resources.GRPCRoutes, *object.DeepCopy())
+			case "referencegrant":
+				resources.ReferenceGrants = append(resources.ReferenceGrants, *object.DeepCopy())
+			case customResourceTypeOf(object):
+				resources.CustomResources = append(resources.CustomResources, *object.DeepCopy())
+			default:
+				return resources, fmt.Errorf("Resource type '%s' is not supported for consistency check", event.ResourceType)
+			}
+		default:
+			return resources, fmt.Errorf("Resource type '%s' is not supported for consistency check", event.ResourceType)
+		}
+	}
+
+	return resources, nil
+}
+
+//groupsMissingIn returns groups of source which have no group with same members in target.
+func groupsMissingIn(source, target MappedResources, sourceName string) []ConsistencyDivergence {
+	var divergences []ConsistencyDivergence
+
+	targetSignatures := make(map[string]int)
+	for _, mappedResource := range target.MappedResource {
+		targetSignatures[groupSignature(mappedResource)]++
+	}
+
+	for _, mappedResource := range source.MappedResource {
+		signature := groupSignature(mappedResource)
+		if targetSignatures[signature] > 0 {
+			targetSignatures[signature]--
+			continue
+		}
+
+		divergences = append(divergences, ConsistencyDivergence{
+			Source:      sourceName,
+			Namespace:   mappedResource.Namespace,
+			CommonLabel: mappedResource.CommonLabel,
+			Members:     groupMembers(mappedResource),
+		})
+	}
+
+	return divergences
+}
+
+//groupSignature identifies a group by its namespace and members irrespective of order and common label.
+func groupSignature(mappedResource MappedResource) string {
+	return fmt.Sprintf("%s$%s", mappedResource.Namespace, strings.Join(groupMembers(mappedResource), ","))
+}
+
+//groupMembers returns sorted 'type/name' of every resource in group including attached ones.
+func groupMembers(mappedResource MappedResource) []string {
+	var members []string
+
+	for _, ingress := range mappedResource.Kube.Ingresses {
+		members = append(members, "ingress/"+ingress.Name)
+	}
+
+	for _, service := range mappedResource.Kube.Services {
+		members = append(members, "service/"+service.Name)
+	}
+
+	for _, deployment := range mappedResource.Kube.Deployments {
+		members = append(members, "deployment/"+deployment.Name)
+	}
+
+	for _, replicaSet := range mappedResource.Kube.ReplicaSets {
+		members = append(members, "replicaset/"+replicaSet.Name)
+	}
+
+	for _, pod := range mappedResource.Kube.Pods {
+		members = append(members, "pod/"+pod.Name)
+	}
+
+	for _, hpa := range mappedResource.Kube.HorizontalPodAutoscalers {
+		members = append(members, "hpa/"+hpa.Name)
+	}
+
+	for _, configMap := range mappedResource.Kube.ConfigMaps {
+		members = append(members, "configmap/"+configMap.Name)
+	}
+
+	for _, secret := range mappedResource.Kube.Secrets {
+		members = append(members, "secret/"+secret.Name)
+	}
+
+	for _, pvc := range mappedResource.Kube.PersistentVolumeClaims {
+		members = append(members, "pvc/"+pvc.Name)
+	}
+
+	for _, pv := range mappedResource.Kube.PersistentVolumes {
+		members = append(members, "pv/"+pv.Name)
+	}
+
+	for _, endpoints := range mappedResource.Kube.Endpoints {
+		members = append(members, "endpoints/"+endpoints.Name)
+	}
+
+	for _, endpointSlice := range mappedResource.Kube.EndpointSlices {
+		members = append(members, "endpointslice/"+endpointSlice.Name)
+	}
+
+	for _, pdb := range mappedResource.Kube.PodDisruptionBudgets {
+		members = append(members, "pdb/"+pdb.Name)
+	}
+
+	for _, networkPolicy := range mappedResource.Kube.NetworkPolicies {
+		members = append(members, "networkpolicy/"+networkPolicy.Name)
+	}
+
+	for _, serviceAccount := range mappedResource.Kube.ServiceAccounts {
+		members = append(members, "serviceaccount/"+serviceAccount.Name)
+	}
+
+	for _, role := range mappedResource.Kube.Roles {
+		members = append(members, "role/"+role.Name)
+	}
+
+	for _, roleBinding := range mappedResource.Kube.RoleBindings {
+		members = append(members, "rolebinding/"+roleBinding.Name)
+	}
+
+	for _, clusterRole := range mappedResource.Kube.ClusterRoles {
+		members = append(members, "clusterrole/"+clusterRole.Name)
+	}
+
+	for _, clusterRoleBinding := range mappedResource.Kube.ClusterRoleBindings {
+		members = append(members, "clusterrolebinding/"+clusterRoleBinding.Name)
+	}
+
+	for _, gateway := range mappedResource.Kube.Gateways {
+		members = append(members, "gateway/"+gateway.Namespace+"/"+gateway.Name)
+	}
+
+	for _, httpRoute := range mappedResource.Kube.HTTPRoutes {
+		members = append(members, "httproute/"+httpRoute.Namespace+"/"+httpRoute.Name)
+	}
+
+	for _, grpcRoute := range mappedResource.Kube.GRPCRoutes {
+		members = append(members, "grpcroute/"+grpcRoute.Namespace+"/"+grpcRoute.Name)
+	}
+
+	for _, referenceGrant := range mappedResource.Kube.ReferenceGrants {
+		members = append(members, "referencegrant/"+referenceGrant.Name)
+	}
+
+	for _, node := range mappedResource.Kube.Nodes {
+		members = append(members, "node/"+node.Name)
+	}
+
+	for i := range mappedResource.Kube.CustomResources {
+		members = append(members, customResourceTypeOf(&mappedResource.Kube.CustomResources[i])+"/"+mappedResource.Kube.CustomResources[i].GetName())
+	}
+
+	sort.Strings(members)
+	return members
+}
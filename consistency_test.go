@@ -0,0 +1,258 @@
+package kubemap
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	apps_v1 "k8s.io/api/apps/v1"
+	core_v1 "k8s.io/api/core/v1"
+	network_v1beta1 "k8s.io/api/networking/v1beta1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+func TestCheckConsistencyWithFixtures(t *testing.T) {
+	events := resourceEventsForMapping(helperGetK8sResources())
+
+	report, err := CheckConsistency(events, MapOptions{})
+	assert.Nil(t, err)
+	assert.True(t, report.Consistent, "divergences - %+v", report.Divergences)
+	assert.Len(t, report.Batch.MappedResource, 1)
+}
+
+func TestCheckConsistencyWithShuffledFixtures(t *testing.T) {
+	events := resourceEventsForMapping(helperGetK8sResources())
+	random := rand.New(rand.NewSource(26))
+
+	for i := 0; i < 50; i++ {
+		random.Shuffle(len(events), func(a, b int) {
+			events[a], events[b] = events[b], events[a]
+		})
+
+		report, err := CheckConsistency(events, MapOptions{})
+		assert.Nil(t, err)
+		assert.True(t, report.Consistent, "order %s diverged - %+v", helperEventOrder(events), report.Divergences)
+	}
+}
+
+func TestCheckConsistencyWithGeneratedTopologies(t *testing.T) {
+	random := rand.New(rand.NewSource(2026))
+
+	for i := 0; i < 30; i++ {
+		resources := helperGenerateTopology(random, 1+random.Intn(4))
+		helperAddSharedService(random, &resources)
+		events := resourceEventsForMapping(resources)
+
+		random.Shuffle(len(events), func(a, b int) {
+			events[a], events[b] = events[b], events[a]
+		})
+
+		report, err := CheckConsistency(events, MapOptions{})
+		assert.Nil(t, err)
+		assert.True(t, report.Consistent, "order %s diverged - %+v", helperEventOrder(events), report.Divergences)
+	}
+}
+
+func TestCheckConsistencyWithDeletedResource(t *testing.T) {
+	resources := helperGetK8sResources()
+	events := resourceEventsForMapping(resources)
+
+	pod := resources.Pods[0]
+	events = append(events, ResourceEvent{
+		EventType:    "DELETED",
+		ResourceType: "pod",
+		Namespace:    pod.Namespace,
+		Name:         pod.Name,
+		Key:          fmt.Sprintf("%s/%s", pod.Namespace, pod.Name),
+	})
+
+	report, err := CheckConsistency(events, MapOptions{})
+	assert.Nil(t, err)
+	assert.True(t, report.Consistent, "divergences - %+v", report.Divergences)
+
+	for _, mappedResource := range report.Batch.MappedResource {
+		assert.Empty(t, mappedResource.Kube.Pods)
+	}
+}
+
+func TestCheckConsistencyWithDeletedLinkingPods(t *testing.T) {
+	resources := helperGetOverlappingResources()
+	events := resourceEventsForMapping(KubeResources{Services: resources.Services, Pods: resources.Pods})
+
+	for _, pod := range resources.Pods {
+		events = append(events, ResourceEvent{
+			EventType:    "DELETED",
+			ResourceType: "pod",
+			Namespace:    pod.Namespace,
+			Name:         pod.Name,
+			Key:          fmt.Sprintf("%s/%s", pod.Namespace, pod.Name),
+		})
+	}
+
+	report, err := CheckConsistency(events, MapOptions{})
+	assert.Nil(t, err)
+	assert.True(t, report.Consistent, "divergences - %+v", report.Divergences)
+	assert.Len(t, report.Incremental.MappedResource, 3)
+}
+
+func TestGroupsMissingIn(t *testing.T) {
+	resources := helperGetK8sResources()
+
+	grouped := MappedResources{MappedResource: []MappedResource{{
+		Namespace: "test-namespace",
+		Kube: Kube{
+			Services: resources.Services,
+			Pods:     resources.Pods,
+		},
+	}}}
+
+	split := MappedResources{MappedResource: []MappedResource{
+		{Namespace: "test-namespace", Kube: Kube{Services: resources.Services}},
+		{Namespace: "test-namespace", Kube: Kube{Pods: resources.Pods}},
+	}}
+
+	assert.Len(t, groupsMissingIn(grouped, split, "incremental"), 1)
+	assert.Len(t, groupsMissingIn(split, grouped, "batch"), 2)
+	assert.Empty(t, groupsMissingIn(grouped, grouped, "batch"))
+}
+
+func helperEventOrder(events []ResourceEvent) []string {
+	var order []string
+	for _, event := range events {
+		order = append(order, event.ResourceType+"/"+event.Name)
+	}
+
+	return order
+}
+
+//helperGenerateTopology creates given number of applications each having ingress, service, deployment, replica set and pods.
+func helperGenerateTopology(random *rand.Rand, apps int) KubeResources {
+	var resources KubeResources
+	namespace := "generated"
+
+	for i := 0; i < apps; i++ {
+		name := fmt.Sprintf("app-%d", i)
+		hash := fmt.Sprintf("%d%d%d", i, random.Intn(1000), i)
+		selector := map[string]string{"app": name}
+		podLabels := map[string]string{"app": name, "pod-template-hash": hash}
+
+		if random.Intn(2) == 0 {
+			resources.Ingresses = append(resources.Ingresses, network_v1beta1.Ingress{
+				ObjectMeta: meta_v1.ObjectMeta{Name: name, Namespace: namespace, UID: helperUID(name, "ingress")},
+				Spec: network_v1beta1.IngressSpec{
+					Rules: []network_v1beta1.IngressRule{{
+						Host: name + ".example.com",
+						IngressRuleValue: network_v1beta1.IngressRuleValue{
+							HTTP: &network_v1beta1.HTTPIngressRuleValue{
+								Paths: []network_v1beta1.HTTPIngressPath{{
+									Path: "/",
+									Backend: network_v1beta1.IngressBackend{
+										ServiceName: name,
+										ServicePort: intstr.FromInt(80),
+									},
+								}},
+							},
+						},
+					}},
+				},
+			})
+		}
+
+		resources.Services = append(resources.Services, core_v1.Service{
+			ObjectMeta: meta_v1.ObjectMeta{Name: name, Namespace: namespace, UID: helperUID(name, "service")},
+			Spec:       core_v1.ServiceSpec{Selector: selector},
+		})
+
+		resources.Deployments = append(resources.Deployments, apps_v1.Deployment{
+			ObjectMeta: meta_v1.ObjectMeta{Name: name, Namespace: namespace, Labels: selector, UID: helperUID(name, "deployment")},
+			Spec: apps_v1.DeploymentSpec{
+				Selector: &meta_v1.LabelSelector{MatchLabels: selector},
+			},
+		})
+
+		rsName := fmt.Sprintf("%s-%s", name, hash)
+		resources.ReplicaSets = append(resources.ReplicaSets, apps_v1.ReplicaSet{
+			ObjectMeta: meta_v1.ObjectMeta{
+				Name:            rsName,
+				Namespace:       namespace,
+				Labels:          podLabels,
+				UID:             helperUID(rsName, "replicaset"),
+				OwnerReferences: []meta_v1.OwnerReference{{Kind: "Deployment", Name: name}},
+			},
+			Spec: apps_v1.ReplicaSetSpec{
+				Selector: &meta_v1.LabelSelector{MatchLabels: podLabels},
+			},
+		})
+
+		if random.Intn(2) == 0 {
+			//Old revision left behind by a rollout
+			oldHash := fmt.Sprintf("old%d", random.Intn(1000))
+			oldRsName := fmt.Sprintf("%s-%s", name, oldHash)
+			resources.ReplicaSets = append(resources.ReplicaSets, apps_v1.ReplicaSet{
+				ObjectMeta: meta_v1.ObjectMeta{
+					Name:            oldRsName,
+					Namespace:       namespace,
+					UID:             helperUID(oldRsName, "replicaset"),
+					OwnerReferences: []meta_v1.OwnerReference{{Kind: "Deployment", Name: name}},
+				},
+				Spec: apps_v1.ReplicaSetSpec{
+					Selector: &meta_v1.LabelSelector{MatchLabels: map[string]string{"app": name, "pod-template-hash": oldHash}},
+				},
+			})
+		}
+
+		for p := 0; p < 1+random.Intn(3); p++ {
+			podName := fmt.Sprintf("%s-%d", rsName, p)
+			resources.Pods = append(resources.Pods, core_v1.Pod{
+				ObjectMeta: meta_v1.ObjectMeta{
+					Name:            podName,
+					Namespace:       namespace,
+					Labels:          podLabels,
+					UID:             helperUID(podName, "pod"),
+					OwnerReferences: []meta_v1.OwnerReference{{Kind: "ReplicaSet", Name: rsName}},
+				},
+			})
+		}
+	}
+
+	return resources
+}
+
+//helperAddSharedService adds a service whose selector matches pods of a random subset of generated applications, so
+//that selectors of their groups overlap.
+func helperAddSharedService(random *rand.Rand, resources *KubeResources) {
+	selector := map[string]string{"tier": "shared"}
+
+	shared := make(map[string]bool)
+	for _, deployment := range resources.Deployments {
+		if random.Intn(2) == 0 {
+			shared[deployment.Name] = true
+		}
+	}
+
+	for i := range resources.Pods {
+		pod := &resources.Pods[i]
+		if !shared[pod.Labels["app"]] {
+			continue
+		}
+
+		labels := map[string]string{"tier": "shared"}
+		for key, value := range pod.Labels {
+			labels[key] = value
+		}
+		pod.Labels = labels
+	}
+
+	resources.Services = append(resources.Services, core_v1.Service{
+		ObjectMeta: meta_v1.ObjectMeta{Name: "shared", Namespace: "generated", UID: helperUID("shared", "service")},
+		Spec:       core_v1.ServiceSpec{Selector: selector},
+	})
+}
+
+func helperUID(name, resourceType string) types.UID {
+	return types.UID(resourceType + "-" + name)
+}
@@ -0,0 +1,376 @@
+package kubemap
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sort"
+	"strings"
+
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/util/jsonpath"
+	"sigs.k8s.io/yaml"
+)
+
+//maxOwnerDepth limits how many custom resources are followed from a member of group to its topmost owner.
+const maxOwnerDepth = 8
+
+//CustomResourceRule describes how objects of a custom resource definition are attached to groups.
+//An object is attached to every group which it owns, selects or routes traffic to.
+type CustomResourceRule struct {
+	Group string `json:"group"`
+	//Version restricts rule to a version of group. Any version is accepted if empty.
+	Version string `json:"version,omitempty"`
+	Kind    string `json:"kind"`
+	//ClusterScoped objects may be attached to groups of any namespace.
+	ClusterScoped bool `json:"clusterScoped,omitempty"`
+	//Selector is JSONPath to label selector of pods managed by object e.g. '{.spec.selector}'.
+	//It may point to a label selector or to a map of labels. Empty selector selects nothing.
+	Selector string `json:"selector,omitempty"`
+	//Owner is JSONPath to names of deployments or replica sets which object manages e.g. '{.spec.workloadRef.name}'.
+	//Members of a group owned by object through owner references are always attached, even without Owner.
+	Owner string `json:"owner,omitempty"`
+	//Services is JSONPath to names or hosts of services which receive traffic of object
+	//e.g. '{.spec.http[*].route[*].destination.host}'. Hosts are '<name>[.<namespace>[.svc...]]'.
+	Services string `json:"services,omitempty"`
+}
+
+//customResourceRules is format of YAML or JSON rule file.
+type customResourceRules struct {
+	Rules []CustomResourceRule `json:"rules"`
+}
+
+//LoadCustomResourceRules reads rules from YAML or JSON of form 'rules: [...]'.
+func LoadCustomResourceRules(r io.Reader) ([]CustomResourceRule, error) {
+	content, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("Cannot read custom resource rules - %v", err)
+	}
+
+	var rules customResourceRules
+	if err := yaml.UnmarshalStrict(content, &rules); err != nil {
+		return nil, fmt.Errorf("Cannot parse custom resource rules - %v", err)
+	}
+
+	for _, rule := range rules.Rules {
+		if err := rule.validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	return rules.Rules, nil
+}
+
+//CustomResourceType returns resource type of objects of group and kind e.g. 'rollout.argoproj.io'.
+//Resource events of custom resources must use it as ResourceType.
+func CustomResourceType(group, kind string) string {
+	if group == "" {
+		return strings.ToLower(kind)
+	}
+
+	return strings.ToLower(kind + "." + group)
+}
+
+func customResourceTypeOf(obj *unstructured.Unstructured) string {
+	return CustomResourceType(obj.GroupVersionKind().Group, obj.GetKind())
+}
+
+func (rule CustomResourceRule) resourceType() string {
+	return CustomResourceType(rule.Group, rule.Kind)
+}
+
+func (rule CustomResourceRule) validate() error {
+	if rule.Kind == "" {
+		return fmt.Errorf("Custom resource rule of group '%s' has no kind", rule.Group)
+	}
+
+	if _, ok := relatedKinds[rule.resourceType()]; ok {
+		return fmt.Errorf("Custom resource rule '%s' conflicts with a built-in resource type", rule.resourceType())
+	}
+
+	for field, path := range map[string]string{"selector": rule.Selector, "owner": rule.Owner, "services": rule.Services} {
+		if path == "" {
+			continue
+		}
+		if err := jsonpath.New(field).Parse(path); err != nil {
+			return fmt.Errorf("Cannot parse %s of custom resource rule '%s' - %v", field, rule.resourceType(), err)
+		}
+	}
+
+	return nil
+}
+
+//addCustomResourceRules registers a related kind for every rule.
+func (r *relatedObjects) addCustomResourceRules(rules []CustomResourceRule) error {
+	for _, rule := range rules {
+		if err := rule.validate(); err != nil {
+			return err
+		}
+
+		if err := r.addKind(rule.resourceType(), customResourceKind(rule)); err != nil {
+			return fmt.Errorf("Cannot add custom resource rule - %v", err)
+		}
+	}
+
+	return nil
+}
+
+//customResourceKind attaches custom resources of rule. Objects of all custom resource kinds share
+//Kube.CustomResources, so attach only replaces objects of its own resource type.
+func customResourceKind(rule CustomResourceRule) relatedKind {
+	resourceType := rule.resourceType()
+
+	return relatedKind{
+		clusterScoped: rule.ClusterScoped,
+		normalize: func(obj interface{}) (interface{}, error) {
+			object, ok := obj.(*unstructured.Unstructured)
+			if !ok {
+				return nil, fmt.Errorf("Object of type %T is not a custom resource", obj)
+			}
+
+			gvk := object.GroupVersionKind()
+			if customResourceTypeOf(object) != resourceType || (rule.Version != "" && gvk.Version != rule.Version) {
+				return nil, fmt.Errorf("Object of kind %s does not match custom resource rule '%s'", gvk.String(), resourceType)
+			}
+			return object.DeepCopy(), nil
+		},
+		attach: func(mappedResource *MappedResource, related *relatedObjects) {
+			customResources := withoutCustomResourceType(mappedResource.Kube.CustomResources, resourceType)
+
+			namespace := mappedResource.Namespace
+			if rule.ClusterScoped {
+				namespace = ""
+			}
+
+			owners := groupOwners(*mappedResource, related)
+			for _, obj := range related.list(resourceType, namespace) {
+				object := obj.(*unstructured.Unstructured)
+				if owners[ownerIdentity(object.GetAPIVersion(), object.GetKind(), object.GetName())] || rule.matches(object, *mappedResource) {
+					customResources = append(customResources, *object.DeepCopy())
+				}
+			}
+
+			mappedResource.Kube.CustomResources = sortCustomResources(customResources)
+		},
+		attached: func(mappedResource MappedResource) []interface{} {
+			var objects []interface{}
+			for i := range mappedResource.Kube.CustomResources {
+				if customResourceTypeOf(&mappedResource.Kube.CustomResources[i]) == resourceType {
+					objects = append(objects, mappedResource.Kube.CustomResources[i].DeepCopy())
+				}
+			}
+			return objects
+		},
+	}
+}
+
+//matches checks if object selects pods of group, manages its workloads or routes traffic to its services.
+func (rule CustomResourceRule) matches(object *unstructured.Unstructured, mappedResource MappedResource) bool {
+	if rule.Selector != "" {
+		for _, value := range evaluateJSONPath(rule.Selector, object.Object) {
+			if labelSelector, ok := customResourceSelector(value); ok && selectorMatchesPods(labelSelector, mappedResource) {
+				return true
+			}
+		}
+	}
+
+	if rule.Owner != "" {
+		for _, name := range jsonPathStrings(rule.Owner, object.Object) {
+			for _, deployment := range mappedResource.Kube.Deployments {
+				if deployment.Name == name {
+					return true
+				}
+			}
+			for _, replicaSet := range mappedResource.Kube.ReplicaSets {
+				if replicaSet.Name == name {
+					return true
+				}
+			}
+		}
+	}
+
+	if rule.Services != "" {
+		for _, host := range jsonPathStrings(rule.Services, object.Object) {
+			name, namespace := serviceOfHost(host, object.GetNamespace())
+			for _, service := range mappedResource.Kube.Services {
+				if service.Name == name && service.Namespace == namespace {
+					return true
+				}
+			}
+		}
+	}
+
+	return false
+}
+
+//groupOwners returns identities of objects owning members of group either directly or through custom resources
+//which own them. Only built-in members are considered, so result does not depend on order of attachment.
+func groupOwners(mappedResource MappedResource, related *relatedObjects) map[string]bool {
+	builtIn := mappedResource
+	builtIn.Kube.CustomResources = nil
+
+	owners := make(map[string]bool)
+	var pending []meta_v1.OwnerReference
+	for _, member := range memberObjects(builtIn) {
+		pending = append(pending, member.ObjectMeta.OwnerReferences...)
+	}
+
+	for depth := 0; depth <= maxOwnerDepth && len(pending) > 0; depth++ {
+		var next []meta_v1.OwnerReference
+		for _, ownerReference := range pending {
+			identity := ownerIdentity(ownerReference.APIVersion, ownerReference.Kind, ownerReference.Name)
+			if owners[identity] {
+				continue
+			}
+			owners[identity] = true
+
+			group := groupOfAPIVersion(ownerReference.APIVersion)
+			resourceType := CustomResourceType(group, ownerReference.Kind)
+			obj, ok := related.get(resourceType, mappedResource.Namespace, ownerReference.Name)
+			if !ok {
+				//Owner may be cluster scoped.
+				obj, ok = related.get(resourceType, "", ownerReference.Name)
+			}
+			if !ok {
+				continue
+			}
+			next = append(next, obj.(*unstructured.Unstructured).GetOwnerReferences()...)
+		}
+		pending = next
+	}
+
+	return owners
+}
+
+func ownerIdentity(apiVersion, kind, name string) string {
+	return CustomResourceType(groupOfAPIVersion(apiVersion), kind) + "/" + name
+}
+
+func groupOfAPIVersion(apiVersion string) string {
+	groupVersion, err := schema.ParseGroupVersion(apiVersion)
+	if err != nil {
+		return ""
+	}
+
+	return groupVersion.Group
+}
+
+//customResourceSelector converts value of selector path into a label selector. Value is either a label selector
+//or a map of labels.
+func customResourceSelector(value interface{}) (*meta_v1.LabelSelector, bool) {
+	content, ok := value.(map[string]interface{})
+	if !ok || len(content) == 0 {
+		return nil, false
+	}
+
+	labelSelector := &meta_v1.LabelSelector{}
+	_, hasMatchLabels := content["matchLabels"]
+	_, hasMatchExpressions := content["matchExpressions"]
+	if hasMatchLabels || hasMatchExpressions {
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(content, labelSelector); err != nil {
+			return nil, false
+		}
+	} else {
+		labelSelector.MatchLabels = make(map[string]string)
+		for key, value := range content {
+			stringValue, ok := value.(string)
+			if !ok {
+				return nil, false
+			}
+			labelSelector.MatchLabels[key] = stringValue
+		}
+	}
+
+	if len(labelSelector.MatchLabels) == 0 && len(labelSelector.MatchExpressions) == 0 {
+		return nil, false
+	}
+
+	return labelSelector, true
+}
+
+//serviceOfHost returns name and namespace of service of host e.g. 'reviews', 'reviews.prod' or
+//'reviews.prod.svc.cluster.local'. Namespace defaults to given namespace.
+func serviceOfHost(host, namespace string) (string, string) {
+	parts := strings.Split(host, ".")
+	if len(parts) > 1 && parts[1] != "" {
+		namespace = parts[1]
+	}
+
+	return parts[0], namespace
+}
+
+//evaluateJSONPath returns values found at path of content. Missing keys yield no values.
+//A parser is created for every evaluation since JSONPath keeps state while evaluating.
+func evaluateJSONPath(path string, content map[string]interface{}) []interface{} {
+	parser := jsonpath.New("rule").AllowMissingKeys(true)
+	if err := parser.Parse(path); err != nil {
+		return nil
+	}
+
+	results, err := parser.FindResults(content)
+	if err != nil {
+		return nil
+	}
+
+	var values []interface{}
+	for _, result := range results {
+		for _, value := range result {
+			if value.IsValid() && value.CanInterface() {
+				values = append(values, value.Interface())
+			}
+		}
+	}
+
+	return values
+}
+
+//jsonPathStrings returns non empty strings found at path of content. Lists of strings are flattened.
+func jsonPathStrings(path string, content map[string]interface{}) []string {
+	var strs []string
+
+	var add func(value interface{})
+	add = func(value interface{}) {
+		switch v := value.(type) {
+		case string:
+			if v != "" {
+				strs = append(strs, v)
+			}
+		case []interface{}:
+			for _, item := range v {
+				add(item)
+			}
+		}
+	}
+
+	for _, value := range evaluateJSONPath(path, content) {
+		add(value)
+	}
+
+	return strs
+}
+
+func withoutCustomResourceType(customResources []unstructured.Unstructured, resourceType string) []unstructured.Unstructured {
+	var filtered []unstructured.Unstructured
+	for _, customResource := range customResources {
+		if customResourceTypeOf(&customResource) != resourceType {
+			filtered = append(filtered, customResource)
+		}
+	}
+
+	return filtered
+}
+
+func sortCustomResources(customResources []unstructured.Unstructured) []unstructured.Unstructured {
+	sort.Slice(customResources, func(i, j int) bool {
+		a, b := customResourceTypeOf(&customResources[i]), customResourceTypeOf(&customResources[j])
+		if a != b {
+			return a < b
+		}
+		return customResources[i].GetName() < customResources[j].GetName()
+	})
+
+	return customResources
+}
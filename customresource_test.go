@@ -0,0 +1,189 @@
+package kubemap
+
+import (
+	"bytes"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestLoadCustomResourceRules(t *testing.T) {
+	rules := helperGetCustomResourceRules()
+	assert.Len(t, rules, 4)
+	assert.Equal(t, CustomResourceRule{
+		Group:    "argoproj.io",
+		Kind:     "Rollout",
+		Selector: "{.spec.selector}",
+		Owner:    "{.spec.workloadRef.name}",
+	}, rules[0])
+	assert.Equal(t, "virtualservice.networking.istio.io", rules[1].resourceType())
+}
+
+func TestLoadInvalidCustomResourceRules(t *testing.T) {
+	invalidRules := map[string]string{
+		"Without_Kind":     "rules:\n  - group: argoproj.io\n",
+		"Invalid_JSONPath": "rules:\n  - group: argoproj.io\n    kind: Rollout\n    selector: '{.spec.selector'\n",
+		"Unknown_Field":    "rules:\n  - group: argoproj.io\n    kind: Rollout\n    selectors: '{.spec.selector}'\n",
+		"Built_In_Type":    "rules:\n  - kind: Endpoints\n",
+	}
+
+	for testName, content := range invalidRules {
+		t.Run(testName, func(t *testing.T) {
+			_, err := LoadCustomResourceRules(strings.NewReader(content))
+			assert.NotNil(t, err)
+		})
+	}
+}
+
+func TestDuplicateCustomResourceRules(t *testing.T) {
+	rules := helperGetCustomResourceRules()
+
+	_, err := NewMapperWithOptions(MapOptions{CustomResources: append(rules, rules[0])})
+	assert.NotNil(t, err)
+}
+
+func TestCustomResourcesAreAttached(t *testing.T) {
+	mapper, err := NewMapperWithOptions(MapOptions{CustomResources: helperGetCustomResourceRules()})
+	assert.Nil(t, err)
+
+	mappedResources, err := mapper.Map(helperGetCustomResources())
+	assert.Nil(t, err)
+	assert.Len(t, mappedResources.MappedResource, 1)
+
+	var attached []string
+	for _, customResource := range mappedResources.MappedResource[0].Kube.CustomResources {
+		attached = append(attached, customResourceTypeOf(&customResource)+"/"+customResource.GetName())
+	}
+	assert.Equal(t, []string{
+		"configuration.serving.knative.dev/kube-map",
+		"revision.serving.knative.dev/kube-map-00001",
+		"rollout.argoproj.io/by-selector",
+		"rollout.argoproj.io/by-workload",
+		"virtualservice.networking.istio.io/kube-map",
+	}, attached)
+}
+
+func TestCustomResourcesWithoutRulesAreNotAttached(t *testing.T) {
+	mappedResources, err := NewMapper().Map(helperGetCustomResources())
+	assert.Nil(t, err)
+	assert.Len(t, mappedResources.MappedResource, 1)
+	assert.Empty(t, mappedResources.MappedResource[0].Kube.CustomResources)
+}
+
+func TestServiceOfHost(t *testing.T) {
+	hostTests := map[string]struct {
+		host      string
+		name      string
+		namespace string
+	}{
+		"Name":      {host: "reviews", name: "reviews", namespace: "shop"},
+		"Namespace": {host: "reviews.prod", name: "reviews", namespace: "prod"},
+		"FQDN":      {host: "reviews.prod.svc.cluster.local", name: "reviews", namespace: "prod"},
+	}
+
+	for testName, test := range hostTests {
+		t.Run(testName, func(t *testing.T) {
+			name, namespace := serviceOfHost(test.host, "shop")
+			assert.Equal(t, test.name, name)
+			assert.Equal(t, test.namespace, namespace)
+		})
+	}
+}
+
+func TestCustomResourcesConsistency(t *testing.T) {
+	events := resourceEventsForMapping(helperGetCustomResources())
+	options := MapOptions{CustomResources: helperGetCustomResourceRules()}
+	random := rand.New(rand.NewSource(40))
+
+	for i := 0; i < 30; i++ {
+		random.Shuffle(len(events), func(a, b int) {
+			events[a], events[b] = events[b], events[a]
+		})
+
+		report, err := CheckConsistency(events, options)
+		assert.Nil(t, err)
+		assert.True(t, report.Consistent, "order %s diverged - %+v", helperEventOrder(events), report.Divergences)
+	}
+}
+
+func helperGetCustomResourceRules() []CustomResourceRule {
+	rules, err := LoadCustomResourceRules(bytes.NewReader(helperGetFileContent("customresource-rules.yaml")))
+	if err != nil {
+		panic(err)
+	}
+
+	return rules
+}
+
+//helperGetCustomResources returns fixtures along with rollouts selecting fixture pods or referring to fixture
+//deployment, a virtual service routing to fixture service, a chain of knative objects owning fixture deployment
+//and custom resources of other groups.
+func helperGetCustomResources() KubeResources {
+	resources := helperGetK8sResources()
+	namespace := resources.Deployments[0].Namespace
+
+	resources.Deployments[0].OwnerReferences = []meta_v1.OwnerReference{
+		{APIVersion: "serving.knative.dev/v1", Kind: "Revision", Name: "kube-map-00001"},
+	}
+
+	resources.CustomResources = []unstructured.Unstructured{
+		helperCustomResource("argoproj.io/v1alpha1", "Rollout", "by-selector", namespace, map[string]interface{}{
+			"spec": map[string]interface{}{
+				"selector": map[string]interface{}{"matchLabels": map[string]interface{}{"test": "map"}},
+			},
+		}),
+		helperCustomResource("argoproj.io/v1alpha1", "Rollout", "by-workload", namespace, map[string]interface{}{
+			"spec": map[string]interface{}{
+				"workloadRef": map[string]interface{}{"kind": "Deployment", "name": resources.Deployments[0].Name},
+			},
+		}),
+		helperCustomResource("argoproj.io/v1alpha1", "Rollout", "other", namespace, map[string]interface{}{
+			"spec": map[string]interface{}{
+				"selector": map[string]interface{}{"matchLabels": map[string]interface{}{"test": "other"}},
+			},
+		}),
+		helperCustomResource("networking.istio.io/v1beta1", "VirtualService", "kube-map", namespace, map[string]interface{}{
+			"spec": map[string]interface{}{
+				"http": []interface{}{
+					map[string]interface{}{
+						"route": []interface{}{
+							map[string]interface{}{"destination": map[string]interface{}{"host": resources.Services[0].Name + "." + namespace + ".svc.cluster.local"}},
+						},
+					},
+				},
+			},
+		}),
+		helperCustomResource("serving.knative.dev/v1", "Revision", "kube-map-00001", namespace, map[string]interface{}{
+			"metadata": map[string]interface{}{
+				"ownerReferences": []interface{}{
+					map[string]interface{}{"apiVersion": "serving.knative.dev/v1", "kind": "Configuration", "name": "kube-map", "uid": "1"},
+				},
+			},
+		}),
+		helperCustomResource("serving.knative.dev/v1", "Configuration", "kube-map", namespace, nil),
+		helperCustomResource("serving.knative.dev/v1", "Configuration", "unrelated", namespace, nil),
+	}
+
+	return resources
+}
+
+func helperCustomResource(apiVersion, kind, name, namespace string, content map[string]interface{}) unstructured.Unstructured {
+	object := map[string]interface{}{
+		"apiVersion": apiVersion,
+		"kind":       kind,
+	}
+	for key, value := range content {
+		object[key] = value
+	}
+
+	customResource := unstructured.Unstructured{Object: object}
+	customResource.SetName(name)
+	customResource.SetNamespace(namespace)
+
+	return customResource
+}
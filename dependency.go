@@ -0,0 +1,237 @@
+package kubemap
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+
+	core_v1 "k8s.io/api/core/v1"
+)
+
+const (
+	//DependencyConfidenceHigh is confidence of service DNS names qualified with 'svc' e.g. 'api.shop.svc.cluster.local'.
+	DependencyConfidenceHigh = 0.9
+	//DependencyConfidenceMedium is confidence of names qualified with namespace only e.g. 'api.shop'.
+	DependencyConfidenceMedium = 0.6
+	//DependencyConfidenceLow is confidence of bare service names e.g. 'http://api:8080', which are resolved in
+	//namespace of group referring them.
+	DependencyConfidenceLow = 0.3
+)
+
+//Sources of dependency evidence.
+const (
+	DependencySourceEnv       = "env"
+	DependencySourceConfigMap = "configmap"
+)
+
+//hostPattern matches host names in env values and config map data. Values are lower cased before matching.
+var hostPattern = regexp.MustCompile(`[a-z0-9]([a-z0-9-]*[a-z0-9])?(\.[a-z0-9]([a-z0-9-]*[a-z0-9])?)*`)
+
+//Dependency is an inferred edge from a group to a group whose services it refers to by DNS name.
+type Dependency struct {
+	Namespace         string `json:"namespace"`
+	CommonLabel       string `json:"commonLabel"`
+	TargetNamespace   string `json:"targetNamespace"`
+	TargetCommonLabel string `json:"targetCommonLabel"`
+	//Confidence is highest confidence of evidence between 0 and 1.
+	Confidence float64              `json:"confidence"`
+	Evidence   []DependencyEvidence `json:"evidence"`
+}
+
+//DependencyEvidence is a service DNS name found in an env var of a container or a key of a config map.
+//Values themselves are not kept as they may hold credentials.
+type DependencyEvidence struct {
+	Source string `json:"source"`
+	//Object is name of container or config map.
+	Object string `json:"object"`
+	//Key is name of env var or key of config map.
+	Key string `json:"key"`
+	//Host is DNS name as found e.g. 'api.shop.svc.cluster.local'.
+	Host       string  `json:"host"`
+	Service    string  `json:"service"`
+	Confidence float64 `json:"confidence"`
+}
+
+//serviceGroup is group of a service by namespace and name of service.
+type serviceGroup struct {
+	namespace   string
+	commonLabel string
+}
+
+//Dependencies infers dependency edges between mapped resources in store.
+func (m *Mapper) Dependencies() []Dependency {
+	return InferDependencies(getAllMappedResources(m.store))
+}
+
+//InferDependencies scans literal env values of containers and data of config maps of every group for DNS names of
+//services of other groups. Names are 'service', 'service.namespace' or 'service.namespace.svc' with optional
+//cluster domain. Bare service names are only taken when they are whole value or host of a URL or 'host:port'.
+//Edges are sorted by source and target. Literal env values are redacted in output of Map and StoreMap unless
+//RedactionOptions.KeepEnvValues is set, so edges from env are only inferred from unredacted mapped resources e.g. those
+//of store as scanned by Mapper.Dependencies and Mapper.Hierarchy.
+func InferDependencies(mappedResources MappedResources) []Dependency {
+	services := make(map[string]serviceGroup)
+	for _, mappedResource := range mappedResources.MappedResource {
+		for _, service := range mappedResource.Kube.Services {
+			services[service.Namespace+"/"+service.Name] = serviceGroup{namespace: mappedResource.Namespace, commonLabel: mappedResource.CommonLabel}
+		}
+	}
+
+	var dependencies []Dependency
+	for _, mappedResource := range mappedResources.MappedResource {
+		edges := make(map[serviceGroup]*Dependency)
+		seen := make(map[DependencyEvidence]bool)
+
+		for _, evidence := range groupDependencyEvidence(mappedResource) {
+			target, ok := services[evidence.namespace+"/"+evidence.Service]
+			if !ok || (target.namespace == mappedResource.Namespace && target.commonLabel == mappedResource.CommonLabel) {
+				continue
+			}
+			if seen[evidence.DependencyEvidence] {
+				continue
+			}
+			seen[evidence.DependencyEvidence] = true
+
+			edge, exists := edges[target]
+			if !exists {
+				edge = &Dependency{
+					Namespace:         mappedResource.Namespace,
+					CommonLabel:       mappedResource.CommonLabel,
+					TargetNamespace:   target.namespace,
+					TargetCommonLabel: target.commonLabel,
+				}
+				edges[target] = edge
+			}
+			edge.Evidence = append(edge.Evidence, evidence.DependencyEvidence)
+			if evidence.Confidence > edge.Confidence {
+				edge.Confidence = evidence.Confidence
+			}
+		}
+
+		for _, edge := range edges {
+			sort.Slice(edge.Evidence, func(i, j int) bool {
+				a, b := edge.Evidence[i], edge.Evidence[j]
+				if a.Source != b.Source {
+					return a.Source < b.Source
+				}
+				if a.Object != b.Object {
+					return a.Object < b.Object
+				}
+				if a.Key != b.Key {
+					return a.Key < b.Key
+				}
+				return a.Host < b.Host
+			})
+			dependencies = append(dependencies, *edge)
+		}
+	}
+
+	sort.Slice(dependencies, func(i, j int) bool {
+		a, b := dependencies[i], dependencies[j]
+		if a.Namespace != b.Namespace {
+			return a.Namespace < b.Namespace
+		}
+		if a.CommonLabel != b.CommonLabel {
+			return a.CommonLabel < b.CommonLabel
+		}
+		if a.TargetNamespace != b.TargetNamespace {
+			return a.TargetNamespace < b.TargetNamespace
+		}
+		return a.TargetCommonLabel < b.TargetCommonLabel
+	})
+
+	return dependencies
+}
+
+//namespacedEvidence is evidence along with namespace of service it refers to.
+type namespacedEvidence struct {
+	DependencyEvidence
+	namespace string
+}
+
+//groupDependencyEvidence returns service names found in env of pods and pod templates of deployments and in config
+//maps of group.
+func groupDependencyEvidence(mappedResource MappedResource) []namespacedEvidence {
+	var evidence []namespacedEvidence
+
+	var podSpecs []core_v1.PodSpec
+	for _, pod := range mappedResource.Kube.Pods {
+		podSpecs = append(podSpecs, pod.Spec)
+	}
+	for _, deployment := range mappedResource.Kube.Deployments {
+		podSpecs = append(podSpecs, deployment.Spec.Template.Spec)
+	}
+
+	for _, podSpec := range podSpecs {
+		containers := append(append([]core_v1.Container(nil), podSpec.InitContainers...), podSpec.Containers...)
+		for _, container := range containers {
+			for _, env := range container.Env {
+				for _, found := range serviceNames(env.Value, mappedResource.Namespace) {
+					found.Source, found.Object, found.Key = DependencySourceEnv, container.Name, env.Name
+					evidence = append(evidence, found)
+				}
+			}
+		}
+	}
+
+	for _, configMap := range mappedResource.Kube.ConfigMaps {
+		for key, value := range configMap.Data {
+			for _, found := range serviceNames(value, mappedResource.Namespace) {
+				found.Source, found.Object, found.Key = DependencySourceConfigMap, configMap.Name, key
+				evidence = append(evidence, found)
+			}
+		}
+	}
+
+	return evidence
+}
+
+//serviceNames returns candidate service names in value. Bare names are resolved in given namespace.
+func serviceNames(value, namespace string) []namespacedEvidence {
+	value = strings.ToLower(value)
+
+	var names []namespacedEvidence
+	for _, match := range hostPattern.FindAllStringIndex(value, -1) {
+		host := value[match[0]:match[1]]
+		parts := strings.Split(host, ".")
+
+		//Names of services start with a letter, which skips numbers and IPs.
+		if host[0] < 'a' || host[0] > 'z' {
+			continue
+		}
+
+		found := namespacedEvidence{DependencyEvidence: DependencyEvidence{Host: host, Service: parts[0]}}
+		switch {
+		case len(parts) >= 3 && parts[2] == "svc":
+			found.namespace, found.Confidence = parts[1], DependencyConfidenceHigh
+		case len(parts) == 2:
+			found.namespace, found.Confidence = parts[1], DependencyConfidenceMedium
+		case len(parts) == 1 && isBareHost(value, match[0], match[1]):
+			found.namespace, found.Confidence = namespace, DependencyConfidenceLow
+		default:
+			continue
+		}
+		names = append(names, found)
+	}
+
+	return names
+}
+
+//isBareHost is true if value[start:end] is whole value or host of a URL or of 'host:port'.
+func isBareHost(value string, start, end int) bool {
+	if start == 0 {
+		//Leading word followed by ':' is a scheme or a key e.g. 'http:' unless a port follows.
+		return end == len(value) || (value[end] == ':' && end+1 < len(value) && value[end+1] >= '0' && value[end+1] <= '9')
+	}
+
+	before := strings.HasSuffix(value[:start], "//") || strings.HasSuffix(value[:start], "@")
+	after := end == len(value) || value[end] == ':' || value[end] == '/'
+
+	//User of a URL e.g. 'user' of '//user:pass@db' is not a host.
+	authority := value[end:]
+	if index := strings.Index(authority, "/"); index >= 0 {
+		authority = authority[:index]
+	}
+
+	return before && after && !strings.Contains(authority, "@")
+}
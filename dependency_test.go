@@ -0,0 +1,155 @@
+package kubemap
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	apps_v1 "k8s.io/api/apps/v1"
+	core_v1 "k8s.io/api/core/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestServiceNames(t *testing.T) {
+	tests := []struct {
+		value      string
+		hosts      []string
+		namespaces []string
+		confidence []float64
+	}{
+		{value: "api", hosts: []string{"api"}, namespaces: []string{"shop"}, confidence: []float64{DependencyConfidenceLow}},
+		{value: "http://api:8080/v1", hosts: []string{"api"}, namespaces: []string{"shop"}, confidence: []float64{DependencyConfidenceLow}},
+		{value: "api:8080", hosts: []string{"api"}, namespaces: []string{"shop"}, confidence: []float64{DependencyConfidenceLow}},
+		{value: "postgres://user:pass@db:5432/orders", hosts: []string{"db"}, namespaces: []string{"shop"}, confidence: []float64{DependencyConfidenceLow}},
+		{value: "api.billing", hosts: []string{"api.billing"}, namespaces: []string{"billing"}, confidence: []float64{DependencyConfidenceMedium}},
+		{value: "grpc://API.Billing.svc.cluster.local:9090", hosts: []string{"api.billing.svc.cluster.local"}, namespaces: []string{"billing"}, confidence: []float64{DependencyConfidenceHigh}},
+		{value: "cache.shop.svc,db.data.svc", hosts: []string{"cache.shop.svc", "db.data.svc"}, namespaces: []string{"shop", "data"}, confidence: []float64{DependencyConfidenceHigh, DependencyConfidenceHigh}},
+		{value: "log level is debug"},
+		{value: "8080"},
+		{value: "10.0.0.1"},
+		{value: "http:"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			var hosts, namespaces []string
+			var confidence []float64
+			for _, found := range serviceNames(tt.value, "shop") {
+				hosts = append(hosts, found.Host)
+				namespaces = append(namespaces, found.namespace)
+				confidence = append(confidence, found.Confidence)
+			}
+
+			assert.Equal(t, tt.hosts, hosts)
+			assert.Equal(t, tt.namespaces, namespaces)
+			assert.Equal(t, tt.confidence, confidence)
+		})
+	}
+}
+
+func TestInferDependencies(t *testing.T) {
+	dependencies := InferDependencies(helperGetDependencyMappedResources())
+	assert.Len(t, dependencies, 2)
+
+	assert.Equal(t, "frontend", dependencies[0].CommonLabel)
+	assert.Equal(t, "billing", dependencies[0].TargetNamespace)
+	assert.Equal(t, "payments", dependencies[0].TargetCommonLabel)
+	assert.Equal(t, DependencyConfidenceHigh, dependencies[0].Confidence)
+	assert.Equal(t, []DependencyEvidence{
+		{Source: DependencySourceConfigMap, Object: "frontend-config", Key: "settings.yaml", Host: "payments.billing.svc.cluster.local", Service: "payments", Confidence: DependencyConfidenceHigh},
+	}, dependencies[0].Evidence)
+
+	//Same env var of pod and template of deployment is evidence once.
+	assert.Equal(t, "frontend", dependencies[1].CommonLabel)
+	assert.Equal(t, "shop", dependencies[1].TargetNamespace)
+	assert.Equal(t, "orders", dependencies[1].TargetCommonLabel)
+	assert.Equal(t, DependencyConfidenceLow, dependencies[1].Confidence)
+	assert.Equal(t, []DependencyEvidence{
+		{Source: DependencySourceEnv, Object: "web", Key: "ORDERS_URL", Host: "orders", Service: "orders", Confidence: DependencyConfidenceLow},
+	}, dependencies[1].Evidence)
+}
+
+func TestDependenciesOfMapper(t *testing.T) {
+	resources := helperGetK8sResources()
+	namespace := resources.Services[0].Namespace
+	resources.Pods[0].Spec.Containers[0].Env = []core_v1.EnvVar{
+		{Name: "SELF", Value: resources.Services[0].Name},
+		{Name: "ORDERS_URL", Value: "http://orders:8080"},
+	}
+	resources.Services = append(resources.Services, core_v1.Service{
+		ObjectMeta: meta_v1.ObjectMeta{Name: "orders", Namespace: namespace, UID: "orders"},
+		Spec:       core_v1.ServiceSpec{Selector: map[string]string{"app": "orders"}},
+	})
+
+	mapper := NewMapper()
+	mappedResources, err := mapper.Map(resources)
+	assert.Nil(t, err)
+	assert.Len(t, mappedResources.MappedResource, 2)
+
+	//Groups referring their own services have no dependency.
+	dependencies := mapper.Dependencies()
+	assert.Len(t, dependencies, 1)
+	assert.Equal(t, namespace, dependencies[0].TargetNamespace)
+	assert.Equal(t, "orders", dependencies[0].TargetCommonLabel)
+	assert.NotEqual(t, "orders", dependencies[0].CommonLabel)
+	assert.Equal(t, []DependencyEvidence{
+		{Source: DependencySourceEnv, Object: resources.Pods[0].Spec.Containers[0].Name, Key: "ORDERS_URL", Host: "orders", Service: "orders", Confidence: DependencyConfidenceLow},
+	}, dependencies[0].Evidence)
+	assert.Equal(t, dependencies, mapper.Hierarchy().Dependencies)
+
+	//Env values of Map output are redacted.
+	assert.Empty(t, BuildHierarchy(mappedResources).Dependencies)
+}
+
+func TestHierarchyIncludesDependencies(t *testing.T) {
+	hierarchy := BuildHierarchy(helperGetDependencyMappedResources())
+	assert.Len(t, hierarchy.Dependencies, 2)
+
+	schemaBytes, err := ioutil.ReadFile("schema/hierarchy.schema.json")
+	assert.Nil(t, err)
+
+	var schema map[string]interface{}
+	assert.Nil(t, json.Unmarshal(schemaBytes, &schema))
+
+	hierarchyBytes, err := json.Marshal(hierarchy)
+	assert.Nil(t, err)
+
+	var decoded interface{}
+	assert.Nil(t, json.Unmarshal(hierarchyBytes, &decoded))
+	assert.Empty(t, helperSchemaViolations(schema, schema, decoded, "$"))
+}
+
+//helperGetDependencyMappedResources returns group 'frontend' referring service 'orders' of its namespace by env and
+//service 'payments' of namespace 'billing' by config map, and groups of those services.
+func helperGetDependencyMappedResources() MappedResources {
+	frontend := MappedResource{CommonLabel: "frontend", Namespace: "shop"}
+	podSpec := core_v1.PodSpec{Containers: []core_v1.Container{{
+		Name: "web",
+		Env: []core_v1.EnvVar{
+			{Name: "ORDERS_URL", Value: "http://orders:8080"},
+			{Name: "PAYMENTS_PASSWORD", ValueFrom: &core_v1.EnvVarSource{}},
+			{Name: "UNKNOWN_URL", Value: "http://inventory:8080"},
+			{Name: "SELF_URL", Value: "http://frontend.shop.svc"},
+		},
+	}}}
+	frontend.Kube.Pods = []core_v1.Pod{{ObjectMeta: meta_v1.ObjectMeta{Name: "frontend-1", Namespace: "shop"}, Spec: podSpec}}
+	frontend.Kube.Deployments = []apps_v1.Deployment{{
+		ObjectMeta: meta_v1.ObjectMeta{Name: "frontend", Namespace: "shop"},
+		Spec:       apps_v1.DeploymentSpec{Template: core_v1.PodTemplateSpec{Spec: podSpec}},
+	}}
+	frontend.Kube.Services = []core_v1.Service{{ObjectMeta: meta_v1.ObjectMeta{Name: "frontend", Namespace: "shop"}}}
+	frontend.Kube.ConfigMaps = []core_v1.ConfigMap{{
+		ObjectMeta: meta_v1.ObjectMeta{Name: "frontend-config", Namespace: "shop"},
+		Data:       map[string]string{"settings.yaml": "payments:\n  endpoint: https://payments.billing.svc.cluster.local/api\n"},
+	}}
+
+	orders := MappedResource{CommonLabel: "orders", Namespace: "shop"}
+	orders.Kube.Services = []core_v1.Service{{ObjectMeta: meta_v1.ObjectMeta{Name: "orders", Namespace: "shop"}}}
+
+	payments := MappedResource{CommonLabel: "payments", Namespace: "billing"}
+	payments.Kube.Services = []core_v1.Service{{ObjectMeta: meta_v1.ObjectMeta{Name: "payments", Namespace: "billing"}}}
+
+	return MappedResources{MappedResource: []MappedResource{frontend, orders, payments}}
+}
@@ -0,0 +1,527 @@
+package kubemap
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+//diffMemberKinds are kinds of members by JSON field of Kube. Custom resources are taken by their own type.
+var diffMemberKinds = map[string]string{
+	"ingresses":                "ingress",
+	"services":                 "service",
+	"deployments":              "deployment",
+	"replicaSets":              "replicaset",
+	"pods":                     "pod",
+	"horizontalPodAutoscalers": "hpa",
+	"configMaps":               "configmap",
+	"secrets":                  "secret",
+	"persistentVolumeClaims":   "pvc",
+	"persistentVolumes":        "pv",
+	"endpoints":                "endpoints",
+	"endpointSlices":           "endpointslice",
+	"podDisruptionBudgets":     "pdb",
+	"networkPolicies":          "networkpolicy",
+	"serviceAccounts":          "serviceaccount",
+	"roles":                    "role",
+	"roleBindings":             "rolebinding",
+	"clusterRoles":             "clusterrole",
+	"clusterRoleBindings":      "clusterrolebinding",
+	"gateways":                 "gateway",
+	"httpRoutes":               "httproute",
+	"grpcRoutes":               "grpcroute",
+	"referenceGrants":          "referencegrant",
+	"nodes":                    "node",
+}
+
+//countedKinds have generated names which differ between environments and runs, so they are compared by count.
+var countedKinds = []string{"endpointslice", "node", "pod", "replicaset"}
+
+//defaultDiffIgnoreFields are fields set by cluster rather than by manifests.
+var defaultDiffIgnoreFields = []string{
+	"status",
+	"metadata.uid",
+	"metadata.resourceVersion",
+	"metadata.creationTimestamp",
+	"metadata.generation",
+	"metadata.selfLink",
+	"metadata.managedFields",
+	"metadata.namespace",
+	"metadata.ownerReferences",
+	"metadata.annotations[" + DeploymentRevisionAnnotation + "]",
+	"metadata.annotations[kubectl.kubernetes.io/last-applied-configuration]",
+	"spec.clusterIP",
+	"spec.ports.nodePort",
+}
+
+var listIndexPattern = regexp.MustCompile(`\[[0-9]+\]`)
+
+//DiffOptions sets how groups of two mappings are aligned and compared.
+type DiffOptions struct {
+	//Namespaces maps namespaces of first mapping to namespaces of second e.g. 'shop-staging' to 'shop'.
+	Namespaces map[string]string
+	//IgnoreFields are field paths of members which are not compared in addition to status and fields set by
+	//cluster e.g. 'spec.replicas' or 'metadata.labels[example.com/build]'. List indices are left out.
+	IgnoreFields []string
+}
+
+//MappingDiff is difference between two mappings e.g. of staging and production or before and after a deploy.
+type MappingDiff struct {
+	Added     []GroupRef  `json:"added,omitempty"`
+	Removed   []GroupRef  `json:"removed,omitempty"`
+	Changed   []GroupDiff `json:"changed,omitempty"`
+	Unchanged int         `json:"unchanged"`
+}
+
+//GroupRef identifies a group of a mapping.
+type GroupRef struct {
+	Namespace   string `json:"namespace"`
+	CommonLabel string `json:"commonLabel"`
+}
+
+//GroupDiff is difference of a group present in both mappings. Namespace and common label are of second mapping.
+type GroupDiff struct {
+	GroupRef
+	//Previous is group of first mapping if its namespace or common label differs.
+	Previous       *GroupRef `json:"previous,omitempty"`
+	AddedMembers   []string  `json:"addedMembers,omitempty"`
+	RemovedMembers []string  `json:"removedMembers,omitempty"`
+	//Counts are changed number of members of kinds with generated names e.g. pods.
+	Counts  []MemberCountDiff `json:"counts,omitempty"`
+	Members []MemberDiff      `json:"members,omitempty"`
+}
+
+//MemberCountDiff is changed number of members of a kind.
+type MemberCountDiff struct {
+	Kind string `json:"kind"`
+	From int    `json:"from"`
+	To   int    `json:"to"`
+}
+
+//MemberDiff is changed fields of a member present in both mappings e.g. 'deployment/api'.
+type MemberDiff struct {
+	Member string      `json:"member"`
+	Fields []FieldDiff `json:"fields"`
+}
+
+//FieldDiff is a changed field e.g. 'spec.template.spec.containers[0].image'. Values are JSON and empty if field is
+//not set.
+type FieldDiff struct {
+	Path string `json:"path"`
+	From string `json:"from,omitempty"`
+	To   string `json:"to,omitempty"`
+}
+
+//groupSnapshot is a group with its members prepared for comparison.
+type groupSnapshot struct {
+	ref GroupRef
+	//aligned is namespace and common label used to align group with other mapping.
+	aligned GroupRef
+	members map[string]map[string]interface{}
+	counts  map[string]int
+	source  MappedResource
+}
+
+//LoadSnapshot reads mapped resources of a snapshot written by Mapper.Snapshot.
+//They are redacted by default RedactionOptions same as output of Map.
+func LoadSnapshot(r io.Reader) (MappedResources, error) {
+	snap, err := readSnapshot(r)
+	if err != nil {
+		return MappedResources{}, err
+	}
+
+	redactor, err := newRedactor(RedactionOptions{})
+	if err != nil {
+		return MappedResources{}, err
+	}
+
+	return redactor.redactMappedResources(MappedResources{MappedResource: snap.MappedResources}), nil
+}
+
+//DiffMappings compares groups of two mappings. Groups are aligned by namespace and common label, and groups left
+//over are aligned by most shared members within a namespace, so renamed groups are reported as changed.
+//Members are compared by 'kind/name', except pods, replica sets, endpoint slices and nodes which are compared by
+//count. Fields of members present in both are compared leaving out status and fields set by cluster.
+func DiffMappings(from, to MappedResources, options DiffOptions) (MappingDiff, error) {
+	var diff MappingDiff
+
+	fromGroups, err := diffGroups(from, options.Namespaces)
+	if err != nil {
+		return diff, err
+	}
+	toGroups, err := diffGroups(to, nil)
+	if err != nil {
+		return diff, err
+	}
+
+	ignoreFields := append(append([]string(nil), defaultDiffIgnoreFields...), options.IgnoreFields...)
+	for _, pair := range alignGroups(fromGroups, toGroups) {
+		switch {
+		case pair[0] == nil:
+			diff.Added = append(diff.Added, pair[1].ref)
+		case pair[1] == nil:
+			diff.Removed = append(diff.Removed, pair[0].ref)
+		default:
+			groupDiff := diffGroup(pair[0], pair[1], ignoreFields)
+			if len(groupDiff.AddedMembers) == 0 && len(groupDiff.RemovedMembers) == 0 && len(groupDiff.Counts) == 0 &&
+				len(groupDiff.Members) == 0 && groupDiff.Previous == nil {
+				diff.Unchanged++
+				continue
+			}
+			diff.Changed = append(diff.Changed, groupDiff)
+		}
+	}
+
+	sortGroupRefs(diff.Added)
+	sortGroupRefs(diff.Removed)
+	sort.Slice(diff.Changed, func(i, j int) bool {
+		return lessGroupRef(diff.Changed[i].GroupRef, diff.Changed[j].GroupRef)
+	})
+
+	return diff, nil
+}
+
+//Empty is true if mappings have no difference.
+func (d MappingDiff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+//WriteText writes diff with a line per added, removed or changed group followed by its changes e.g.
+//'~ group shop/api' and '    ~ deployment/api spec.replicas: 2 -> 3'.
+func (d MappingDiff) WriteText(w io.Writer) error {
+	var lines []string
+	for _, group := range d.Added {
+		lines = append(lines, fmt.Sprintf("+ group %s/%s", group.Namespace, group.CommonLabel))
+	}
+	for _, group := range d.Removed {
+		lines = append(lines, fmt.Sprintf("- group %s/%s", group.Namespace, group.CommonLabel))
+	}
+
+	for _, group := range d.Changed {
+		line := fmt.Sprintf("~ group %s/%s", group.Namespace, group.CommonLabel)
+		if group.Previous != nil {
+			line += fmt.Sprintf(" (was %s/%s)", group.Previous.Namespace, group.Previous.CommonLabel)
+		}
+		lines = append(lines, line)
+
+		for _, member := range group.AddedMembers {
+			lines = append(lines, "    + "+member)
+		}
+		for _, member := range group.RemovedMembers {
+			lines = append(lines, "    - "+member)
+		}
+		for _, count := range group.Counts {
+			lines = append(lines, fmt.Sprintf("    ~ %s count: %d -> %d", count.Kind, count.From, count.To))
+		}
+		for _, member := range group.Members {
+			for _, field := range member.Fields {
+				lines = append(lines, fmt.Sprintf("    ~ %s %s: %s -> %s", member.Member, field.Path, orNone(field.From), orNone(field.To)))
+			}
+		}
+	}
+
+	for _, line := range lines {
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return fmt.Errorf("Cannot write diff - %v", err)
+		}
+	}
+
+	return nil
+}
+
+func orNone(value string) string {
+	if value == "" {
+		return "<none>"
+	}
+	return value
+}
+
+//diffGroups prepares groups of mapping for comparison. Namespaces are renamed as per given map for alignment.
+func diffGroups(mappedResources MappedResources, namespaces map[string]string) ([]*groupSnapshot, error) {
+	var groups []*groupSnapshot
+
+	for _, mappedResource := range mappedResources.MappedResource {
+		group := &groupSnapshot{
+			ref:     GroupRef{Namespace: mappedResource.Namespace, CommonLabel: mappedResource.CommonLabel},
+			members: make(map[string]map[string]interface{}),
+			counts:  make(map[string]int),
+			source:  mappedResource,
+		}
+		group.aligned = group.ref
+		if namespace, ok := namespaces[mappedResource.Namespace]; ok {
+			group.aligned.Namespace = namespace
+		}
+
+		content, err := json.Marshal(mappedResource.Kube)
+		if err != nil {
+			return nil, fmt.Errorf("Cannot compare Common Label %s - %v", mappedResource.CommonLabel, err)
+		}
+		var kube map[string]interface{}
+		if err := json.Unmarshal(content, &kube); err != nil {
+			return nil, fmt.Errorf("Cannot compare Common Label %s - %v", mappedResource.CommonLabel, err)
+		}
+
+		for field, kind := range diffMemberKinds {
+			items, _ := kube[field].([]interface{})
+			for _, item := range items {
+				object, _ := item.(map[string]interface{})
+				metadata, _ := object["metadata"].(map[string]interface{})
+				name, _ := metadata["name"].(string)
+				group.add(kind, name, object)
+			}
+		}
+
+		for i := range mappedResource.Kube.CustomResources {
+			customResource := &mappedResource.Kube.CustomResources[i]
+			group.add(customResourceTypeOf(customResource), customResource.GetName(), customResource.DeepCopy().Object)
+		}
+
+		groups = append(groups, group)
+	}
+
+	return groups, nil
+}
+
+func (g *groupSnapshot) add(kind, name string, object map[string]interface{}) {
+	for _, counted := range countedKinds {
+		if kind == counted {
+			g.counts[kind]++
+			return
+		}
+	}
+
+	g.members[kind+"/"+name] = object
+}
+
+//alignGroups pairs groups of two mappings. Pairs with a nil side are groups present in only one mapping.
+func alignGroups(fromGroups, toGroups []*groupSnapshot) [][2]*groupSnapshot {
+	var pairs [][2]*groupSnapshot
+
+	toByRef := make(map[GroupRef]*groupSnapshot)
+	for _, group := range toGroups {
+		toByRef[group.aligned] = group
+	}
+
+	matched := make(map[*groupSnapshot]bool)
+	var unmatchedFrom []*groupSnapshot
+	for _, group := range fromGroups {
+		if toGroup, ok := toByRef[group.aligned]; ok && !matched[toGroup] {
+			matched[toGroup] = true
+			pairs = append(pairs, [2]*groupSnapshot{group, toGroup})
+			continue
+		}
+		unmatchedFrom = append(unmatchedFrom, group)
+	}
+
+	//Left over groups are paired by shared members, most shared first.
+	type candidate struct {
+		from, to *groupSnapshot
+		score    float64
+	}
+	var candidates []candidate
+	for _, fromGroup := range unmatchedFrom {
+		for _, toGroup := range toGroups {
+			if matched[toGroup] || fromGroup.aligned.Namespace != toGroup.aligned.Namespace {
+				continue
+			}
+			if score := sharedMembers(fromGroup, toGroup); score > 0 {
+				candidates = append(candidates, candidate{from: fromGroup, to: toGroup, score: score})
+			}
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].score != candidates[j].score {
+			return candidates[i].score > candidates[j].score
+		}
+		if candidates[i].from.ref != candidates[j].from.ref {
+			return lessGroupRef(candidates[i].from.ref, candidates[j].from.ref)
+		}
+		return lessGroupRef(candidates[i].to.ref, candidates[j].to.ref)
+	})
+
+	paired := make(map[*groupSnapshot]bool)
+	for _, candidate := range candidates {
+		if paired[candidate.from] || matched[candidate.to] {
+			continue
+		}
+		paired[candidate.from] = true
+		matched[candidate.to] = true
+		pairs = append(pairs, [2]*groupSnapshot{candidate.from, candidate.to})
+	}
+
+	for _, group := range unmatchedFrom {
+		if !paired[group] {
+			pairs = append(pairs, [2]*groupSnapshot{group, nil})
+		}
+	}
+	for _, group := range toGroups {
+		if !matched[group] {
+			pairs = append(pairs, [2]*groupSnapshot{nil, group})
+		}
+	}
+
+	return pairs
+}
+
+//sharedMembers is ratio of members present in both groups to members present in either.
+func sharedMembers(a, b *groupSnapshot) float64 {
+	shared, total := 0, len(b.members)
+	for member := range a.members {
+		if _, ok := b.members[member]; ok {
+			shared++
+		} else {
+			total++
+		}
+	}
+
+	if total == 0 {
+		return 0
+	}
+	return float64(shared) / float64(total)
+}
+
+func diffGroup(from, to *groupSnapshot, ignoreFields []string) GroupDiff {
+	groupDiff := GroupDiff{GroupRef: to.ref}
+	if from.aligned != to.aligned {
+		previous := from.ref
+		groupDiff.Previous = &previous
+	}
+
+	for member, fromObject := range from.members {
+		toObject, ok := to.members[member]
+		if !ok {
+			groupDiff.RemovedMembers = append(groupDiff.RemovedMembers, member)
+			continue
+		}
+
+		var fields []FieldDiff
+		diffFields("", fromObject, toObject, ignoreFields, &fields)
+		if len(fields) > 0 {
+			groupDiff.Members = append(groupDiff.Members, MemberDiff{Member: member, Fields: fields})
+		}
+	}
+
+	for member := range to.members {
+		if _, ok := from.members[member]; !ok {
+			groupDiff.AddedMembers = append(groupDiff.AddedMembers, member)
+		}
+	}
+
+	for _, kind := range countedKinds {
+		if from.counts[kind] != to.counts[kind] {
+			groupDiff.Counts = append(groupDiff.Counts, MemberCountDiff{Kind: kind, From: from.counts[kind], To: to.counts[kind]})
+		}
+	}
+
+	sort.Strings(groupDiff.AddedMembers)
+	sort.Strings(groupDiff.RemovedMembers)
+	sort.Slice(groupDiff.Members, func(i, j int) bool {
+		return groupDiff.Members[i].Member < groupDiff.Members[j].Member
+	})
+
+	return groupDiff
+}
+
+//diffFields appends fields which differ between decoded JSON values. Lists are compared item by item.
+func diffFields(path string, from, to interface{}, ignoreFields []string, fields *[]FieldDiff) {
+	if ignoredField(path, ignoreFields) {
+		return
+	}
+
+	//Missing maps and lists are compared as empty so that only changed keys and items are reported.
+	fromMap, fromIsMap := from.(map[string]interface{})
+	toMap, toIsMap := to.(map[string]interface{})
+	if (fromIsMap || from == nil) && (toIsMap || to == nil) && (fromIsMap || toIsMap) {
+		keys := make(map[string]bool)
+		for key := range fromMap {
+			keys[key] = true
+		}
+		for key := range toMap {
+			keys[key] = true
+		}
+
+		var sorted []string
+		for key := range keys {
+			sorted = append(sorted, key)
+		}
+		sort.Strings(sorted)
+
+		for _, key := range sorted {
+			diffFields(fieldPath(path, key), fromMap[key], toMap[key], ignoreFields, fields)
+		}
+		return
+	}
+
+	fromList, fromIsList := from.([]interface{})
+	toList, toIsList := to.([]interface{})
+	if (fromIsList || from == nil) && (toIsList || to == nil) && (fromIsList || toIsList) {
+		length := len(fromList)
+		if len(toList) > length {
+			length = len(toList)
+		}
+
+		for i := 0; i < length; i++ {
+			var fromItem, toItem interface{}
+			if i < len(fromList) {
+				fromItem = fromList[i]
+			}
+			if i < len(toList) {
+				toItem = toList[i]
+			}
+			diffFields(fmt.Sprintf("%s[%d]", path, i), fromItem, toItem, ignoreFields, fields)
+		}
+		return
+	}
+
+	if !reflect.DeepEqual(from, to) {
+		*fields = append(*fields, FieldDiff{Path: path, From: fieldValue(from), To: fieldValue(to)})
+	}
+}
+
+//fieldPath appends key to path. Keys having dots or slashes e.g. annotation keys are enclosed in brackets.
+func fieldPath(path, key string) string {
+	if strings.ContainsAny(key, "./") {
+		return path + "[" + key + "]"
+	}
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+func fieldValue(value interface{}) string {
+	if value == nil {
+		return ""
+	}
+
+	content, _ := json.Marshal(value)
+	return string(content)
+}
+
+func ignoredField(path string, ignoreFields []string) bool {
+	path = listIndexPattern.ReplaceAllString(path, "")
+	for _, field := range ignoreFields {
+		if path == field || strings.HasPrefix(path, field+".") || strings.HasPrefix(path, field+"[") {
+			return true
+		}
+	}
+
+	return false
+}
+
+func sortGroupRefs(refs []GroupRef) {
+	sort.Slice(refs, func(i, j int) bool {
+		return lessGroupRef(refs[i], refs[j])
+	})
+}
+
+func lessGroupRef(a, b GroupRef) bool {
+	if a.Namespace != b.Namespace {
+		return a.Namespace < b.Namespace
+	}
+	return a.CommonLabel < b.CommonLabel
+}
@@ -0,0 +1,208 @@
+package kubemap
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	apps_v1 "k8s.io/api/apps/v1"
+	core_v1 "k8s.io/api/core/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestDiffMappings(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(to *MappedResources)
+		options DiffOptions
+		diff    MappingDiff
+	}{
+		{
+			name:   "same",
+			modify: func(to *MappedResources) {},
+			diff:   MappingDiff{Unchanged: 2},
+		},
+		{
+			name: "status and fields set by cluster",
+			modify: func(to *MappedResources) {
+				deployment := &to.MappedResource[0].Kube.Deployments[0]
+				deployment.ResourceVersion = "42"
+				deployment.UID = "other"
+				deployment.Annotations = map[string]string{DeploymentRevisionAnnotation: "7"}
+				deployment.Status.AvailableReplicas = 1
+			},
+			diff: MappingDiff{Unchanged: 2},
+		},
+		{
+			name: "added and removed groups",
+			modify: func(to *MappedResources) {
+				to.MappedResource = to.MappedResource[:1]
+				to.MappedResource = append(to.MappedResource, helperDiffGroup("billing", "payments"))
+			},
+			diff: MappingDiff{
+				Added:     []GroupRef{{Namespace: "billing", CommonLabel: "payments"}},
+				Removed:   []GroupRef{{Namespace: "shop", CommonLabel: "worker"}},
+				Unchanged: 1,
+			},
+		},
+		{
+			name: "members and fields",
+			modify: func(to *MappedResources) {
+				api := &to.MappedResource[0]
+				api.Kube.ConfigMaps[0].Name = "api-v2"
+				api.Kube.ConfigMaps[0].Data["mode"] = "fast"
+				api.Kube.Pods = append(api.Kube.Pods, *api.Kube.Pods[0].DeepCopy())
+				replicas := int32(3)
+				api.Kube.Deployments[0].Spec.Replicas = &replicas
+				api.Kube.Deployments[0].Spec.Template.Spec.Containers[0].Image = "api:2"
+				api.Kube.Services[0].Spec.ClusterIP = "10.0.0.2"
+			},
+			diff: MappingDiff{
+				Changed: []GroupDiff{{
+					GroupRef:       GroupRef{Namespace: "shop", CommonLabel: "api"},
+					AddedMembers:   []string{"configmap/api-v2"},
+					RemovedMembers: []string{"configmap/api-v1"},
+					Counts:         []MemberCountDiff{{Kind: "pod", From: 1, To: 2}},
+					Members: []MemberDiff{{
+						Member: "deployment/api",
+						Fields: []FieldDiff{
+							{Path: "spec.replicas", From: "2", To: "3"},
+							{Path: "spec.template.spec.containers[0].image", From: `"api:1"`, To: `"api:2"`},
+						},
+					}},
+				}},
+				Unchanged: 1,
+			},
+		},
+		{
+			name: "ignored fields",
+			modify: func(to *MappedResources) {
+				replicas := int32(3)
+				to.MappedResource[0].Kube.Deployments[0].Spec.Replicas = &replicas
+				to.MappedResource[0].Kube.Deployments[0].Labels = map[string]string{"example.com/build": "2"}
+			},
+			options: DiffOptions{IgnoreFields: []string{"spec.replicas", "metadata.labels[example.com/build]"}},
+			diff:    MappingDiff{Unchanged: 2},
+		},
+		{
+			name: "renamed group",
+			modify: func(to *MappedResources) {
+				to.MappedResource[0].CommonLabel = "shop-api"
+			},
+			diff: MappingDiff{
+				Changed: []GroupDiff{{
+					GroupRef: GroupRef{Namespace: "shop", CommonLabel: "shop-api"},
+					Previous: &GroupRef{Namespace: "shop", CommonLabel: "api"},
+				}},
+				Unchanged: 1,
+			},
+		},
+		{
+			name: "mapped namespace",
+			modify: func(to *MappedResources) {
+				for i := range to.MappedResource {
+					to.MappedResource[i].Namespace = "shop-production"
+				}
+				to.MappedResource[0].Kube.Deployments[0].Namespace = "shop-production"
+			},
+			options: DiffOptions{Namespaces: map[string]string{"shop": "shop-production"}},
+			diff:    MappingDiff{Unchanged: 2},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			to := helperGetDiffMappedResources()
+			tt.modify(&to)
+
+			diff, err := DiffMappings(helperGetDiffMappedResources(), to, tt.options)
+			assert.Nil(t, err)
+			assert.Equal(t, tt.diff, diff)
+			assert.Equal(t, len(tt.diff.Added)+len(tt.diff.Removed)+len(tt.diff.Changed) == 0, diff.Empty())
+		})
+	}
+}
+
+func TestMappingDiffText(t *testing.T) {
+	diff := MappingDiff{
+		Added:   []GroupRef{{Namespace: "billing", CommonLabel: "payments"}},
+		Removed: []GroupRef{{Namespace: "shop", CommonLabel: "worker"}},
+		Changed: []GroupDiff{{
+			GroupRef:       GroupRef{Namespace: "shop", CommonLabel: "shop-api"},
+			Previous:       &GroupRef{Namespace: "shop", CommonLabel: "api"},
+			AddedMembers:   []string{"configmap/api-v2"},
+			RemovedMembers: []string{"configmap/api-v1"},
+			Counts:         []MemberCountDiff{{Kind: "pod", From: 1, To: 2}},
+			Members:        []MemberDiff{{Member: "deployment/api", Fields: []FieldDiff{{Path: "spec.paused", To: "true"}}}},
+		}},
+	}
+
+	var buffer bytes.Buffer
+	assert.Nil(t, diff.WriteText(&buffer))
+	assert.Equal(t, strings.Join([]string{
+		"+ group billing/payments",
+		"- group shop/worker",
+		"~ group shop/shop-api (was shop/api)",
+		"    + configmap/api-v2",
+		"    - configmap/api-v1",
+		"    ~ pod count: 1 -> 2",
+		"    ~ deployment/api spec.paused: <none> -> true",
+		"",
+	}, "\n"), buffer.String())
+}
+
+func TestDiffSnapshots(t *testing.T) {
+	mapper := NewMapper()
+	_, err := mapper.Map(helperGetK8sResources())
+	assert.Nil(t, err)
+
+	var buffer bytes.Buffer
+	assert.Nil(t, mapper.Snapshot(&buffer))
+
+	from, err := LoadSnapshot(&buffer)
+	assert.Nil(t, err)
+	assert.Len(t, from.MappedResource, 1)
+
+	resources := helperGetK8sResources()
+	resources.Services = nil
+	to, err := NewMapper().Map(resources)
+	assert.Nil(t, err)
+
+	diff, err := DiffMappings(from, to, DiffOptions{})
+	assert.Nil(t, err)
+	assert.Len(t, diff.Changed, 1)
+	assert.Contains(t, diff.Changed[0].RemovedMembers, "service/"+helperGetK8sResources().Services[0].Name)
+
+	_, err = LoadSnapshot(strings.NewReader("{}"))
+	assert.NotNil(t, err)
+}
+
+//helperGetDiffMappedResources returns group 'api' with a deployment, a pod, a service and a config map and group
+//'worker' with a deployment.
+func helperGetDiffMappedResources() MappedResources {
+	api := helperDiffGroup("shop", "api")
+	api.Kube.Services = []core_v1.Service{{
+		ObjectMeta: meta_v1.ObjectMeta{Name: "api", Namespace: "shop"},
+		Spec:       core_v1.ServiceSpec{ClusterIP: "10.0.0.1", Ports: []core_v1.ServicePort{{Port: 80, NodePort: 30080}}},
+	}}
+	api.Kube.ConfigMaps = []core_v1.ConfigMap{{ObjectMeta: meta_v1.ObjectMeta{Name: "api-v1", Namespace: "shop"}, Data: map[string]string{"mode": "safe"}}}
+	api.Kube.Pods = []core_v1.Pod{{ObjectMeta: meta_v1.ObjectMeta{Name: "api-5d8f7b-x2k9q", Namespace: "shop"}}}
+
+	return MappedResources{MappedResource: []MappedResource{api, helperDiffGroup("shop", "worker")}}
+}
+
+func helperDiffGroup(namespace, name string) MappedResource {
+	replicas := int32(2)
+	mappedResource := MappedResource{Namespace: namespace, CommonLabel: name}
+	mappedResource.Kube.Deployments = []apps_v1.Deployment{{
+		ObjectMeta: meta_v1.ObjectMeta{Name: name, Namespace: namespace},
+		Spec: apps_v1.DeploymentSpec{
+			Replicas: &replicas,
+			Template: core_v1.PodTemplateSpec{Spec: core_v1.PodSpec{Containers: []core_v1.Container{{Name: name, Image: name + ":1"}}}},
+		},
+	}}
+
+	return mappedResource
+}
@@ -0,0 +1,273 @@
+package kubemap
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	apps_v1 "k8s.io/api/apps/v1"
+	core_v1 "k8s.io/api/core/v1"
+)
+
+const (
+	//DriftMissingLive is a member of manifests which does not exist in cluster.
+	DriftMissingLive = "MissingLive"
+	//DriftExtraLive is a member in cluster which is not in manifests.
+	DriftExtraLive = "ExtraLive"
+	//DriftReplicas is a deployment whose replicas in cluster differ from manifests.
+	DriftReplicas = "ReplicasMismatch"
+	//DriftImage is a container whose image in cluster differs from manifests.
+	DriftImage = "ImageMismatch"
+)
+
+//controllerCreatedKinds are kinds created in cluster by controllers for objects of manifests, so they are never
+//reported as extra.
+var controllerCreatedKinds = map[string]bool{
+	"endpoints":     true,
+	"endpointslice": true,
+	"node":          true,
+	"pod":           true,
+	"replicaset":    true,
+}
+
+//clusterCreatedMembers are objects created in every namespace by cluster, which groups refer to without manifests.
+var clusterCreatedMembers = map[string]bool{
+	"serviceaccount/default":     true,
+	"configmap/kube-root-ca.crt": true,
+}
+
+//ManifestDrift is difference of live state of cluster from desired state of manifests.
+type ManifestDrift struct {
+	Groups []GroupDrift `json:"groups,omitempty"`
+	//InSync is number of groups without discrepancies.
+	InSync int `json:"inSync"`
+}
+
+//GroupDrift is discrepancies of a group. Namespace and common label are of manifests unless group is only live.
+type GroupDrift struct {
+	GroupRef
+	Discrepancies []Discrepancy `json:"discrepancies"`
+}
+
+//Discrepancy is a member of a group whose live state differs from manifests.
+type Discrepancy struct {
+	Type string `json:"type"`
+	//Member is 'kind/name' e.g. 'deployment/api'.
+	Member    string `json:"member"`
+	Container string `json:"container,omitempty"`
+	Desired   string `json:"desired,omitempty"`
+	Live      string `json:"live,omitempty"`
+}
+
+//CompareManifests compares desired mapped resources e.g. mapped from rendered manifests with live state of store
+//e.g. as kept up to date by informers.
+func (m *Mapper) CompareManifests(desired MappedResources, options DiffOptions) (ManifestDrift, error) {
+	return CompareManifests(desired, getAllMappedResources(m.store), options)
+}
+
+//CompareManifests reports members of manifests which are missing live, live members which are not in manifests and
+//deployments whose replicas or container images differ. Groups are aligned as by DiffMappings, where namespaces of
+//options rename namespaces of manifests, including empty namespace of manifests without one. Pods, replica sets,
+//endpoints and other objects created by controllers are not reported as extra. Replicas of deployments scaled by an
+//autoscaler are not compared.
+func CompareManifests(desired, live MappedResources, options DiffOptions) (ManifestDrift, error) {
+	var drift ManifestDrift
+
+	desiredGroups, err := diffGroups(desired, options.Namespaces)
+	if err != nil {
+		return drift, err
+	}
+	liveGroups, err := diffGroups(live, nil)
+	if err != nil {
+		return drift, err
+	}
+
+	for _, pair := range alignGroups(desiredGroups, liveGroups) {
+		var groupDrift GroupDrift
+		switch {
+		case pair[0] == nil:
+			groupDrift = GroupDrift{GroupRef: pair[1].ref, Discrepancies: extraLiveMembers(nil, pair[1])}
+		case pair[1] == nil:
+			groupDrift = GroupDrift{GroupRef: pair[0].aligned}
+			for _, member := range sortedMembers(pair[0]) {
+				groupDrift.Discrepancies = append(groupDrift.Discrepancies, Discrepancy{Type: DriftMissingLive, Member: member})
+			}
+		default:
+			groupDrift = GroupDrift{GroupRef: pair[0].aligned, Discrepancies: groupDiscrepancies(pair[0], pair[1])}
+		}
+
+		if len(groupDrift.Discrepancies) == 0 {
+			drift.InSync++
+			continue
+		}
+		drift.Groups = append(drift.Groups, groupDrift)
+	}
+
+	sort.Slice(drift.Groups, func(i, j int) bool {
+		return lessGroupRef(drift.Groups[i].GroupRef, drift.Groups[j].GroupRef)
+	})
+
+	return drift, nil
+}
+
+//Empty is true if live state has no discrepancy from manifests.
+func (d ManifestDrift) Empty() bool {
+	return len(d.Groups) == 0
+}
+
+//WriteText writes a line per discrepancy e.g. 'shop/api ImageMismatch deployment/api api: a:1 -> a:2'.
+func (d ManifestDrift) WriteText(w io.Writer) error {
+	for _, group := range d.Groups {
+		for _, discrepancy := range group.Discrepancies {
+			line := fmt.Sprintf("%s/%s %s %s", group.Namespace, group.CommonLabel, discrepancy.Type, discrepancy.Member)
+			if discrepancy.Container != "" {
+				line += " " + discrepancy.Container + ":"
+			}
+			if discrepancy.Type == DriftReplicas || discrepancy.Type == DriftImage {
+				line += fmt.Sprintf(" %s -> %s", orNone(discrepancy.Desired), orNone(discrepancy.Live))
+			}
+			if _, err := fmt.Fprintln(w, line); err != nil {
+				return fmt.Errorf("Cannot write drift - %v", err)
+			}
+		}
+	}
+
+	return nil
+}
+
+func groupDiscrepancies(desired, live *groupSnapshot) []Discrepancy {
+	var discrepancies []Discrepancy
+
+	for _, member := range sortedMembers(desired) {
+		if _, ok := live.members[member]; !ok {
+			discrepancies = append(discrepancies, Discrepancy{Type: DriftMissingLive, Member: member})
+		}
+	}
+	discrepancies = append(discrepancies, extraLiveMembers(desired, live)...)
+
+	liveDeployments := make(map[string]apps_v1.Deployment)
+	for _, deployment := range live.source.Kube.Deployments {
+		liveDeployments[deployment.Name] = deployment
+	}
+
+	for _, deployment := range desired.source.Kube.Deployments {
+		liveDeployment, ok := liveDeployments[deployment.Name]
+		if !ok {
+			continue
+		}
+		member := "deployment/" + deployment.Name
+
+		if !autoscaled(desired.source, deployment.Name) && !autoscaled(live.source, deployment.Name) {
+			desiredReplicas, liveReplicas := deploymentReplicas(deployment), deploymentReplicas(liveDeployment)
+			if desiredReplicas != liveReplicas {
+				discrepancies = append(discrepancies, Discrepancy{
+					Type:    DriftReplicas,
+					Member:  member,
+					Desired: strconv.Itoa(int(desiredReplicas)),
+					Live:    strconv.Itoa(int(liveReplicas)),
+				})
+			}
+		}
+
+		desiredImages := podSpecImagesByContainer(deployment.Spec.Template.Spec)
+		liveImages := podSpecImagesByContainer(liveDeployment.Spec.Template.Spec)
+		var containers []string
+		for container := range desiredImages {
+			containers = append(containers, container)
+		}
+		for container := range liveImages {
+			if _, ok := desiredImages[container]; !ok {
+				containers = append(containers, container)
+			}
+		}
+		sort.Strings(containers)
+
+		for _, container := range containers {
+			if !sameImage(desiredImages[container], liveImages[container]) {
+				discrepancies = append(discrepancies, Discrepancy{
+					Type:      DriftImage,
+					Member:    member,
+					Container: container,
+					Desired:   desiredImages[container],
+					Live:      liveImages[container],
+				})
+			}
+		}
+	}
+
+	return discrepancies
+}
+
+//extraLiveMembers returns members of live group which are not in desired group. Desired group may be nil.
+func extraLiveMembers(desired, live *groupSnapshot) []Discrepancy {
+	var discrepancies []Discrepancy
+
+	tokens := serviceAccountTokens(live.source)
+	for _, member := range sortedMembers(live) {
+		if clusterCreatedMembers[member] || controllerCreatedKinds[memberKind(member)] || tokens[member] {
+			continue
+		}
+		if desired != nil {
+			if _, ok := desired.members[member]; ok {
+				continue
+			}
+		}
+		discrepancies = append(discrepancies, Discrepancy{Type: DriftExtraLive, Member: member})
+	}
+
+	return discrepancies
+}
+
+//serviceAccountTokens returns secret members of mapped resource holding service account tokens. They are created in
+//cluster for service accounts and mounted into pods, so live groups refer to them without manifests.
+func serviceAccountTokens(mappedResource MappedResource) map[string]bool {
+	tokens := map[string]bool{}
+	for _, secret := range mappedResource.Kube.Secrets {
+		if secret.Type == core_v1.SecretTypeServiceAccountToken {
+			tokens["secret/"+secret.Name] = true
+		}
+	}
+
+	return tokens
+}
+
+func sortedMembers(group *groupSnapshot) []string {
+	var members []string
+	for member := range group.members {
+		if !controllerCreatedKinds[memberKind(member)] {
+			members = append(members, member)
+		}
+	}
+	sort.Strings(members)
+
+	return members
+}
+
+//memberKind returns kind of 'kind/name'. Kinds of custom resources may have a slash themselves.
+func memberKind(member string) string {
+	if index := strings.LastIndex(member, "/"); index >= 0 {
+		return member[:index]
+	}
+	return member
+}
+
+//autoscaled is true if a HorizontalPodAutoscaler of group scales deployment.
+func autoscaled(mappedResource MappedResource, deployment string) bool {
+	for _, hpa := range mappedResource.Kube.HorizontalPodAutoscalers {
+		if hpa.Spec.ScaleTargetRef.Kind == "Deployment" && hpa.Spec.ScaleTargetRef.Name == deployment {
+			return true
+		}
+	}
+
+	return false
+}
+
+//deploymentReplicas returns desired replicas of deployment which default to 1.
+func deploymentReplicas(deployment apps_v1.Deployment) int32 {
+	if deployment.Spec.Replicas == nil {
+		return 1
+	}
+	return *deployment.Spec.Replicas
+}
@@ -0,0 +1,212 @@
+package kubemap
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	autoscaling_v2beta2 "k8s.io/api/autoscaling/v2beta2"
+	core_v1 "k8s.io/api/core/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestCompareManifests(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(live *MappedResources)
+		options DiffOptions
+		groups  []GroupDrift
+		inSync  int
+	}{
+		{
+			name: "in sync",
+			modify: func(live *MappedResources) {
+				api := &live.MappedResource[0]
+				api.Kube.Pods = append(api.Kube.Pods, *api.Kube.Pods[0].DeepCopy())
+				api.Kube.Endpoints = []core_v1.Endpoints{{ObjectMeta: meta_v1.ObjectMeta{Name: "api"}}}
+				api.Kube.ServiceAccounts = []core_v1.ServiceAccount{{ObjectMeta: meta_v1.ObjectMeta{Name: "default"}}}
+				api.Kube.Deployments[0].Spec.Template.Spec.Containers[0].Image = "docker.io/library/api:1"
+			},
+			inSync: 2,
+		},
+		{
+			name: "replicas and images",
+			modify: func(live *MappedResources) {
+				replicas := int32(5)
+				deployment := &live.MappedResource[0].Kube.Deployments[0]
+				deployment.Spec.Replicas = &replicas
+				deployment.Spec.Template.Spec.Containers[0].Image = "api:2"
+				deployment.Spec.Template.Spec.Containers = append(deployment.Spec.Template.Spec.Containers, core_v1.Container{Name: "proxy", Image: "envoy:1"})
+			},
+			groups: []GroupDrift{{
+				GroupRef: GroupRef{Namespace: "shop", CommonLabel: "api"},
+				Discrepancies: []Discrepancy{
+					{Type: DriftReplicas, Member: "deployment/api", Desired: "2", Live: "5"},
+					{Type: DriftImage, Member: "deployment/api", Container: "api", Desired: "api:1", Live: "api:2"},
+					{Type: DriftImage, Member: "deployment/api", Container: "proxy", Live: "envoy:1"},
+				},
+			}},
+			inSync: 1,
+		},
+		{
+			name: "autoscaled replicas",
+			modify: func(live *MappedResources) {
+				replicas := int32(5)
+				api := &live.MappedResource[0]
+				api.Kube.Deployments[0].Spec.Replicas = &replicas
+				api.Kube.HorizontalPodAutoscalers = []autoscaling_v2beta2.HorizontalPodAutoscaler{{
+					ObjectMeta: meta_v1.ObjectMeta{Name: "api"},
+					Spec: autoscaling_v2beta2.HorizontalPodAutoscalerSpec{
+						ScaleTargetRef: autoscaling_v2beta2.CrossVersionObjectReference{Kind: "Deployment", Name: "api"},
+					},
+				}}
+			},
+			groups: []GroupDrift{{
+				GroupRef:      GroupRef{Namespace: "shop", CommonLabel: "api"},
+				Discrepancies: []Discrepancy{{Type: DriftExtraLive, Member: "hpa/api"}},
+			}},
+			inSync: 1,
+		},
+		{
+			name: "missing and extra members",
+			modify: func(live *MappedResources) {
+				api := &live.MappedResource[0]
+				api.Kube.ConfigMaps = nil
+				api.Kube.Secrets = []core_v1.Secret{{ObjectMeta: meta_v1.ObjectMeta{Name: "api-token"}}}
+			},
+			groups: []GroupDrift{{
+				GroupRef: GroupRef{Namespace: "shop", CommonLabel: "api"},
+				Discrepancies: []Discrepancy{
+					{Type: DriftMissingLive, Member: "configmap/api-v1"},
+					{Type: DriftExtraLive, Member: "secret/api-token"},
+				},
+			}},
+			inSync: 1,
+		},
+		{
+			name: "service account token mounted by pods",
+			modify: func(live *MappedResources) {
+				api := &live.MappedResource[0]
+				api.Kube.Secrets = []core_v1.Secret{{
+					ObjectMeta: meta_v1.ObjectMeta{Name: "default-token-x7k2p"},
+					Type:       core_v1.SecretTypeServiceAccountToken,
+				}}
+				for i := range api.Kube.Pods {
+					api.Kube.Pods[i].Spec.Volumes = append(api.Kube.Pods[i].Spec.Volumes, core_v1.Volume{
+						Name:         "default-token-x7k2p",
+						VolumeSource: core_v1.VolumeSource{Secret: &core_v1.SecretVolumeSource{SecretName: "default-token-x7k2p"}},
+					})
+				}
+			},
+			inSync: 2,
+		},
+		{
+			name: "missing and extra groups",
+			modify: func(live *MappedResources) {
+				live.MappedResource[1] = helperDiffGroup("shop", "cron")
+			},
+			groups: []GroupDrift{
+				{GroupRef: GroupRef{Namespace: "shop", CommonLabel: "cron"}, Discrepancies: []Discrepancy{{Type: DriftExtraLive, Member: "deployment/cron"}}},
+				{GroupRef: GroupRef{Namespace: "shop", CommonLabel: "worker"}, Discrepancies: []Discrepancy{{Type: DriftMissingLive, Member: "deployment/worker"}}},
+			},
+			inSync: 1,
+		},
+		{
+			name: "mapped namespace",
+			modify: func(live *MappedResources) {
+				for i := range live.MappedResource {
+					live.MappedResource[i].Namespace = "shop-production"
+				}
+			},
+			options: DiffOptions{Namespaces: map[string]string{"shop": "shop-production"}},
+			inSync:  2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			live := helperGetDiffMappedResources()
+			tt.modify(&live)
+
+			drift, err := CompareManifests(helperGetDiffMappedResources(), live, tt.options)
+			assert.Nil(t, err)
+			assert.Equal(t, tt.groups, drift.Groups)
+			assert.Equal(t, tt.inSync, drift.InSync)
+			assert.Equal(t, len(tt.groups) == 0, drift.Empty())
+		})
+	}
+}
+
+func TestCompareManifestsOfMapper(t *testing.T) {
+	mapper := NewMapper()
+	_, err := mapper.Map(helperGetK8sResources())
+	assert.Nil(t, err)
+
+	resources := helperGetK8sResources()
+	for i := range resources.Services {
+		resources.Services[i].Namespace = ""
+	}
+	for i := range resources.Deployments {
+		resources.Deployments[i].Namespace = ""
+		resources.Deployments[i].Spec.Template.Spec.Containers[0].Image = "some/random/image:v2"
+	}
+	desired, err := NewMapper().Map(KubeResources{Services: resources.Services, Deployments: resources.Deployments})
+	assert.Nil(t, err)
+
+	drift, err := mapper.CompareManifests(desired, DiffOptions{Namespaces: map[string]string{"": helperGetK8sResources().Services[0].Namespace}})
+	assert.Nil(t, err)
+	assert.Len(t, drift.Groups, 1)
+
+	var types []string
+	for _, discrepancy := range drift.Groups[0].Discrepancies {
+		types = append(types, discrepancy.Type+" "+discrepancy.Member)
+	}
+	assert.Equal(t, []string{"ExtraLive ingress/" + resources.Ingresses[0].Name, "ImageMismatch deployment/" + resources.Deployments[0].Name}, types)
+}
+
+func TestCompareManifestsIgnoresServiceAccountTokens(t *testing.T) {
+	live := helperGetK8sResources()
+	namespace := live.Pods[0].Namespace
+	live.Secrets = append(live.Secrets, core_v1.Secret{
+		ObjectMeta: meta_v1.ObjectMeta{Name: "default-token-x7k2p", Namespace: namespace},
+		Type:       core_v1.SecretTypeServiceAccountToken,
+	})
+	for i := range live.Pods {
+		live.Pods[i].Spec.Volumes = append(live.Pods[i].Spec.Volumes, core_v1.Volume{
+			Name:         "default-token-x7k2p",
+			VolumeSource: core_v1.VolumeSource{Secret: &core_v1.SecretVolumeSource{SecretName: "default-token-x7k2p"}},
+		})
+	}
+
+	mapper := NewMapper()
+	_, err := mapper.Map(live)
+	assert.Nil(t, err)
+	desired, err := NewMapper().Map(helperGetK8sResources())
+	assert.Nil(t, err)
+
+	drift, err := mapper.CompareManifests(desired, DiffOptions{})
+	assert.Nil(t, err)
+	assert.True(t, drift.Empty(), "drift - %+v", drift.Groups)
+}
+
+func TestManifestDriftText(t *testing.T) {
+	drift := ManifestDrift{Groups: []GroupDrift{{
+		GroupRef: GroupRef{Namespace: "shop", CommonLabel: "api"},
+		Discrepancies: []Discrepancy{
+			{Type: DriftMissingLive, Member: "configmap/api-v1"},
+			{Type: DriftReplicas, Member: "deployment/api", Desired: "2", Live: "5"},
+			{Type: DriftImage, Member: "deployment/api", Container: "proxy", Live: "envoy:1"},
+		},
+	}}}
+
+	var buffer bytes.Buffer
+	assert.Nil(t, drift.WriteText(&buffer))
+	assert.Equal(t, strings.Join([]string{
+		"shop/api MissingLive configmap/api-v1",
+		"shop/api ReplicasMismatch deployment/api 2 -> 5",
+		"shop/api ImageMismatch deployment/api proxy: <none> -> envoy:1",
+		"",
+	}, "\n"), buffer.String())
+}
@@ -0,0 +1,250 @@
+package kubemap
+
+import (
+	"fmt"
+	"sort"
+
+	core_v1 "k8s.io/api/core/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+//ServiceNameLabel links an EndpointSlice to its service.
+const ServiceNameLabel = "kubernetes.io/service-name"
+
+const (
+	//EndpointSourceEndpoints marks addresses taken from core/v1 Endpoints.
+	EndpointSourceEndpoints = "endpoints"
+	//EndpointSourceEndpointSlice marks addresses taken from discovery.k8s.io/v1 EndpointSlices.
+	EndpointSourceEndpointSlice = "endpointslice"
+)
+
+//EndpointSlice mirrors discovery.k8s.io/v1 EndpointSlice which is not part of vendored k8s api.
+//Its JSON form is same as that of discovery.k8s.io/v1.
+type EndpointSlice struct {
+	meta_v1.TypeMeta   `json:",inline"`
+	meta_v1.ObjectMeta `json:"metadata,omitempty"`
+	AddressType        string              `json:"addressType"`
+	Endpoints          []SliceEndpoint     `json:"endpoints"`
+	Ports              []EndpointSlicePort `json:"ports,omitempty"`
+}
+
+//SliceEndpoint is a single backend of an EndpointSlice.
+type SliceEndpoint struct {
+	Addresses  []string                 `json:"addresses"`
+	Conditions EndpointConditions       `json:"conditions,omitempty"`
+	Hostname   *string                  `json:"hostname,omitempty"`
+	TargetRef  *core_v1.ObjectReference `json:"targetRef,omitempty"`
+	NodeName   *string                  `json:"nodeName,omitempty"`
+	Zone       *string                  `json:"zone,omitempty"`
+}
+
+//EndpointConditions of a SliceEndpoint. Nil ready condition is interpreted as ready.
+type EndpointConditions struct {
+	Ready       *bool `json:"ready,omitempty"`
+	Serving     *bool `json:"serving,omitempty"`
+	Terminating *bool `json:"terminating,omitempty"`
+}
+
+//EndpointSlicePort is a port exposed by each endpoint of an EndpointSlice.
+type EndpointSlicePort struct {
+	Name        *string           `json:"name,omitempty"`
+	Protocol    *core_v1.Protocol `json:"protocol,omitempty"`
+	Port        *int32            `json:"port,omitempty"`
+	AppProtocol *string           `json:"appProtocol,omitempty"`
+}
+
+//DeepCopy copies EndpointSlice through its JSON form.
+func (in *EndpointSlice) DeepCopy() *EndpointSlice {
+	if in == nil {
+		return nil
+	}
+
+	out := new(EndpointSlice)
+	copyThroughJSON(in, out)
+
+	return out
+}
+
+//ServiceEndpoint is an address which receives traffic of a service of group.
+type ServiceEndpoint struct {
+	Service string `json:"service"`
+	Address string `json:"address"`
+	//Pod is name of pod behind address if endpoint refers to one.
+	Pod   string `json:"pod,omitempty"`
+	Ready bool   `json:"ready"`
+	//Source is either 'endpoints' or 'endpointslice'.
+	Source string `json:"source"`
+}
+
+//endpointsKind attaches Endpoints having same name as a service of group.
+var endpointsKind = relatedKind{
+	normalize: func(obj interface{}) (interface{}, error) {
+		endpoints, ok := obj.(*core_v1.Endpoints)
+		if !ok {
+			return nil, fmt.Errorf("Object of type %T is not Endpoints", obj)
+		}
+		return endpoints.DeepCopy(), nil
+	},
+	attach: attachEndpoints,
+	attached: func(mappedResource MappedResource) []interface{} {
+		var objects []interface{}
+		for i := range mappedResource.Kube.Endpoints {
+			objects = append(objects, mappedResource.Kube.Endpoints[i].DeepCopy())
+		}
+		return objects
+	},
+}
+
+//endpointSliceKind attaches EndpointSlices labelled with name of a service of group.
+//Slices are received as unstructured objects or as EndpointSlice.
+var endpointSliceKind = relatedKind{
+	normalize: normalizeEndpointSlice,
+	attach:    attachEndpointSlices,
+	attached: func(mappedResource MappedResource) []interface{} {
+		var objects []interface{}
+		for i := range mappedResource.Kube.EndpointSlices {
+			objects = append(objects, mappedResource.Kube.EndpointSlices[i].DeepCopy())
+		}
+		return objects
+	},
+}
+
+func normalizeEndpointSlice(obj interface{}) (interface{}, error) {
+	switch slice := obj.(type) {
+	case *EndpointSlice:
+		return slice.DeepCopy(), nil
+	case *unstructured.Unstructured:
+		if slice.GetKind() != "" && slice.GetKind() != "EndpointSlice" {
+			return nil, fmt.Errorf("Object of kind %s is not an EndpointSlice", slice.GetKind())
+		}
+
+		converted := &EndpointSlice{}
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(slice.Object, converted); err != nil {
+			return nil, fmt.Errorf("Cannot convert EndpointSlice %s - %v", slice.GetName(), err)
+		}
+		return converted, nil
+	}
+
+	return nil, fmt.Errorf("Object of type %T is not an EndpointSlice", obj)
+}
+
+func attachEndpoints(mappedResource *MappedResource, related *relatedObjects) {
+	mappedResource.Kube.Endpoints = nil
+	serviceEndpoints := withoutEndpointSource(mappedResource.Kube.ServiceEndpoints, EndpointSourceEndpoints)
+
+	for _, service := range mappedResource.Kube.Services {
+		obj, ok := related.get("endpoints", mappedResource.Namespace, service.Name)
+		if !ok {
+			continue
+		}
+		endpoints := obj.(*core_v1.Endpoints)
+		mappedResource.Kube.Endpoints = append(mappedResource.Kube.Endpoints, *endpoints.DeepCopy())
+
+		for _, subset := range endpoints.Subsets {
+			for _, address := range subset.Addresses {
+				serviceEndpoints = append(serviceEndpoints, endpointAddress(service.Name, address, true))
+			}
+			for _, address := range subset.NotReadyAddresses {
+				serviceEndpoints = append(serviceEndpoints, endpointAddress(service.Name, address, false))
+			}
+		}
+	}
+
+	mappedResource.Kube.ServiceEndpoints = sortServiceEndpoints(serviceEndpoints)
+}
+
+func attachEndpointSlices(mappedResource *MappedResource, related *relatedObjects) {
+	mappedResource.Kube.EndpointSlices = nil
+	serviceEndpoints := withoutEndpointSource(mappedResource.Kube.ServiceEndpoints, EndpointSourceEndpointSlice)
+
+	services := make(map[string]bool)
+	for _, service := range mappedResource.Kube.Services {
+		services[service.Name] = true
+	}
+
+	for _, obj := range related.list("endpointslice", mappedResource.Namespace) {
+		slice := obj.(*EndpointSlice)
+		serviceName := slice.Labels[ServiceNameLabel]
+		if !services[serviceName] {
+			continue
+		}
+		mappedResource.Kube.EndpointSlices = append(mappedResource.Kube.EndpointSlices, *slice.DeepCopy())
+
+		for _, endpoint := range slice.Endpoints {
+			ready := endpoint.Conditions.Ready == nil || *endpoint.Conditions.Ready
+			pod := ""
+			if endpoint.TargetRef != nil && endpoint.TargetRef.Kind == "Pod" {
+				pod = endpoint.TargetRef.Name
+			}
+
+			for _, address := range endpoint.Addresses {
+				serviceEndpoints = append(serviceEndpoints, ServiceEndpoint{
+					Service: serviceName,
+					Address: address,
+					Pod:     pod,
+					Ready:   ready,
+					Source:  EndpointSourceEndpointSlice,
+				})
+			}
+		}
+	}
+
+	mappedResource.Kube.ServiceEndpoints = sortServiceEndpoints(serviceEndpoints)
+}
+
+func endpointAddress(serviceName string, address core_v1.EndpointAddress, ready bool) ServiceEndpoint {
+	serviceEndpoint := ServiceEndpoint{
+		Service: serviceName,
+		Address: address.IP,
+		Ready:   ready,
+		Source:  EndpointSourceEndpoints,
+	}
+	if address.TargetRef != nil && address.TargetRef.Kind == "Pod" {
+		serviceEndpoint.Pod = address.TargetRef.Name
+	}
+
+	return serviceEndpoint
+}
+
+func withoutEndpointSource(serviceEndpoints []ServiceEndpoint, source string) []ServiceEndpoint {
+	var filtered []ServiceEndpoint
+	for _, serviceEndpoint := range serviceEndpoints {
+		if serviceEndpoint.Source != source {
+			filtered = append(filtered, serviceEndpoint)
+		}
+	}
+
+	return filtered
+}
+
+func sortServiceEndpoints(serviceEndpoints []ServiceEndpoint) []ServiceEndpoint {
+	sort.Slice(serviceEndpoints, func(i, j int) bool {
+		a, b := serviceEndpoints[i], serviceEndpoints[j]
+		if a.Service != b.Service {
+			return a.Service < b.Service
+		}
+		if a.Source != b.Source {
+			return a.Source < b.Source
+		}
+		return a.Address < b.Address
+	})
+
+	return serviceEndpoints
+}
+
+//TrafficPods returns names of pods of group which receive traffic of its services i.e. have a ready endpoint.
+func TrafficPods(mappedResource MappedResource) []string {
+	var pods []string
+	for _, serviceEndpoint := range mappedResource.Kube.ServiceEndpoints {
+		if serviceEndpoint.Ready && serviceEndpoint.Pod != "" {
+			pods = append(pods, serviceEndpoint.Pod)
+		}
+	}
+
+	pods = removeDuplicateStrings(pods)
+	sort.Strings(pods)
+
+	return pods
+}
@@ -0,0 +1,109 @@
+package kubemap
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	core_v1 "k8s.io/api/core/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestEndpointsAreAttachedToService(t *testing.T) {
+	resources := helperGetEndpointResources()
+
+	mappedResources, err := NewMapper().Map(resources)
+	assert.Nil(t, err)
+	assert.Len(t, mappedResources.MappedResource, 1)
+
+	kube := mappedResources.MappedResource[0].Kube
+	assert.Len(t, kube.Endpoints, 1)
+	assert.Len(t, kube.EndpointSlices, 1)
+	assert.Equal(t, resources.Services[0].Name+"-abcde", kube.EndpointSlices[0].Name)
+
+	service, pod := resources.Services[0].Name, resources.Pods[0].Name
+	assert.Equal(t, []ServiceEndpoint{
+		{Service: service, Address: "10.0.0.9", Ready: false, Source: EndpointSourceEndpoints},
+		{Service: service, Address: "10.0.1.5", Pod: pod, Ready: true, Source: EndpointSourceEndpoints},
+		{Service: service, Address: "10.0.0.9", Ready: false, Source: EndpointSourceEndpointSlice},
+		{Service: service, Address: "10.0.1.5", Pod: pod, Ready: true, Source: EndpointSourceEndpointSlice},
+	}, kube.ServiceEndpoints)
+
+	assert.Equal(t, []string{pod}, TrafficPods(mappedResources.MappedResource[0]))
+}
+
+func TestEndpointSliceOfOtherServiceIsNotAttached(t *testing.T) {
+	resources := helperGetEndpointResources()
+	resources.EndpointSlices[0].SetLabels(map[string]string{ServiceNameLabel: "other"})
+
+	mappedResources, err := NewMapper().Map(resources)
+	assert.Nil(t, err)
+	assert.Empty(t, mappedResources.MappedResource[0].Kube.EndpointSlices)
+	assert.Len(t, mappedResources.MappedResource[0].Kube.ServiceEndpoints, 2)
+}
+
+func TestEndpointsConsistency(t *testing.T) {
+	events := resourceEventsForMapping(helperGetEndpointResources())
+	random := rand.New(rand.NewSource(37))
+
+	for i := 0; i < 30; i++ {
+		random.Shuffle(len(events), func(a, b int) {
+			events[a], events[b] = events[b], events[a]
+		})
+
+		report, err := CheckConsistency(events, MapOptions{})
+		assert.Nil(t, err)
+		assert.True(t, report.Consistent, "order %s diverged - %+v", helperEventOrder(events), report.Divergences)
+	}
+}
+
+func TestNormalizeEndpointSliceRejectsOtherKinds(t *testing.T) {
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{"kind": "Gateway"}}
+
+	_, err := normalizeEndpointSlice(obj)
+	assert.NotNil(t, err)
+}
+
+//helperGetEndpointResources returns fixtures with endpoints and a slice for the service. Both have a ready address
+//of fixture pod and a not ready address without target.
+func helperGetEndpointResources() KubeResources {
+	resources := helperGetK8sResources()
+	service, pod := resources.Services[0], resources.Pods[0]
+
+	resources.Endpoints = []core_v1.Endpoints{{
+		ObjectMeta: meta_v1.ObjectMeta{Name: service.Name, Namespace: service.Namespace},
+		Subsets: []core_v1.EndpointSubset{{
+			Addresses: []core_v1.EndpointAddress{{
+				IP:        "10.0.1.5",
+				TargetRef: &core_v1.ObjectReference{Kind: "Pod", Name: pod.Name, Namespace: pod.Namespace},
+			}},
+			NotReadyAddresses: []core_v1.EndpointAddress{{IP: "10.0.0.9"}},
+		}},
+	}}
+
+	resources.EndpointSlices = []unstructured.Unstructured{{Object: map[string]interface{}{
+		"apiVersion": "discovery.k8s.io/v1",
+		"kind":       "EndpointSlice",
+		"metadata": map[string]interface{}{
+			"name":      service.Name + "-abcde",
+			"namespace": service.Namespace,
+			"labels":    map[string]interface{}{ServiceNameLabel: service.Name},
+		},
+		"addressType": "IPv4",
+		"endpoints": []interface{}{
+			map[string]interface{}{
+				"addresses":  []interface{}{"10.0.1.5"},
+				"conditions": map[string]interface{}{"ready": true},
+				"targetRef":  map[string]interface{}{"kind": "Pod", "name": pod.Name, "namespace": pod.Namespace},
+			},
+			map[string]interface{}{
+				"addresses":  []interface{}{"10.0.0.9"},
+				"conditions": map[string]interface{}{"ready": false},
+			},
+		},
+	}}}
+
+	return resources
+}
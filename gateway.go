@@ -0,0 +1,489 @@
+package kubemap
+
+import (
+	"fmt"
+	"sort"
+
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+//GatewayAPIGroup is API group of Gateway API objects.
+const GatewayAPIGroup = "gateway.networking.k8s.io"
+
+//Gateway mirrors gateway.networking.k8s.io/v1 Gateway which is not part of vendored k8s api.
+//Types of Gateway API in this file keep only fields needed for mapping. Their JSON form is same as that of Gateway API.
+type Gateway struct {
+	meta_v1.TypeMeta   `json:",inline"`
+	meta_v1.ObjectMeta `json:"metadata,omitempty"`
+	Spec               GatewaySpec `json:"spec"`
+}
+
+//GatewaySpec ...
+type GatewaySpec struct {
+	GatewayClassName string            `json:"gatewayClassName"`
+	Listeners        []GatewayListener `json:"listeners,omitempty"`
+}
+
+//GatewayListener ...
+type GatewayListener struct {
+	Name     string  `json:"name"`
+	Hostname *string `json:"hostname,omitempty"`
+	Port     int32   `json:"port"`
+	Protocol string  `json:"protocol"`
+}
+
+//HTTPRoute mirrors gateway.networking.k8s.io/v1 HTTPRoute.
+type HTTPRoute struct {
+	meta_v1.TypeMeta   `json:",inline"`
+	meta_v1.ObjectMeta `json:"metadata,omitempty"`
+	Spec               HTTPRouteSpec `json:"spec"`
+}
+
+//HTTPRouteSpec ...
+type HTTPRouteSpec struct {
+	ParentRefs []ParentReference `json:"parentRefs,omitempty"`
+	Hostnames  []string          `json:"hostnames,omitempty"`
+	Rules      []HTTPRouteRule   `json:"rules,omitempty"`
+}
+
+//HTTPRouteRule ...
+type HTTPRouteRule struct {
+	Matches     []HTTPRouteMatch `json:"matches,omitempty"`
+	BackendRefs []BackendRef     `json:"backendRefs,omitempty"`
+}
+
+//HTTPRouteMatch ...
+type HTTPRouteMatch struct {
+	Path   *HTTPPathMatch `json:"path,omitempty"`
+	Method *string        `json:"method,omitempty"`
+}
+
+//HTTPPathMatch ...
+type HTTPPathMatch struct {
+	Type  *string `json:"type,omitempty"`
+	Value *string `json:"value,omitempty"`
+}
+
+//GRPCRoute mirrors gateway.networking.k8s.io/v1 GRPCRoute.
+type GRPCRoute struct {
+	meta_v1.TypeMeta   `json:",inline"`
+	meta_v1.ObjectMeta `json:"metadata,omitempty"`
+	Spec               GRPCRouteSpec `json:"spec"`
+}
+
+//GRPCRouteSpec ...
+type GRPCRouteSpec struct {
+	ParentRefs []ParentReference `json:"parentRefs,omitempty"`
+	Hostnames  []string          `json:"hostnames,omitempty"`
+	Rules      []GRPCRouteRule   `json:"rules,omitempty"`
+}
+
+//GRPCRouteRule ...
+type GRPCRouteRule struct {
+	Matches     []GRPCRouteMatch `json:"matches,omitempty"`
+	BackendRefs []BackendRef     `json:"backendRefs,omitempty"`
+}
+
+//GRPCRouteMatch ...
+type GRPCRouteMatch struct {
+	Method *GRPCMethodMatch `json:"method,omitempty"`
+}
+
+//GRPCMethodMatch ...
+type GRPCMethodMatch struct {
+	Service *string `json:"service,omitempty"`
+	Method  *string `json:"method,omitempty"`
+}
+
+//ParentReference refers a route to a Gateway. Namespace defaults to that of route.
+type ParentReference struct {
+	Group       *string `json:"group,omitempty"`
+	Kind        *string `json:"kind,omitempty"`
+	Namespace   *string `json:"namespace,omitempty"`
+	Name        string  `json:"name"`
+	SectionName *string `json:"sectionName,omitempty"`
+	Port        *int32  `json:"port,omitempty"`
+}
+
+//BackendRef refers a route to a backend, which is a Service unless group or kind say otherwise.
+//Namespace defaults to that of route.
+type BackendRef struct {
+	Group     *string `json:"group,omitempty"`
+	Kind      *string `json:"kind,omitempty"`
+	Name      string  `json:"name"`
+	Namespace *string `json:"namespace,omitempty"`
+	Port      *int32  `json:"port,omitempty"`
+	Weight    *int32  `json:"weight,omitempty"`
+}
+
+//ReferenceGrant mirrors gateway.networking.k8s.io/v1beta1 ReferenceGrant. It permits objects of other namespaces
+//to refer to objects of its namespace.
+type ReferenceGrant struct {
+	meta_v1.TypeMeta   `json:",inline"`
+	meta_v1.ObjectMeta `json:"metadata,omitempty"`
+	Spec               ReferenceGrantSpec `json:"spec"`
+}
+
+//ReferenceGrantSpec ...
+type ReferenceGrantSpec struct {
+	From []ReferenceGrantFrom `json:"from"`
+	To   []ReferenceGrantTo   `json:"to"`
+}
+
+//ReferenceGrantFrom ...
+type ReferenceGrantFrom struct {
+	Group     string `json:"group"`
+	Kind      string `json:"kind"`
+	Namespace string `json:"namespace"`
+}
+
+//ReferenceGrantTo ...
+type ReferenceGrantTo struct {
+	Group string  `json:"group"`
+	Kind  string  `json:"kind"`
+	Name  *string `json:"name,omitempty"`
+}
+
+//DeepCopy copies Gateway through its JSON form.
+func (in *Gateway) DeepCopy() *Gateway {
+	if in == nil {
+		return nil
+	}
+
+	out := new(Gateway)
+	copyThroughJSON(in, out)
+
+	return out
+}
+
+//DeepCopy copies HTTPRoute through its JSON form.
+func (in *HTTPRoute) DeepCopy() *HTTPRoute {
+	if in == nil {
+		return nil
+	}
+
+	out := new(HTTPRoute)
+	copyThroughJSON(in, out)
+
+	return out
+}
+
+//DeepCopy copies GRPCRoute through its JSON form.
+func (in *GRPCRoute) DeepCopy() *GRPCRoute {
+	if in == nil {
+		return nil
+	}
+
+	out := new(GRPCRoute)
+	copyThroughJSON(in, out)
+
+	return out
+}
+
+//DeepCopy copies ReferenceGrant through its JSON form.
+func (in *ReferenceGrant) DeepCopy() *ReferenceGrant {
+	if in == nil {
+		return nil
+	}
+
+	out := new(ReferenceGrant)
+	copyThroughJSON(in, out)
+
+	return out
+}
+
+//RouteBackend is a service of group which receives traffic of an HTTPRoute or GRPCRoute.
+type RouteBackend struct {
+	//RouteKind is either 'HTTPRoute' or 'GRPCRoute'.
+	RouteKind      string `json:"routeKind"`
+	RouteName      string `json:"routeName"`
+	RouteNamespace string `json:"routeNamespace"`
+	Service        string `json:"service"`
+	Port           *int32 `json:"port,omitempty"`
+	Weight         *int32 `json:"weight,omitempty"`
+	//Permitted is false if route refers to service of other namespace without a ReferenceGrant.
+	//Such backend receives no traffic.
+	Permitted bool `json:"permitted"`
+	//ReferenceGrant permitting a reference of other namespace.
+	ReferenceGrant string `json:"referenceGrant,omitempty"`
+}
+
+//Gateway API kinds share attachGatewayAPIObjects which resolves routes, their gateways and reference grants at once.
+//Routes and gateways may be in other namespaces than services of group, so their changes refresh all namespaces.
+var (
+	gatewayKind = relatedKind{
+		clusterScoped: true,
+		normalize: func(obj interface{}) (interface{}, error) {
+			gateway := &Gateway{}
+			return gateway, normalizeGatewayAPIObject(obj, "Gateway", gateway)
+		},
+		attach: attachGatewayAPIObjects,
+		attached: func(mappedResource MappedResource) []interface{} {
+			var objects []interface{}
+			for i := range mappedResource.Kube.Gateways {
+				objects = append(objects, mappedResource.Kube.Gateways[i].DeepCopy())
+			}
+			return objects
+		},
+	}
+
+	httpRouteKind = relatedKind{
+		clusterScoped: true,
+		normalize: func(obj interface{}) (interface{}, error) {
+			route := &HTTPRoute{}
+			return route, normalizeGatewayAPIObject(obj, "HTTPRoute", route)
+		},
+		attach: attachGatewayAPIObjects,
+		attached: func(mappedResource MappedResource) []interface{} {
+			var objects []interface{}
+			for i := range mappedResource.Kube.HTTPRoutes {
+				objects = append(objects, mappedResource.Kube.HTTPRoutes[i].DeepCopy())
+			}
+			return objects
+		},
+	}
+
+	grpcRouteKind = relatedKind{
+		clusterScoped: true,
+		normalize: func(obj interface{}) (interface{}, error) {
+			route := &GRPCRoute{}
+			return route, normalizeGatewayAPIObject(obj, "GRPCRoute", route)
+		},
+		attach: attachGatewayAPIObjects,
+		attached: func(mappedResource MappedResource) []interface{} {
+			var objects []interface{}
+			for i := range mappedResource.Kube.GRPCRoutes {
+				objects = append(objects, mappedResource.Kube.GRPCRoutes[i].DeepCopy())
+			}
+			return objects
+		},
+	}
+
+	referenceGrantKind = relatedKind{
+		normalize: func(obj interface{}) (interface{}, error) {
+			referenceGrant := &ReferenceGrant{}
+			return referenceGrant, normalizeGatewayAPIObject(obj, "ReferenceGrant", referenceGrant)
+		},
+		attach: attachGatewayAPIObjects,
+		attached: func(mappedResource MappedResource) []interface{} {
+			var objects []interface{}
+			for i := range mappedResource.Kube.ReferenceGrants {
+				objects = append(objects, mappedResource.Kube.ReferenceGrants[i].DeepCopy())
+			}
+			return objects
+		},
+	}
+)
+
+//normalizeGatewayAPIObject converts an unstructured object or a typed object of this package into out.
+func normalizeGatewayAPIObject(obj interface{}, kind string, out interface{}) error {
+	switch object := obj.(type) {
+	case *unstructured.Unstructured:
+		if object.GetKind() != "" && object.GetKind() != kind {
+			return fmt.Errorf("Object of kind %s is not a %s", object.GetKind(), kind)
+		}
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(object.Object, out); err != nil {
+			return fmt.Errorf("Cannot convert %s %s - %v", kind, object.GetName(), err)
+		}
+		return nil
+	case *Gateway, *HTTPRoute, *GRPCRoute, *ReferenceGrant:
+		if fmt.Sprintf("%T", obj) != fmt.Sprintf("%T", out) {
+			return fmt.Errorf("Object of type %T is not a %s", obj, kind)
+		}
+		copyThroughJSON(object, out)
+		return nil
+	}
+
+	return fmt.Errorf("Object of type %T is not a %s", obj, kind)
+}
+
+//attachGatewayAPIObjects attaches routes with a backend among services of group, gateways those routes are
+//attached to and reference grants permitting their backends of other namespaces.
+func attachGatewayAPIObjects(mappedResource *MappedResource, related *relatedObjects) {
+	kube := &mappedResource.Kube
+	kube.Gateways, kube.HTTPRoutes, kube.GRPCRoutes, kube.ReferenceGrants, kube.RouteBackends = nil, nil, nil, nil, nil
+
+	if len(kube.Services) == 0 {
+		return
+	}
+
+	var parentRefs []ParentReference
+	var parentNamespaces []string
+	referenceGrants := make(map[string]bool)
+
+	addBackends := func(routeKind string, routeMeta meta_v1.ObjectMeta, backendRefs []BackendRef) bool {
+		found := false
+		for _, backendRef := range backendRefs {
+			routeBackend, ok := resolveBackendRef(routeKind, routeMeta, backendRef, *mappedResource, related)
+			if !ok {
+				continue
+			}
+			found = true
+			kube.RouteBackends = append(kube.RouteBackends, routeBackend)
+			if routeBackend.ReferenceGrant != "" {
+				referenceGrants[routeBackend.ReferenceGrant] = true
+			}
+		}
+		return found
+	}
+
+	for _, obj := range related.list("httproute", "") {
+		route := obj.(*HTTPRoute)
+		var backendRefs []BackendRef
+		for _, rule := range route.Spec.Rules {
+			backendRefs = append(backendRefs, rule.BackendRefs...)
+		}
+
+		if addBackends("HTTPRoute", route.ObjectMeta, backendRefs) {
+			kube.HTTPRoutes = append(kube.HTTPRoutes, *route.DeepCopy())
+			for _, parentRef := range route.Spec.ParentRefs {
+				parentRefs = append(parentRefs, parentRef)
+				parentNamespaces = append(parentNamespaces, route.Namespace)
+			}
+		}
+	}
+
+	for _, obj := range related.list("grpcroute", "") {
+		route := obj.(*GRPCRoute)
+		var backendRefs []BackendRef
+		for _, rule := range route.Spec.Rules {
+			backendRefs = append(backendRefs, rule.BackendRefs...)
+		}
+
+		if addBackends("GRPCRoute", route.ObjectMeta, backendRefs) {
+			kube.GRPCRoutes = append(kube.GRPCRoutes, *route.DeepCopy())
+			for _, parentRef := range route.Spec.ParentRefs {
+				parentRefs = append(parentRefs, parentRef)
+				parentNamespaces = append(parentNamespaces, route.Namespace)
+			}
+		}
+	}
+
+	gateways := make(map[string]bool)
+	for i, parentRef := range parentRefs {
+		if !isGatewayAPIRef(parentRef.Group, parentRef.Kind, "Gateway") {
+			continue
+		}
+		namespace := parentNamespaces[i]
+		if parentRef.Namespace != nil {
+			namespace = *parentRef.Namespace
+		}
+		gateways[namespace+"/"+parentRef.Name] = true
+	}
+	for _, obj := range related.list("gateway", "") {
+		gateway := obj.(*Gateway)
+		if gateways[gateway.Namespace+"/"+gateway.Name] {
+			kube.Gateways = append(kube.Gateways, *gateway.DeepCopy())
+		}
+	}
+
+	for _, obj := range related.list("referencegrant", mappedResource.Namespace) {
+		referenceGrant := obj.(*ReferenceGrant)
+		if referenceGrants[referenceGrant.Name] {
+			kube.ReferenceGrants = append(kube.ReferenceGrants, *referenceGrant.DeepCopy())
+		}
+	}
+
+	sort.Slice(kube.RouteBackends, func(i, j int) bool {
+		a, b := kube.RouteBackends[i], kube.RouteBackends[j]
+		if a.RouteKind != b.RouteKind {
+			return a.RouteKind < b.RouteKind
+		}
+		if a.RouteNamespace != b.RouteNamespace {
+			return a.RouteNamespace < b.RouteNamespace
+		}
+		if a.RouteName != b.RouteName {
+			return a.RouteName < b.RouteName
+		}
+		return a.Service < b.Service
+	})
+}
+
+//resolveBackendRef returns backend of route if backend reference is a service of group.
+//A reference to other namespace is permitted only by a ReferenceGrant of namespace of service.
+func resolveBackendRef(routeKind string, routeMeta meta_v1.ObjectMeta, backendRef BackendRef, mappedResource MappedResource, related *relatedObjects) (RouteBackend, bool) {
+	if !isServiceRef(backendRef) {
+		return RouteBackend{}, false
+	}
+
+	namespace := routeMeta.Namespace
+	if backendRef.Namespace != nil && *backendRef.Namespace != "" {
+		namespace = *backendRef.Namespace
+	}
+	if namespace != mappedResource.Namespace {
+		return RouteBackend{}, false
+	}
+
+	found := false
+	for _, service := range mappedResource.Kube.Services {
+		if service.Name == backendRef.Name {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return RouteBackend{}, false
+	}
+
+	routeBackend := RouteBackend{
+		RouteKind:      routeKind,
+		RouteName:      routeMeta.Name,
+		RouteNamespace: routeMeta.Namespace,
+		Service:        backendRef.Name,
+		Port:           backendRef.Port,
+		Weight:         backendRef.Weight,
+		Permitted:      routeMeta.Namespace == namespace,
+	}
+
+	if !routeBackend.Permitted {
+		routeBackend.ReferenceGrant = grantingReference(routeKind, routeMeta.Namespace, namespace, backendRef.Name, related)
+		routeBackend.Permitted = routeBackend.ReferenceGrant != ""
+	}
+
+	return routeBackend, true
+}
+
+//grantingReference returns name of first ReferenceGrant of namespace which permits routes of kind in route
+//namespace to refer to service.
+func grantingReference(routeKind, routeNamespace, namespace, service string, related *relatedObjects) string {
+	for _, obj := range related.list("referencegrant", namespace) {
+		referenceGrant := obj.(*ReferenceGrant)
+
+		fromPermitted := false
+		for _, from := range referenceGrant.Spec.From {
+			if from.Group == GatewayAPIGroup && from.Kind == routeKind && from.Namespace == routeNamespace {
+				fromPermitted = true
+				break
+			}
+		}
+		if !fromPermitted {
+			continue
+		}
+
+		for _, to := range referenceGrant.Spec.To {
+			if to.Group == "" && to.Kind == "Service" && (to.Name == nil || *to.Name == "" || *to.Name == service) {
+				return referenceGrant.Name
+			}
+		}
+	}
+
+	return ""
+}
+
+func isServiceRef(backendRef BackendRef) bool {
+	if backendRef.Group != nil && *backendRef.Group != "" {
+		return false
+	}
+
+	return backendRef.Kind == nil || *backendRef.Kind == "Service"
+}
+
+func isGatewayAPIRef(group, kind *string, expectedKind string) bool {
+	if group != nil && *group != GatewayAPIGroup {
+		return false
+	}
+
+	return kind == nil || *kind == expectedKind
+}
@@ -0,0 +1,157 @@
+package kubemap
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/tools/cache"
+)
+
+func TestRoutesAreAttachedToBackendServices(t *testing.T) {
+	resources := helperGetGatewayResources()
+	namespace, service := resources.Services[0].Namespace, resources.Services[0].Name
+
+	mappedResources, err := NewMapper().Map(resources)
+	assert.Nil(t, err)
+	assert.Len(t, mappedResources.MappedResource, 1)
+
+	kube := mappedResources.MappedResource[0].Kube
+	assert.Len(t, kube.HTTPRoutes, 1)
+	assert.Equal(t, "web", kube.HTTPRoutes[0].Name)
+	assert.Len(t, kube.GRPCRoutes, 1)
+	assert.Equal(t, "api", kube.GRPCRoutes[0].Name)
+	assert.Len(t, kube.Gateways, 1)
+	assert.Equal(t, "public", kube.Gateways[0].Name)
+	assert.Len(t, kube.ReferenceGrants, 1)
+	assert.Equal(t, "from-frontend", kube.ReferenceGrants[0].Name)
+
+	port := int32(80)
+	assert.Equal(t, []RouteBackend{
+		{RouteKind: "GRPCRoute", RouteName: "api", RouteNamespace: "frontend", Service: service, Permitted: true, ReferenceGrant: "from-frontend"},
+		{RouteKind: "HTTPRoute", RouteName: "web", RouteNamespace: namespace, Service: service, Port: &port, Permitted: true},
+	}, kube.RouteBackends)
+}
+
+func TestRouteWithoutReferenceGrant(t *testing.T) {
+	resources := helperGetGatewayResources()
+	resources.ReferenceGrants = nil
+
+	mappedResources, err := NewMapper().Map(resources)
+	assert.Nil(t, err)
+
+	mappedResource := mappedResources.MappedResource[0]
+	assert.Len(t, mappedResource.Kube.GRPCRoutes, 1)
+	assert.Empty(t, mappedResource.Kube.ReferenceGrants)
+	assert.False(t, mappedResource.Kube.RouteBackends[0].Permitted)
+
+	for _, resource := range buildComponentNode(mappedResource).Resources {
+		if resource.Kind == "grpcroute" {
+			assert.Equal(t, HealthDegraded, resource.Health)
+		}
+	}
+}
+
+func TestTypedRouteEvent(t *testing.T) {
+	resources := helperGetK8sResources()
+	mapper := NewStoreMapper(cache.NewStore(metaResourceKeyFunc))
+
+	for _, event := range resourceEventsForMapping(resources) {
+		_, err := mapper.StoreMap(event)
+		assert.Nil(t, err)
+	}
+
+	route := &HTTPRoute{
+		ObjectMeta: meta_v1.ObjectMeta{Name: "typed", Namespace: resources.Services[0].Namespace},
+		Spec: HTTPRouteSpec{
+			Rules: []HTTPRouteRule{{BackendRefs: []BackendRef{{Name: resources.Services[0].Name}}}},
+		},
+	}
+	results, err := mapper.StoreMap(gerResourceEvent(route, "httproute"))
+	assert.Nil(t, err)
+	assert.Len(t, results, 1)
+	assert.Len(t, results[0].MappedResource.Kube.HTTPRoutes, 1)
+
+	_, err = mapper.StoreMap(gerResourceEvent(&Gateway{ObjectMeta: meta_v1.ObjectMeta{Name: "typed"}}, "httproute"))
+	assert.NotNil(t, err)
+}
+
+func TestGatewayConsistency(t *testing.T) {
+	events := resourceEventsForMapping(helperGetGatewayResources())
+	random := rand.New(rand.NewSource(41))
+
+	for i := 0; i < 30; i++ {
+		random.Shuffle(len(events), func(a, b int) {
+			events[a], events[b] = events[b], events[a]
+		})
+
+		report, err := CheckConsistency(events, MapOptions{})
+		assert.Nil(t, err)
+		assert.True(t, report.Consistent, "order %s diverged - %+v", helperEventOrder(events), report.Divergences)
+	}
+}
+
+//helperGetGatewayResources returns fixtures with an HTTPRoute to fixture service through a gateway of other namespace,
+//a GRPCRoute of other namespace permitted by a ReferenceGrant and a route to other service.
+func helperGetGatewayResources() KubeResources {
+	resources := helperGetK8sResources()
+	namespace, service := resources.Services[0].Namespace, resources.Services[0].Name
+
+	resources.Gateways = []unstructured.Unstructured{
+		helperGatewayAPIObject("Gateway", "public", "infra", map[string]interface{}{
+			"gatewayClassName": "istio",
+			"listeners":        []interface{}{map[string]interface{}{"name": "http", "port": int64(80), "protocol": "HTTP"}},
+		}),
+		helperGatewayAPIObject("Gateway", "internal", "infra", map[string]interface{}{"gatewayClassName": "istio"}),
+	}
+
+	resources.HTTPRoutes = []unstructured.Unstructured{
+		helperGatewayAPIObject("HTTPRoute", "web", namespace, map[string]interface{}{
+			"parentRefs": []interface{}{map[string]interface{}{"name": "public", "namespace": "infra"}},
+			"hostnames":  []interface{}{"shop.example.com"},
+			"rules": []interface{}{map[string]interface{}{
+				"matches":     []interface{}{map[string]interface{}{"path": map[string]interface{}{"type": "PathPrefix", "value": "/"}}},
+				"backendRefs": []interface{}{map[string]interface{}{"name": service, "port": int64(80)}},
+			}},
+		}),
+		helperGatewayAPIObject("HTTPRoute", "other", namespace, map[string]interface{}{
+			"parentRefs": []interface{}{map[string]interface{}{"name": "internal", "namespace": "infra"}},
+			"rules": []interface{}{map[string]interface{}{
+				"backendRefs": []interface{}{map[string]interface{}{"name": "other"}},
+			}},
+		}),
+	}
+
+	resources.GRPCRoutes = []unstructured.Unstructured{
+		helperGatewayAPIObject("GRPCRoute", "api", "frontend", map[string]interface{}{
+			"rules": []interface{}{map[string]interface{}{
+				"backendRefs": []interface{}{map[string]interface{}{"name": service, "namespace": namespace}},
+			}},
+		}),
+	}
+
+	resources.ReferenceGrants = []unstructured.Unstructured{
+		helperGatewayAPIObject("ReferenceGrant", "from-frontend", namespace, map[string]interface{}{
+			"from": []interface{}{map[string]interface{}{"group": GatewayAPIGroup, "kind": "GRPCRoute", "namespace": "frontend"}},
+			"to":   []interface{}{map[string]interface{}{"group": "", "kind": "Service"}},
+		}),
+		helperGatewayAPIObject("ReferenceGrant", "from-elsewhere", namespace, map[string]interface{}{
+			"from": []interface{}{map[string]interface{}{"group": GatewayAPIGroup, "kind": "HTTPRoute", "namespace": "elsewhere"}},
+			"to":   []interface{}{map[string]interface{}{"group": "", "kind": "Service"}},
+		}),
+	}
+
+	return resources
+}
+
+func helperGatewayAPIObject(kind, name, namespace string, spec map[string]interface{}) unstructured.Unstructured {
+	apiVersion := GatewayAPIGroup + "/v1"
+	if kind == "ReferenceGrant" {
+		apiVersion = GatewayAPIGroup + "/v1beta1"
+	}
+
+	return helperCustomResource(apiVersion, kind, name, namespace, map[string]interface{}{"spec": spec})
+}
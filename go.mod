@@ -3,7 +3,12 @@ module github.com/apollocse/kubemap
 go 1.12
 
 require (
+	github.com/magiconair/properties v1.8.1
+	github.com/stretchr/testify v1.8.1
+	go.etcd.io/bbolt v1.3.3
+	go.uber.org/zap v1.28.0
 	k8s.io/api v0.0.0-20190620084959-7cf5895f2711
 	k8s.io/apimachinery v0.0.0-20190612205821-1799e75a0719
 	k8s.io/client-go v0.0.0-20190620085101-78d2af792bab
+	sigs.k8s.io/yaml v1.1.0
 )
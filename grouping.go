@@ -0,0 +1,97 @@
+package kubemap
+
+import (
+	"sort"
+)
+
+const (
+	//PartOfLabel is recommended k8s label holding name of higher level application.
+	PartOfLabel = "app.kubernetes.io/part-of"
+	//InstanceLabel is recommended k8s label holding unique name of an application instance.
+	InstanceLabel = "app.kubernetes.io/instance"
+)
+
+//Application is parent of mapped resources which share value of one of grouping label keys.
+//Selector based mapped resources are its components.
+type Application struct {
+	Name       string `json:"name,omitempty"`
+	Namespace  string `json:"namespace,omitempty"`
+	LabelKey   string `json:"labelKey,omitempty"`
+	LabelValue string `json:"labelValue,omitempty"`
+	//Components are common labels of mapped resources belonging to this application.
+	Components []string `json:"components,omitempty"`
+}
+
+//GroupByLabels groups mapped resources into applications by given label keys.
+//Keys are tried in order and first key present on any member of a mapped resource decides its application.
+//Application of each grouped mapped resource is set on returned copy.
+func GroupByLabels(mappedResources MappedResources, labelKeys []string) MappedResources {
+	if len(labelKeys) == 0 {
+		return mappedResources
+	}
+
+	grouped := MappedResources{}
+	applications := make(map[string]*Application)
+	var applicationKeys []string
+
+	for _, mappedResource := range mappedResources.MappedResource {
+		labelKey, labelValue := applicationLabel(mappedResource, labelKeys)
+		if labelKey == "" {
+			grouped.MappedResource = append(grouped.MappedResource, mappedResource)
+			continue
+		}
+
+		applicationKey := mappedResource.Namespace + "/" + labelKey + "=" + labelValue
+		application, exists := applications[applicationKey]
+		if !exists {
+			application = &Application{
+				Name:       labelValue,
+				Namespace:  mappedResource.Namespace,
+				LabelKey:   labelKey,
+				LabelValue: labelValue,
+			}
+			applications[applicationKey] = application
+			applicationKeys = append(applicationKeys, applicationKey)
+		}
+		application.Components = append(application.Components, mappedResource.CommonLabel)
+
+		mappedResource.Application = application.Name
+		grouped.MappedResource = append(grouped.MappedResource, mappedResource)
+	}
+
+	sort.Strings(applicationKeys)
+	for _, applicationKey := range applicationKeys {
+		application := applications[applicationKey]
+		sort.Strings(application.Components)
+		grouped.Applications = append(grouped.Applications, *application)
+	}
+
+	return grouped
+}
+
+//Applications returns mapped resources in store grouped by MapOptions.Grouping label keys.
+func (m *Mapper) Applications() []Application {
+	mappedResources := GroupByLabels(getAllMappedResources(m.store), m.options.Grouping.LabelKeys)
+
+	return mappedResources.Applications
+}
+
+//applicationLabel returns first of label keys present on members of mapped resource along with its smallest value.
+func applicationLabel(mappedResource MappedResource, labelKeys []string) (string, string) {
+	labels := memberLabels(mappedResource)
+
+	for _, labelKey := range labelKeys {
+		var values []string
+		for _, memberLabel := range labels {
+			if value, ok := memberLabel[labelKey]; ok && value != "" {
+				values = append(values, value)
+			}
+		}
+
+		if len(values) > 0 {
+			return labelKey, smallestString(values)
+		}
+	}
+
+	return "", ""
+}
@@ -0,0 +1,82 @@
+package kubemap
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	apps_v1 "k8s.io/api/apps/v1"
+	core_v1 "k8s.io/api/core/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestGroupByLabels(t *testing.T) {
+	mappedResources := MappedResources{MappedResource: []MappedResource{
+		helperMappedDeployment("shop", "api", map[string]string{PartOfLabel: "shop", InstanceLabel: "shop-prod"}),
+		helperMappedDeployment("shop", "worker", map[string]string{PartOfLabel: "shop"}),
+		helperMappedDeployment("shop", "cron", map[string]string{InstanceLabel: "shop-prod"}),
+		helperMappedDeployment("other", "api", map[string]string{PartOfLabel: "shop"}),
+		helperMappedDeployment("shop", "standalone", nil),
+	}}
+
+	grouped := GroupByLabels(mappedResources, []string{PartOfLabel, InstanceLabel})
+
+	assert.Len(t, grouped.MappedResource, 5)
+	assert.Equal(t, []Application{
+		{Name: "shop", Namespace: "other", LabelKey: PartOfLabel, LabelValue: "shop", Components: []string{"api"}},
+		{Name: "shop-prod", Namespace: "shop", LabelKey: InstanceLabel, LabelValue: "shop-prod", Components: []string{"cron"}},
+		{Name: "shop", Namespace: "shop", LabelKey: PartOfLabel, LabelValue: "shop", Components: []string{"api", "worker"}},
+	}, grouped.Applications)
+
+	applicationOf := map[string]string{}
+	for _, mappedResource := range grouped.MappedResource {
+		applicationOf[mappedResource.Namespace+"/"+mappedResource.CommonLabel] = mappedResource.Application
+	}
+	assert.Equal(t, "shop", applicationOf["shop/worker"])
+	assert.Equal(t, "shop-prod", applicationOf["shop/cron"])
+	assert.Equal(t, "", applicationOf["shop/standalone"])
+}
+
+func TestGroupByLabelsWithoutKeys(t *testing.T) {
+	mappedResources := MappedResources{MappedResource: []MappedResource{
+		helperMappedDeployment("shop", "api", map[string]string{PartOfLabel: "shop"}),
+	}}
+
+	grouped := GroupByLabels(mappedResources, nil)
+	assert.Empty(t, grouped.Applications)
+	assert.Empty(t, grouped.MappedResource[0].Application)
+}
+
+func TestMapWithLabelGrouping(t *testing.T) {
+	resources := helperGetK8sResources()
+	for i := range resources.Pods {
+		resources.Pods[i].Labels[PartOfLabel] = "kube-map-suite"
+	}
+
+	mapper, _ := NewMapperWithOptions(MapOptions{
+		Grouping: GroupingOptions{
+			LabelKeys: []string{PartOfLabel},
+		},
+	})
+	mappedResources, _ := mapper.Map(resources)
+
+	assert.Len(t, mappedResources.Applications, 1)
+	assert.Equal(t, "kube-map-suite", mappedResources.Applications[0].Name)
+	assert.Equal(t, "kube-map-suite", mappedResources.MappedResource[0].Application)
+	assert.Len(t, mapper.Applications(), 1)
+}
+
+func helperMappedDeployment(namespace, name string, labels map[string]string) MappedResource {
+	return MappedResource{
+		CommonLabel: name,
+		Namespace:   namespace,
+		Kube: Kube{
+			Deployments: []apps_v1.Deployment{{
+				ObjectMeta: meta_v1.ObjectMeta{Name: name, Namespace: namespace, Labels: labels},
+			}},
+			Pods: []core_v1.Pod{{
+				ObjectMeta: meta_v1.ObjectMeta{Name: name + "-pod", Namespace: namespace},
+			}},
+		},
+	}
+}
@@ -0,0 +1,502 @@
+package kubemap
+
+import (
+	"sort"
+
+	apps_v1 "k8s.io/api/apps/v1"
+	autoscaling_v2beta2 "k8s.io/api/autoscaling/v2beta2"
+	core_v1 "k8s.io/api/core/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+const (
+	//HealthHealthy means resource is running as desired.
+	HealthHealthy = "Healthy"
+	//HealthUnknown means health of resource cannot be determined from its status.
+	HealthUnknown = "Unknown"
+	//HealthProgressing means resource is not yet running as desired but may still get there.
+	HealthProgressing = "Progressing"
+	//HealthDegraded means resource failed or has given up getting to desired state.
+	HealthDegraded = "Degraded"
+)
+
+//healthSeverity orders health from best to worst. Aggregate health of a node is worst health of its children.
+var healthSeverity = map[string]int{
+	HealthHealthy:     0,
+	HealthUnknown:     1,
+	HealthProgressing: 2,
+	HealthDegraded:    3,
+}
+
+//Hierarchy is tree view of mapped resources viz namespace -> application -> component -> resource.
+//Its JSON form is described by schema/hierarchy.schema.json.
+type Hierarchy struct {
+	HierarchySummary
+	Namespaces []NamespaceNode `json:"namespaces,omitempty"`
+	//Dependencies are inferred edges between components. See InferDependencies.
+	Dependencies []Dependency `json:"dependencies,omitempty"`
+}
+
+//HierarchySummary is aggregate of all resources under a node of hierarchy.
+type HierarchySummary struct {
+	Health string `json:"health"`
+	//Counts is number of resources under node by kind e.g. 'pod'.
+	Counts map[string]int `json:"counts"`
+	Total  int            `json:"total"`
+}
+
+//NamespaceNode holds applications and components of a namespace.
+//Components which are not part of any application are direct children of namespace.
+type NamespaceNode struct {
+	HierarchySummary
+	Name         string            `json:"name"`
+	Applications []ApplicationNode `json:"applications,omitempty"`
+	Components   []ComponentNode   `json:"components,omitempty"`
+}
+
+//ApplicationNode holds components grouped by GroupingOptions label keys.
+type ApplicationNode struct {
+	HierarchySummary
+	Name       string          `json:"name"`
+	LabelKey   string          `json:"labelKey,omitempty"`
+	LabelValue string          `json:"labelValue,omitempty"`
+	Components []ComponentNode `json:"components,omitempty"`
+}
+
+//ComponentNode is a single mapped resource.
+type ComponentNode struct {
+	HierarchySummary
+	CommonLabel string         `json:"commonLabel"`
+	Resources   []ResourceNode `json:"resources,omitempty"`
+}
+
+//ResourceNode is a single k8s object of a mapped resource.
+type ResourceNode struct {
+	Kind   string `json:"kind"`
+	Name   string `json:"name"`
+	Health string `json:"health"`
+	//Reason explains health other than healthy.
+	Reason string `json:"reason,omitempty"`
+}
+
+//BuildHierarchy nests mapped resources under their namespace and application.
+//Applications are taken from MappedResources.Applications, see GroupByLabels.
+//Nodes at every level are sorted by name. Dependencies between components are included as edges, which need
+//unredacted mapped resources as explained by InferDependencies. Mapper.Hierarchy builds it from store which is.
+func BuildHierarchy(mappedResources MappedResources) Hierarchy {
+	applications := make(map[string]Application)
+	for _, application := range mappedResources.Applications {
+		applications[application.Namespace+"/"+application.Name] = application
+	}
+
+	namespaces := make(map[string]*NamespaceNode)
+	namespaceApplications := make(map[string]map[string]*ApplicationNode)
+
+	for _, mappedResource := range mappedResources.MappedResource {
+		namespace, exists := namespaces[mappedResource.Namespace]
+		if !exists {
+			namespace = &NamespaceNode{Name: mappedResource.Namespace}
+			namespaces[mappedResource.Namespace] = namespace
+			namespaceApplications[mappedResource.Namespace] = make(map[string]*ApplicationNode)
+		}
+
+		component := buildComponentNode(mappedResource)
+
+		if mappedResource.Application == "" {
+			namespace.Components = append(namespace.Components, component)
+			continue
+		}
+
+		application, exists := namespaceApplications[mappedResource.Namespace][mappedResource.Application]
+		if !exists {
+			groupingApplication := applications[mappedResource.Namespace+"/"+mappedResource.Application]
+			application = &ApplicationNode{
+				Name:       mappedResource.Application,
+				LabelKey:   groupingApplication.LabelKey,
+				LabelValue: groupingApplication.LabelValue,
+			}
+			namespaceApplications[mappedResource.Namespace][mappedResource.Application] = application
+		}
+		application.Components = append(application.Components, component)
+	}
+
+	hierarchy := Hierarchy{}
+	var summaries []HierarchySummary
+
+	for _, namespace := range namespaces {
+		var namespaceSummaries []HierarchySummary
+
+		for _, application := range namespaceApplications[namespace.Name] {
+			sortComponentNodes(application.Components)
+			application.HierarchySummary = summarizeComponents(application.Components)
+			namespace.Applications = append(namespace.Applications, *application)
+			namespaceSummaries = append(namespaceSummaries, application.HierarchySummary)
+		}
+		sort.Slice(namespace.Applications, func(i, j int) bool {
+			return namespace.Applications[i].Name < namespace.Applications[j].Name
+		})
+
+		sortComponentNodes(namespace.Components)
+		for _, component := range namespace.Components {
+			namespaceSummaries = append(namespaceSummaries, component.HierarchySummary)
+		}
+
+		namespace.HierarchySummary = mergeSummaries(namespaceSummaries)
+		hierarchy.Namespaces = append(hierarchy.Namespaces, *namespace)
+		summaries = append(summaries, namespace.HierarchySummary)
+	}
+	sort.Slice(hierarchy.Namespaces, func(i, j int) bool {
+		return hierarchy.Namespaces[i].Name < hierarchy.Namespaces[j].Name
+	})
+
+	hierarchy.HierarchySummary = mergeSummaries(summaries)
+	hierarchy.Dependencies = InferDependencies(mappedResources)
+
+	return hierarchy
+}
+
+//Hierarchy returns tree view of mapped resources in store.
+//Applications are present only if MapOptions.Grouping is set.
+func (m *Mapper) Hierarchy() Hierarchy {
+	return BuildHierarchy(GroupByLabels(getAllMappedResources(m.store), m.options.Grouping.LabelKeys))
+}
+
+func buildComponentNode(mappedResource MappedResource) ComponentNode {
+	component := ComponentNode{CommonLabel: mappedResource.CommonLabel}
+
+	for _, ingress := range mappedResource.Kube.Ingresses {
+		component.Resources = append(component.Resources, ResourceNode{Kind: "ingress", Name: ingress.Name, Health: HealthHealthy})
+	}
+
+	for _, service := range mappedResource.Kube.Services {
+		health, reason := serviceHealth(service)
+		component.Resources = append(component.Resources, ResourceNode{Kind: "service", Name: service.Name, Health: health, Reason: reason})
+	}
+
+	for _, deployment := range mappedResource.Kube.Deployments {
+		health, reason := deploymentHealth(deployment)
+		component.Resources = append(component.Resources, ResourceNode{Kind: "deployment", Name: deployment.Name, Health: health, Reason: reason})
+	}
+
+	for _, replicaSet := range mappedResource.Kube.ReplicaSets {
+		health, reason := replicaSetHealth(replicaSet)
+		component.Resources = append(component.Resources, ResourceNode{Kind: "replicaset", Name: replicaSet.Name, Health: health, Reason: reason})
+	}
+
+	for _, pod := range mappedResource.Kube.Pods {
+		health, reason := podHealth(pod)
+		component.Resources = append(component.Resources, ResourceNode{Kind: "pod", Name: pod.Name, Health: health, Reason: reason})
+	}
+
+	for _, hpa := range mappedResource.Kube.HorizontalPodAutoscalers {
+		health, reason := autoscalerHealth(hpa)
+		component.Resources = append(component.Resources, ResourceNode{Kind: "hpa", Name: hpa.Name, Health: health, Reason: reason})
+	}
+
+	for _, configMap := range mappedResource.Kube.ConfigMaps {
+		component.Resources = append(component.Resources, ResourceNode{Kind: "configmap", Name: configMap.Name, Health: HealthHealthy})
+	}
+
+	for _, secret := range mappedResource.Kube.Secrets {
+		component.Resources = append(component.Resources, ResourceNode{Kind: "secret", Name: secret.Name, Health: HealthHealthy})
+	}
+
+	for _, pvc := range mappedResource.Kube.PersistentVolumeClaims {
+		health, reason := persistentVolumeClaimHealth(pvc)
+		component.Resources = append(component.Resources, ResourceNode{Kind: "pvc", Name: pvc.Name, Health: health, Reason: reason})
+	}
+
+	for _, pv := range mappedResource.Kube.PersistentVolumes {
+		health, reason := persistentVolumeHealth(pv)
+		component.Resources = append(component.Resources, ResourceNode{Kind: "pv", Name: pv.Name, Health: health, Reason: reason})
+	}
+
+	for _, endpoints := range mappedResource.Kube.Endpoints {
+		health, reason := endpointsHealth(endpoints)
+		component.Resources = append(component.Resources, ResourceNode{Kind: "endpoints", Name: endpoints.Name, Health: health, Reason: reason})
+	}
+
+	for _, endpointSlice := range mappedResource.Kube.EndpointSlices {
+		health, reason := endpointSliceHealth(endpointSlice)
+		component.Resources = append(component.Resources, ResourceNode{Kind: "endpointslice", Name: endpointSlice.Name, Health: health, Reason: reason})
+	}
+
+	for _, pdb := range mappedResource.Kube.PodDisruptionBudgets {
+		health, reason := HealthHealthy, ""
+		if allowsZeroDisruptions(pdb, mappedResource) {
+			health, reason = HealthDegraded, "Budget allows zero disruptions"
+		}
+		component.Resources = append(component.Resources, ResourceNode{Kind: "pdb", Name: pdb.Name, Health: health, Reason: reason})
+	}
+
+	for _, networkPolicy := range mappedResource.Kube.NetworkPolicies {
+		component.Resources = append(component.Resources, ResourceNode{Kind: "networkpolicy", Name: networkPolicy.Name, Health: HealthHealthy})
+	}
+
+	for _, serviceAccount := range mappedResource.Kube.ServiceAccounts {
+		component.Resources = append(component.Resources, ResourceNode{Kind: "serviceaccount", Name: serviceAccount.Name, Health: HealthHealthy})
+	}
+
+	for _, roleBinding := range mappedResource.Kube.RoleBindings {
+		component.Resources = append(component.Resources, ResourceNode{Kind: "rolebinding", Name: roleBinding.Name, Health: HealthHealthy})
+	}
+
+	for _, clusterRoleBinding := range mappedResource.Kube.ClusterRoleBindings {
+		component.Resources = append(component.Resources, ResourceNode{Kind: "clusterrolebinding", Name: clusterRoleBinding.Name, Health: HealthHealthy})
+	}
+
+	for _, gateway := range mappedResource.Kube.Gateways {
+		component.Resources = append(component.Resources, ResourceNode{Kind: "gateway", Name: gateway.Name, Health: HealthHealthy})
+	}
+
+	for _, httpRoute := range mappedResource.Kube.HTTPRoutes {
+		health, reason := routeHealth("HTTPRoute", httpRoute.ObjectMeta, mappedResource.Kube.RouteBackends)
+		component.Resources = append(component.Resources, ResourceNode{Kind: "httproute", Name: httpRoute.Name, Health: health, Reason: reason})
+	}
+
+	for _, grpcRoute := range mappedResource.Kube.GRPCRoutes {
+		health, reason := routeHealth("GRPCRoute", grpcRoute.ObjectMeta, mappedResource.Kube.RouteBackends)
+		component.Resources = append(component.Resources, ResourceNode{Kind: "grpcroute", Name: grpcRoute.Name, Health: health, Reason: reason})
+	}
+
+	for _, referenceGrant := range mappedResource.Kube.ReferenceGrants {
+		component.Resources = append(component.Resources, ResourceNode{Kind: "referencegrant", Name: referenceGrant.Name, Health: HealthHealthy})
+	}
+
+	for _, node := range mappedResource.Kube.Nodes {
+		health, reason := nodeHealth(node)
+		component.Resources = append(component.Resources, ResourceNode{Kind: "node", Name: node.Name, Health: health, Reason: reason})
+	}
+
+	for i := range mappedResource.Kube.CustomResources {
+		customResource := &mappedResource.Kube.CustomResources[i]
+		health, reason := customResourceHealth(customResource)
+		component.Resources = append(component.Resources, ResourceNode{Kind: customResourceTypeOf(customResource), Name: customResource.GetName(), Health: health, Reason: reason})
+	}
+
+	summary := HierarchySummary{Health: HealthHealthy, Counts: make(map[string]int)}
+	for _, resource := range component.Resources {
+		summary.Counts[resource.Kind]++
+		summary.Total++
+		summary.Health = worseHealth(summary.Health, resource.Health)
+	}
+	if len(mappedResource.Kube.MissingReferences) > 0 {
+		//Pods cannot start without objects they require.
+		summary.Health = HealthDegraded
+	}
+	component.HierarchySummary = summary
+
+	return component
+}
+
+func sortComponentNodes(components []ComponentNode) {
+	sort.Slice(components, func(i, j int) bool {
+		return components[i].CommonLabel < components[j].CommonLabel
+	})
+}
+
+func summarizeComponents(components []ComponentNode) HierarchySummary {
+	var summaries []HierarchySummary
+	for _, component := range components {
+		summaries = append(summaries, component.HierarchySummary)
+	}
+
+	return mergeSummaries(summaries)
+}
+
+//mergeSummaries adds up counts and takes worst health of summaries.
+func mergeSummaries(summaries []HierarchySummary) HierarchySummary {
+	merged := HierarchySummary{Health: HealthHealthy, Counts: make(map[string]int)}
+
+	for _, summary := range summaries {
+		for kind, count := range summary.Counts {
+			merged.Counts[kind] += count
+		}
+		merged.Total += summary.Total
+		merged.Health = worseHealth(merged.Health, summary.Health)
+	}
+
+	return merged
+}
+
+func worseHealth(a, b string) string {
+	if healthSeverity[b] > healthSeverity[a] {
+		return b
+	}
+
+	return a
+}
+
+func serviceHealth(service core_v1.Service) (string, string) {
+	if service.Spec.Type == core_v1.ServiceTypeLoadBalancer && len(service.Status.LoadBalancer.Ingress) == 0 {
+		return HealthProgressing, "Load balancer is not provisioned"
+	}
+
+	return HealthHealthy, ""
+}
+
+func deploymentHealth(deployment apps_v1.Deployment) (string, string) {
+	for _, condition := range deployment.Status.Conditions {
+		if condition.Type == apps_v1.DeploymentProgressing && condition.Status == core_v1.ConditionFalse {
+			return HealthDegraded, condition.Reason
+		}
+	}
+
+	desired := int32(1)
+	if deployment.Spec.Replicas != nil {
+		desired = *deployment.Spec.Replicas
+	}
+
+	if deployment.Status.AvailableReplicas < desired {
+		return HealthProgressing, "Available replicas are less than desired"
+	}
+
+	return HealthHealthy, ""
+}
+
+func replicaSetHealth(replicaSet apps_v1.ReplicaSet) (string, string) {
+	desired := int32(1)
+	if replicaSet.Spec.Replicas != nil {
+		desired = *replicaSet.Spec.Replicas
+	}
+
+	if replicaSet.Status.ReadyReplicas < desired {
+		return HealthProgressing, "Ready replicas are less than desired"
+	}
+
+	return HealthHealthy, ""
+}
+
+func podHealth(pod core_v1.Pod) (string, string) {
+	for _, containerStatus := range pod.Status.ContainerStatuses {
+		if containerStatus.State.Waiting != nil && containerStatus.State.Waiting.Reason == "CrashLoopBackOff" {
+			return HealthDegraded, "Container " + containerStatus.Name + " is in CrashLoopBackOff"
+		}
+	}
+
+	switch pod.Status.Phase {
+	case core_v1.PodSucceeded:
+		return HealthHealthy, ""
+	case core_v1.PodFailed:
+		return HealthDegraded, pod.Status.Reason
+	case core_v1.PodPending:
+		return HealthProgressing, "Pod is pending"
+	case core_v1.PodRunning:
+		for _, condition := range pod.Status.Conditions {
+			if condition.Type == core_v1.PodReady && condition.Status != core_v1.ConditionTrue {
+				return HealthProgressing, "Pod is not ready"
+			}
+		}
+		return HealthHealthy, ""
+	}
+
+	return HealthUnknown, ""
+}
+
+func autoscalerHealth(hpa autoscaling_v2beta2.HorizontalPodAutoscaler) (string, string) {
+	for _, condition := range hpa.Status.Conditions {
+		if condition.Status != core_v1.ConditionFalse {
+			continue
+		}
+
+		switch condition.Type {
+		case autoscaling_v2beta2.AbleToScale:
+			return HealthDegraded, condition.Reason
+		case autoscaling_v2beta2.ScalingActive:
+			return HealthProgressing, condition.Reason
+		}
+	}
+
+	return HealthHealthy, ""
+}
+
+func persistentVolumeClaimHealth(pvc core_v1.PersistentVolumeClaim) (string, string) {
+	switch pvc.Status.Phase {
+	case core_v1.ClaimBound:
+		return HealthHealthy, ""
+	case core_v1.ClaimPending:
+		return HealthProgressing, "Claim is not bound"
+	case core_v1.ClaimLost:
+		return HealthDegraded, "Bound volume is lost"
+	}
+
+	return HealthUnknown, ""
+}
+
+func persistentVolumeHealth(pv core_v1.PersistentVolume) (string, string) {
+	switch pv.Status.Phase {
+	case core_v1.VolumeBound, core_v1.VolumeAvailable, core_v1.VolumeReleased:
+		return HealthHealthy, ""
+	case core_v1.VolumePending:
+		return HealthProgressing, pv.Status.Reason
+	case core_v1.VolumeFailed:
+		return HealthDegraded, pv.Status.Reason
+	}
+
+	return HealthUnknown, ""
+}
+
+//nodeHealth only tells whether node is cordoned, as conditions of nodes are not kept. See nodeMetadata.
+func nodeHealth(node core_v1.Node) (string, string) {
+	if node.Spec.Unschedulable {
+		return HealthDegraded, "Node is cordoned"
+	}
+
+	return HealthHealthy, ""
+}
+
+func endpointsHealth(endpoints core_v1.Endpoints) (string, string) {
+	for _, subset := range endpoints.Subsets {
+		if len(subset.Addresses) > 0 {
+			return HealthHealthy, ""
+		}
+	}
+
+	return HealthDegraded, "No ready addresses"
+}
+
+func endpointSliceHealth(endpointSlice EndpointSlice) (string, string) {
+	for _, endpoint := range endpointSlice.Endpoints {
+		if endpoint.Conditions.Ready == nil || *endpoint.Conditions.Ready {
+			return HealthHealthy, ""
+		}
+	}
+
+	return HealthDegraded, "No ready endpoints"
+}
+
+//routeHealth is degraded if route refers to a service of group in other namespace without a ReferenceGrant.
+func routeHealth(routeKind string, routeMeta meta_v1.ObjectMeta, routeBackends []RouteBackend) (string, string) {
+	for _, routeBackend := range routeBackends {
+		if routeBackend.RouteKind == routeKind && routeBackend.RouteName == routeMeta.Name && routeBackend.RouteNamespace == routeMeta.Namespace && !routeBackend.Permitted {
+			return HealthDegraded, "Reference to service " + routeBackend.Service + " is not permitted by a ReferenceGrant"
+		}
+	}
+
+	return HealthHealthy, ""
+}
+
+//customResourceHealth is decided by 'Ready' or 'Available' condition of status, which most controllers set.
+//Objects without such condition e.g. routing rules are considered healthy.
+func customResourceHealth(customResource *unstructured.Unstructured) (string, string) {
+	conditions, _, _ := unstructured.NestedSlice(customResource.Object, "status", "conditions")
+	for _, item := range conditions {
+		condition, ok := item.(map[string]interface{})
+		if !ok || (condition["type"] != "Ready" && condition["type"] != "Available") {
+			continue
+		}
+
+		message, _ := condition["message"].(string)
+		switch condition["status"] {
+		case "True":
+			return HealthHealthy, ""
+		case "False":
+			return HealthDegraded, message
+		default:
+			return HealthProgressing, message
+		}
+	}
+
+	return HealthHealthy, ""
+}
@@ -0,0 +1,197 @@
+package kubemap
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	core_v1 "k8s.io/api/core/v1"
+)
+
+func TestBuildHierarchy(t *testing.T) {
+	mappedResources := MappedResources{MappedResource: []MappedResource{
+		helperMappedDeployment("shop", "api", map[string]string{PartOfLabel: "shop"}),
+		helperMappedDeployment("shop", "worker", map[string]string{PartOfLabel: "shop"}),
+		helperMappedDeployment("shop", "standalone", nil),
+		helperMappedDeployment("other", "api", nil),
+	}}
+	mappedResources.MappedResource[1].Kube.Pods[0].Status.Phase = core_v1.PodFailed
+	for i := range mappedResources.MappedResource {
+		mappedResources.MappedResource[i].Kube.Deployments[0].Status.AvailableReplicas = 1
+	}
+	mappedResources.MappedResource[0].Kube.Pods[0].Status.Phase = core_v1.PodRunning
+	mappedResources.MappedResource[2].Kube.Pods[0].Status.Phase = core_v1.PodPending
+	mappedResources.MappedResource[3].Kube.Pods[0].Status.Phase = core_v1.PodRunning
+
+	hierarchy := BuildHierarchy(GroupByLabels(mappedResources, []string{PartOfLabel}))
+
+	assert.Equal(t, HealthDegraded, hierarchy.Health)
+	assert.Equal(t, 8, hierarchy.Total)
+	assert.Equal(t, map[string]int{"deployment": 4, "pod": 4}, hierarchy.Counts)
+
+	assert.Len(t, hierarchy.Namespaces, 2)
+	other, shop := hierarchy.Namespaces[0], hierarchy.Namespaces[1]
+
+	assert.Equal(t, "other", other.Name)
+	assert.Equal(t, HealthHealthy, other.Health)
+	assert.Empty(t, other.Applications)
+	assert.Len(t, other.Components, 1)
+
+	assert.Equal(t, "shop", shop.Name)
+	assert.Equal(t, HealthDegraded, shop.Health)
+	assert.Equal(t, 6, shop.Total)
+	assert.Len(t, shop.Applications, 1)
+	assert.Equal(t, "shop", shop.Applications[0].Name)
+	assert.Equal(t, PartOfLabel, shop.Applications[0].LabelKey)
+	assert.Equal(t, HealthDegraded, shop.Applications[0].Health)
+	assert.Equal(t, []string{"api", "worker"}, []string{shop.Applications[0].Components[0].CommonLabel, shop.Applications[0].Components[1].CommonLabel})
+	assert.Len(t, shop.Components, 1)
+	assert.Equal(t, "standalone", shop.Components[0].CommonLabel)
+	assert.Equal(t, HealthProgressing, shop.Components[0].Health)
+	assert.Equal(t, ResourceNode{Kind: "pod", Name: "standalone-pod", Health: HealthProgressing, Reason: "Pod is pending"}, shop.Components[0].Resources[1])
+}
+
+func TestPodHealth(t *testing.T) {
+	podHealthTests := map[string]struct {
+		status core_v1.PodStatus
+		health string
+	}{
+		"Running_And_Ready": {
+			status: core_v1.PodStatus{Phase: core_v1.PodRunning, Conditions: []core_v1.PodCondition{{Type: core_v1.PodReady, Status: core_v1.ConditionTrue}}},
+			health: HealthHealthy,
+		},
+		"Running_Not_Ready": {
+			status: core_v1.PodStatus{Phase: core_v1.PodRunning, Conditions: []core_v1.PodCondition{{Type: core_v1.PodReady, Status: core_v1.ConditionFalse}}},
+			health: HealthProgressing,
+		},
+		"Crash_Loop": {
+			status: core_v1.PodStatus{Phase: core_v1.PodRunning, ContainerStatuses: []core_v1.ContainerStatus{{Name: "app", State: core_v1.ContainerState{Waiting: &core_v1.ContainerStateWaiting{Reason: "CrashLoopBackOff"}}}}},
+			health: HealthDegraded,
+		},
+		"Succeeded": {
+			status: core_v1.PodStatus{Phase: core_v1.PodSucceeded},
+			health: HealthHealthy,
+		},
+		"Without_Status": {
+			health: HealthUnknown,
+		},
+	}
+
+	for testName, test := range podHealthTests {
+		t.Run(testName, func(t *testing.T) {
+			health, _ := podHealth(core_v1.Pod{Status: test.status})
+			assert.Equal(t, test.health, health)
+		})
+	}
+}
+
+func TestHierarchyMatchesSchema(t *testing.T) {
+	schemaBytes, err := ioutil.ReadFile("schema/hierarchy.schema.json")
+	assert.Nil(t, err)
+
+	var schema map[string]interface{}
+	assert.Nil(t, json.Unmarshal(schemaBytes, &schema))
+
+	mapper, _ := NewMapperWithOptions(MapOptions{Grouping: GroupingOptions{LabelKeys: []string{AppLabel}}})
+	_, err = mapper.Map(helperGetK8sResources())
+	assert.Nil(t, err)
+
+	hierarchyBytes, err := json.Marshal(mapper.Hierarchy())
+	assert.Nil(t, err)
+
+	var hierarchy interface{}
+	assert.Nil(t, json.Unmarshal(hierarchyBytes, &hierarchy))
+
+	assert.Empty(t, helperSchemaViolations(schema, schema, hierarchy, "$"))
+}
+
+//helperSchemaViolations checks value against subset of JSON schema used by schema directory.
+func helperSchemaViolations(root, schema map[string]interface{}, value interface{}, path string) []string {
+	if ref, ok := schema["$ref"].(string); ok {
+		definition := root
+		for _, part := range strings.Split(strings.TrimPrefix(ref, "#/"), "/") {
+			definition = definition[part].(map[string]interface{})
+		}
+		return helperSchemaViolations(root, definition, value, path)
+	}
+
+	var violations []string
+
+	switch schema["type"] {
+	case "object":
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return []string{path + " is not an object"}
+		}
+
+		for _, required := range schemaStrings(schema["required"]) {
+			if _, ok := object[required]; !ok {
+				violations = append(violations, path+"."+required+" is required")
+			}
+		}
+
+		properties, _ := schema["properties"].(map[string]interface{})
+		for key, field := range object {
+			if property, ok := properties[key]; ok {
+				violations = append(violations, helperSchemaViolations(root, property.(map[string]interface{}), field, path+"."+key)...)
+			} else if additional, ok := schema["additionalProperties"].(map[string]interface{}); ok {
+				violations = append(violations, helperSchemaViolations(root, additional, field, path+"."+key)...)
+			} else if schema["additionalProperties"] == false {
+				violations = append(violations, path+"."+key+" is not allowed")
+			}
+		}
+	case "array":
+		array, ok := value.([]interface{})
+		if !ok {
+			return []string{path + " is not an array"}
+		}
+
+		for _, item := range array {
+			violations = append(violations, helperSchemaViolations(root, schema["items"].(map[string]interface{}), item, path+"[]")...)
+		}
+	case "string":
+		text, ok := value.(string)
+		if !ok {
+			return []string{path + " is not a string"}
+		}
+
+		if enum := schemaStrings(schema["enum"]); len(enum) > 0 {
+			allowed := false
+			for _, option := range enum {
+				allowed = allowed || option == text
+			}
+			if !allowed {
+				violations = append(violations, path+" has value "+text+" which is not allowed")
+			}
+		}
+	case "integer":
+		if _, ok := value.(float64); !ok {
+			return []string{path + " is not an integer"}
+		}
+	case "number":
+		number, ok := value.(float64)
+		if !ok {
+			return []string{path + " is not a number"}
+		}
+
+		if maximum, ok := schema["maximum"].(float64); ok && number > maximum {
+			violations = append(violations, path+" is greater than maximum")
+		}
+	}
+
+	return violations
+}
+
+func schemaStrings(value interface{}) []string {
+	var strs []string
+
+	items, _ := value.([]interface{})
+	for _, item := range items {
+		strs = append(strs, item.(string))
+	}
+
+	return strs
+}
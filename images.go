@@ -0,0 +1,315 @@
+package kubemap
+
+import (
+	"sort"
+
+	apps_v1 "k8s.io/api/apps/v1"
+	core_v1 "k8s.io/api/core/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	//ImageDriftTemplate means a pod runs an image other than template of its ReplicaSet or Deployment.
+	ImageDriftTemplate = "TemplateMismatch"
+	//ImageDriftDigest means pods of same template run different digests of same tag e.g. 'latest' pulled at different times.
+	ImageDriftDigest = "DigestMismatch"
+	//ImageDriftRollout means a ReplicaSet with replicas runs other images than its Deployment, usually mid-rollout.
+	ImageDriftRollout = "RolloutInProgress"
+)
+
+//ImageInventory is container images of a mapped resource and drift between them.
+type ImageInventory struct {
+	Namespace   string           `json:"namespace,omitempty"`
+	CommonLabel string           `json:"commonLabel,omitempty"`
+	Images      []ContainerImage `json:"images,omitempty"`
+	Drift       []ImageDrift     `json:"drift,omitempty"`
+}
+
+//ContainerImage is image of a container of a deployment, replica set or pod. Init and sidecar containers included.
+type ContainerImage struct {
+	Kind      string `json:"kind"`
+	Name      string `json:"name"`
+	Container string `json:"container"`
+	Init      bool   `json:"init,omitempty"`
+	Image     string `json:"image"`
+	//Repository is normalized as docker does e.g. 'docker.io/library/nginx' for 'nginx'.
+	Repository string `json:"repository"`
+	Tag        string `json:"tag,omitempty"`
+	//Digest is digest of image reference or, for pods, digest of image running as per container status.
+	Digest string `json:"digest,omitempty"`
+}
+
+//ImageDrift is a container running other image than expected.
+type ImageDrift struct {
+	//Reason is one of ImageDriftTemplate, ImageDriftDigest or ImageDriftRollout.
+	Reason string `json:"reason"`
+	//Kind is either 'pod' or 'replicaset'.
+	Kind       string `json:"kind"`
+	Name       string `json:"name"`
+	Deployment string `json:"deployment,omitempty"`
+	Container  string `json:"container"`
+	Expected   string `json:"expected"`
+	Actual     string `json:"actual"`
+}
+
+//ImageInventories returns image inventories of mapped resources having containers sorted by namespace and common label.
+func ImageInventories(mappedResources MappedResources) []ImageInventory {
+	var inventories []ImageInventory
+	for _, mappedResource := range mappedResources.MappedResource {
+		inventory := BuildImageInventory(mappedResource)
+		if len(inventory.Images) > 0 {
+			inventories = append(inventories, inventory)
+		}
+	}
+
+	sort.SliceStable(inventories, func(i, j int) bool {
+		if inventories[i].Namespace != inventories[j].Namespace {
+			return inventories[i].Namespace < inventories[j].Namespace
+		}
+		return inventories[i].CommonLabel < inventories[j].CommonLabel
+	})
+
+	return inventories
+}
+
+//Images returns image inventories of mapped resources in store.
+func (m *Mapper) Images() []ImageInventory {
+	return ImageInventories(getAllMappedResources(m.store))
+}
+
+//BuildImageInventory lists images of pod templates and pods of mapped resource and detects drift. A pod is expected
+//to run images of its ReplicaSet, or of Deployment of group if its ReplicaSet is not known.
+func BuildImageInventory(mappedResource MappedResource) ImageInventory {
+	inventory := ImageInventory{Namespace: mappedResource.Namespace, CommonLabel: mappedResource.CommonLabel}
+
+	for _, deployment := range mappedResource.Kube.Deployments {
+		inventory.Images = append(inventory.Images, containerImages("deployment", deployment.Name, deployment.Spec.Template.Spec, nil)...)
+	}
+	for _, replicaSet := range mappedResource.Kube.ReplicaSets {
+		inventory.Images = append(inventory.Images, containerImages("replicaset", replicaSet.Name, replicaSet.Spec.Template.Spec, nil)...)
+	}
+	for _, pod := range mappedResource.Kube.Pods {
+		statuses := append(append([]core_v1.ContainerStatus(nil), pod.Status.InitContainerStatuses...), pod.Status.ContainerStatuses...)
+		inventory.Images = append(inventory.Images, containerImages("pod", pod.Name, pod.Spec, statuses)...)
+	}
+
+	inventory.Drift = append(rolloutDrift(mappedResource), podDrift(mappedResource)...)
+	sort.SliceStable(inventory.Drift, func(i, j int) bool {
+		a, b := inventory.Drift[i], inventory.Drift[j]
+		if a.Kind != b.Kind {
+			return a.Kind > b.Kind
+		}
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		return a.Container < b.Container
+	})
+
+	return inventory
+}
+
+func containerImages(kind, name string, podSpec core_v1.PodSpec, statuses []core_v1.ContainerStatus) []ContainerImage {
+	digests := make(map[string]string)
+	for _, status := range statuses {
+		digests[status.Name] = imageIDDigest(status.ImageID)
+	}
+
+	var images []ContainerImage
+	add := func(container core_v1.Container, init bool) {
+		reference := parseImageReference(container.Image)
+		if digest := digests[container.Name]; digest != "" {
+			reference.Digest = digest
+		}
+
+		images = append(images, ContainerImage{
+			Kind:       kind,
+			Name:       name,
+			Container:  container.Name,
+			Init:       init,
+			Image:      container.Image,
+			Repository: reference.Repository,
+			Tag:        reference.Tag,
+			Digest:     reference.Digest,
+		})
+	}
+
+	for _, container := range podSpec.InitContainers {
+		add(container, true)
+	}
+	for _, container := range podSpec.Containers {
+		add(container, false)
+	}
+
+	return images
+}
+
+//rolloutDrift reports ReplicaSets with replicas whose images differ from their Deployment.
+func rolloutDrift(mappedResource MappedResource) []ImageDrift {
+	var drift []ImageDrift
+
+	for _, replicaSet := range mappedResource.Kube.ReplicaSets {
+		deployment, ok := findDeployment(mappedResource, controllerName(replicaSet.ObjectMeta, "Deployment"))
+		if !ok || !replicaSetHasReplicas(replicaSet) {
+			continue
+		}
+
+		expected := podSpecImagesByContainer(deployment.Spec.Template.Spec)
+		for container, image := range podSpecImagesByContainer(replicaSet.Spec.Template.Spec) {
+			if expectedImage, ok := expected[container]; ok && !sameImage(expectedImage, image) {
+				drift = append(drift, ImageDrift{
+					Reason:     ImageDriftRollout,
+					Kind:       "replicaset",
+					Name:       replicaSet.Name,
+					Deployment: deployment.Name,
+					Container:  container,
+					Expected:   expectedImage,
+					Actual:     image,
+				})
+			}
+		}
+	}
+
+	return drift
+}
+
+//podDrift reports pods running images other than their template and pods of a template running odd digests.
+func podDrift(mappedResource MappedResource) []ImageDrift {
+	var drift []ImageDrift
+
+	//Digests of pods by template and container when template does not pin a digest.
+	type templateContainer struct{ template, container string }
+	digests := make(map[templateContainer]map[string][]core_v1.Pod)
+	deploymentOf := make(map[string]string)
+
+	for _, pod := range mappedResource.Kube.Pods {
+		template, deploymentName, expected, ok := podTemplate(mappedResource, pod)
+		if !ok {
+			continue
+		}
+
+		running := make(map[string]string)
+		for _, status := range append(append([]core_v1.ContainerStatus(nil), pod.Status.InitContainerStatuses...), pod.Status.ContainerStatuses...) {
+			running[status.Name] = imageIDDigest(status.ImageID)
+		}
+
+		for container, image := range podSpecImagesByContainer(pod.Spec) {
+			expectedImage, ok := expected[container]
+			if !ok {
+				continue
+			}
+
+			podImageDrift := ImageDrift{Reason: ImageDriftTemplate, Kind: "pod", Name: pod.Name, Deployment: deploymentName, Container: container, Expected: expectedImage, Actual: image}
+			expectedDigest, digest := parseImageReference(expectedImage).Digest, running[container]
+			switch {
+			case !sameImage(expectedImage, image):
+				drift = append(drift, podImageDrift)
+			case expectedDigest != "" && digest != "" && digest != expectedDigest:
+				podImageDrift.Expected, podImageDrift.Actual = expectedDigest, digest
+				drift = append(drift, podImageDrift)
+			case expectedDigest == "" && digest != "":
+				key := templateContainer{template: template, container: container}
+				if digests[key] == nil {
+					digests[key] = make(map[string][]core_v1.Pod)
+				}
+				digests[key][digest] = append(digests[key][digest], pod)
+				deploymentOf[template] = deploymentName
+			}
+		}
+	}
+
+	for key, pods := range digests {
+		if len(pods) < 2 {
+			continue
+		}
+
+		majority := ""
+		for digest := range pods {
+			if majority == "" || len(pods[digest]) > len(pods[majority]) || (len(pods[digest]) == len(pods[majority]) && digest < majority) {
+				majority = digest
+			}
+		}
+
+		for digest, digestPods := range pods {
+			if digest == majority {
+				continue
+			}
+			for _, pod := range digestPods {
+				drift = append(drift, ImageDrift{
+					Reason:     ImageDriftDigest,
+					Kind:       "pod",
+					Name:       pod.Name,
+					Deployment: deploymentOf[key.template],
+					Container:  key.container,
+					Expected:   majority,
+					Actual:     digest,
+				})
+			}
+		}
+	}
+
+	return drift
+}
+
+//podTemplate returns name of template pod was created from, its Deployment and images it expects by container.
+func podTemplate(mappedResource MappedResource, pod core_v1.Pod) (string, string, map[string]string, bool) {
+	if replicaSetName := controllerName(pod.ObjectMeta, "ReplicaSet"); replicaSetName != "" {
+		for _, replicaSet := range mappedResource.Kube.ReplicaSets {
+			if replicaSet.Name == replicaSetName {
+				return "replicaset/" + replicaSet.Name, controllerName(replicaSet.ObjectMeta, "Deployment"), podSpecImagesByContainer(replicaSet.Spec.Template.Spec), true
+			}
+		}
+	}
+
+	//ReplicaSet is not known. Pod can only be compared when group has a single Deployment.
+	if len(mappedResource.Kube.Deployments) == 1 {
+		deployment := mappedResource.Kube.Deployments[0]
+		return "deployment/" + deployment.Name, deployment.Name, podSpecImagesByContainer(deployment.Spec.Template.Spec), true
+	}
+
+	return "", "", nil, false
+}
+
+func findDeployment(mappedResource MappedResource, name string) (apps_v1.Deployment, bool) {
+	for _, deployment := range mappedResource.Kube.Deployments {
+		if name != "" && deployment.Name == name {
+			return deployment, true
+		}
+	}
+
+	return apps_v1.Deployment{}, false
+}
+
+//controllerName returns name of controlling owner of given kind.
+func controllerName(objectMeta meta_v1.ObjectMeta, kind string) string {
+	for _, ownerReference := range objectMeta.OwnerReferences {
+		if ownerReference.Kind == kind && (ownerReference.Controller == nil || *ownerReference.Controller) {
+			return ownerReference.Name
+		}
+	}
+
+	return ""
+}
+
+func replicaSetHasReplicas(replicaSet apps_v1.ReplicaSet) bool {
+	return replicaSet.Status.Replicas > 0 || (replicaSet.Spec.Replicas != nil && *replicaSet.Spec.Replicas > 0)
+}
+
+func podSpecImagesByContainer(podSpec core_v1.PodSpec) map[string]string {
+	images := make(map[string]string)
+	for _, container := range append(append([]core_v1.Container(nil), podSpec.InitContainers...), podSpec.Containers...) {
+		images[container.Name] = container.Image
+	}
+
+	return images
+}
+
+//sameImage compares references after normalization e.g. 'nginx' and 'docker.io/library/nginx:latest' are same.
+//Digests are compared only if both references have one.
+func sameImage(a, b string) bool {
+	referenceA, referenceB := parseImageReference(a), parseImageReference(b)
+	if referenceA.Digest != "" && referenceB.Digest != "" {
+		return referenceA.Repository == referenceB.Repository && referenceA.Digest == referenceB.Digest
+	}
+
+	return referenceA.Repository == referenceB.Repository && referenceA.Tag == referenceB.Tag
+}
@@ -0,0 +1,117 @@
+package kubemap
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	core_v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestImageInventory(t *testing.T) {
+	resources := helperGetK8sResources()
+	resources.Pods[0].Spec.InitContainers = []core_v1.Container{{Name: "init", Image: "busybox"}}
+	resources.Pods[0].Status.ContainerStatuses = []core_v1.ContainerStatus{{Name: "kube-map", ImageID: "docker-pullable://some/random/image@sha256:aaaa"}}
+
+	mapper := NewMapper()
+	_, err := mapper.Map(resources)
+	assert.Nil(t, err)
+
+	inventories := mapper.Images()
+	assert.Len(t, inventories, 1)
+	assert.Equal(t, "test-namespace", inventories[0].Namespace)
+	assert.Empty(t, inventories[0].Drift)
+	assert.Equal(t, []ContainerImage{
+		{Kind: "deployment", Name: "kube-map", Container: "kube-map", Image: "some/random/image", Repository: "docker.io/some/random/image", Tag: "latest"},
+		{Kind: "replicaset", Name: "kube-map-644c5c58fc", Container: "kube-map", Image: "some/random/image", Repository: "docker.io/some/random/image", Tag: "latest"},
+		{Kind: "pod", Name: "kube-map-644c5c58fc-ggdmn", Container: "init", Init: true, Image: "busybox", Repository: "docker.io/library/busybox", Tag: "latest"},
+		{Kind: "pod", Name: "kube-map-644c5c58fc-ggdmn", Container: "kube-map", Image: "some/random/image", Repository: "docker.io/some/random/image", Tag: "latest", Digest: "sha256:aaaa"},
+	}, inventories[0].Images)
+}
+
+func TestImageDrift(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(resources *KubeResources)
+		drift  []ImageDrift
+	}{
+		{
+			name: "pod image changed in place",
+			modify: func(resources *KubeResources) {
+				resources.Pods[0].Spec.Containers[0].Image = "some/random/image:hotfix"
+			},
+			drift: []ImageDrift{{Reason: ImageDriftTemplate, Kind: "pod", Name: "kube-map-644c5c58fc-ggdmn", Deployment: "kube-map", Container: "kube-map", Expected: "some/random/image", Actual: "some/random/image:hotfix"}},
+		},
+		{
+			name: "normalized references are same",
+			modify: func(resources *KubeResources) {
+				resources.Pods[0].Spec.Containers[0].Image = "docker.io/some/random/image:latest"
+			},
+		},
+		{
+			name: "pod runs other digest than pinned by template",
+			modify: func(resources *KubeResources) {
+				resources.Deployments[0].Spec.Template.Spec.Containers[0].Image = "some/random/image@sha256:aaaa"
+				resources.ReplicaSets[0].Spec.Template.Spec.Containers[0].Image = "some/random/image@sha256:aaaa"
+				resources.Pods[0].Spec.Containers[0].Image = "some/random/image@sha256:aaaa"
+				resources.Pods[0].Status.ContainerStatuses = []core_v1.ContainerStatus{{Name: "kube-map", ImageID: "some/random/image@sha256:bbbb"}}
+			},
+			drift: []ImageDrift{{Reason: ImageDriftTemplate, Kind: "pod", Name: "kube-map-644c5c58fc-ggdmn", Deployment: "kube-map", Container: "kube-map", Expected: "sha256:aaaa", Actual: "sha256:bbbb"}},
+		},
+		{
+			name: "pods of a template run different digests",
+			modify: func(resources *KubeResources) {
+				for _, suffix := range []string{"a", "b"} {
+					pod := resources.Pods[0].DeepCopy()
+					pod.Name += suffix
+					pod.UID += types.UID("-" + suffix)
+					pod.Status.ContainerStatuses = []core_v1.ContainerStatus{{Name: "kube-map", ImageID: "some/random/image@sha256:aaaa"}}
+					resources.Pods = append(resources.Pods, *pod)
+				}
+				resources.Pods[0].Status.ContainerStatuses = []core_v1.ContainerStatus{{Name: "kube-map", ImageID: "some/random/image@sha256:bbbb"}}
+			},
+			drift: []ImageDrift{{Reason: ImageDriftDigest, Kind: "pod", Name: "kube-map-644c5c58fc-ggdmn", Deployment: "kube-map", Container: "kube-map", Expected: "sha256:aaaa", Actual: "sha256:bbbb"}},
+		},
+		{
+			name: "replica set mid rollout",
+			modify: func(resources *KubeResources) {
+				resources.Deployments[0].Spec.Template.Spec.Containers[0].Image = "some/random/image:v2"
+			},
+			drift: []ImageDrift{
+				{Reason: ImageDriftRollout, Kind: "replicaset", Name: "kube-map-644c5c58fc", Deployment: "kube-map", Container: "kube-map", Expected: "some/random/image:v2", Actual: "some/random/image"},
+			},
+		},
+		{
+			name: "scaled down replica set",
+			modify: func(resources *KubeResources) {
+				replicas := int32(0)
+				resources.Deployments[0].Spec.Template.Spec.Containers[0].Image = "some/random/image:v2"
+				resources.ReplicaSets[0].Spec.Replicas = &replicas
+				resources.ReplicaSets[0].Status.Replicas = 0
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resources := helperGetK8sResources()
+			tt.modify(&resources)
+
+			mappedResources, err := NewMapper().Map(resources)
+			assert.Nil(t, err)
+
+			inventories := ImageInventories(mappedResources)
+			assert.Len(t, inventories, 1)
+			assert.Equal(t, tt.drift, inventories[0].Drift)
+		})
+	}
+}
+
+func TestSameImage(t *testing.T) {
+	assert.True(t, sameImage("nginx", "docker.io/library/nginx:latest"))
+	assert.True(t, sameImage("nginx:1.19@sha256:aaaa", "nginx@sha256:aaaa"))
+	assert.False(t, sameImage("nginx:1.19", "nginx:1.20"))
+	assert.False(t, sameImage("nginx", "gcr.io/nginx"))
+	assert.False(t, sameImage("nginx@sha256:aaaa", "nginx@sha256:bbbb"))
+}
@@ -16,10 +16,13 @@ const maxRetries = 5
 func NewMapper() *Mapper {
 	store := cache.NewStore(metaResourceKeyFunc)
 	queue := workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter())
+	redactor, _ := newRedactor(RedactionOptions{})
 
 	return &Mapper{
-		store: store,
-		queue: queue,
+		store:    store,
+		queue:    queue,
+		redactor: redactor,
+		related:  newRelatedObjects(),
 	}
 }
 
@@ -33,9 +36,28 @@ func NewMapperWithOptions(options MapOptions) (*Mapper, error) {
 		return nil, zapErr
 	}
 
+	projection, projectionErr := newProjection(options.Projection)
+	if projectionErr != nil {
+		return nil, projectionErr
+	}
+
+	redactor, redactorErr := newRedactor(options.Redaction)
+	if redactorErr != nil {
+		return nil, redactorErr
+	}
+
+	related := newRelatedObjects()
+	if relatedErr := related.addCustomResourceRules(options.CustomResources); relatedErr != nil {
+		return nil, relatedErr
+	}
+
 	return &Mapper{
-		store: store,
-		queue: queue,
+		store:      store,
+		queue:      queue,
+		options:    options,
+		projection: projection,
+		redactor:   redactor,
+		related:    related,
 		log: Logger{
 			enabled: options.Logging.Enabled,
 			logger:  zapLogger,
@@ -45,8 +67,12 @@ func NewMapperWithOptions(options MapOptions) (*Mapper, error) {
 
 //NewStoreMapper created a mapper that works with existing store.
 func NewStoreMapper(store cache.Store) *Mapper {
+	redactor, _ := newRedactor(RedactionOptions{})
+
 	return &Mapper{
-		store: store,
+		store:    store,
+		redactor: redactor,
+		related:  newRelatedObjectsFromStore(store),
 	}
 }
 
@@ -57,8 +83,28 @@ func NewStoreMapperWithOptions(store cache.Store, options MapOptions) (*Mapper,
 		return nil, zapErr
 	}
 
+	projection, projectionErr := newProjection(options.Projection)
+	if projectionErr != nil {
+		return nil, projectionErr
+	}
+
+	redactor, redactorErr := newRedactor(options.Redaction)
+	if redactorErr != nil {
+		return nil, redactorErr
+	}
+
+	related := newRelatedObjects()
+	if relatedErr := related.addCustomResourceRules(options.CustomResources); relatedErr != nil {
+		return nil, relatedErr
+	}
+	related.addStore(store)
+
 	return &Mapper{
-		store: store,
+		store:      store,
+		options:    options,
+		projection: projection,
+		redactor:   redactor,
+		related:    related,
 		log: Logger{
 			enabled: options.Logging.Enabled,
 			logger:  zapLogger,
@@ -73,7 +119,7 @@ func (m *Mapper) StoreMap(obj interface{}) ([]MapResult, error) {
 		return []MapResult{}, err
 	}
 
-	return mapResults, nil
+	return m.redactor.redactMapResults(mapResults), nil
 }
 
 //StoreMapObj gets a resources and maps it with exiting resources in store
@@ -84,7 +130,7 @@ func (m *Mapper) StoreMapObj(obj interface{}) ([]MapResult, error) {
 		return []MapResult{}, err
 	}
 
-	return mapResults, nil
+	return m.redactor.redactMapResults(mapResults), nil
 }
 
 //Map accepts collection different k8s resources.
@@ -94,7 +140,9 @@ func (m *Mapper) Map(resources KubeResources) (MappedResources, error) {
 
 	mappedResources := m.runMap(m.queue, m.store)
 
-	return mappedResources, nil
+	mappedResources = GroupByLabels(mappedResources, m.options.Grouping.LabelKeys)
+
+	return m.redactor.redactMappedResources(mappedResources), nil
 }
 
 //RunMap starts mapper controller
@@ -163,30 +211,133 @@ func getAllMappedResources(store cache.Store) MappedResources {
 }
 
 func addResourcesForMapping(resources KubeResources, queue workqueue.RateLimitingInterface) {
+	for _, event := range resourceEventsForMapping(resources) {
+		queue.Add(event)
+	}
+}
+
+//resourceEventsForMapping returns an 'ADDED' event for every object of resources in order they are mapped.
+func resourceEventsForMapping(resources KubeResources) []ResourceEvent {
+	var events []ResourceEvent
+
 	//Add ingresses
 	for _, ingress := range resources.Ingresses {
-		queue.Add(gerResourceEvent(ingress.DeepCopy(), "ingress"))
+		events = append(events, gerResourceEvent(ingress.DeepCopy(), "ingress"))
 	}
 
 	//Add services
 	for _, service := range resources.Services {
-		queue.Add(gerResourceEvent(service.DeepCopy(), "service"))
+		events = append(events, gerResourceEvent(service.DeepCopy(), "service"))
 	}
 
 	//Add deployments
 	for _, deployment := range resources.Deployments {
-		queue.Add(gerResourceEvent(deployment.DeepCopy(), "deployment"))
+		events = append(events, gerResourceEvent(deployment.DeepCopy(), "deployment"))
 	}
 
 	//Add replica sets
 	for _, replicaSet := range resources.ReplicaSets {
-		queue.Add(gerResourceEvent(replicaSet.DeepCopy(), "replicaset"))
+		events = append(events, gerResourceEvent(replicaSet.DeepCopy(), "replicaset"))
 	}
 
 	//Add pods
 	for _, pod := range resources.Pods {
-		queue.Add(gerResourceEvent(pod.DeepCopy(), "pod"))
+		events = append(events, gerResourceEvent(pod.DeepCopy(), "pod"))
+	}
+
+	//Add horizontal pod autoscalers
+	for _, hpa := range resources.HorizontalPodAutoscalers {
+		events = append(events, gerResourceEvent(hpa.DeepCopy(), "hpa"))
+	}
+
+	for _, hpa := range resources.HorizontalPodAutoscalersV1 {
+		events = append(events, gerResourceEvent(hpa.DeepCopy(), "hpa"))
+	}
+
+	//Add objects referenced by pod specs
+	for _, configMap := range resources.ConfigMaps {
+		events = append(events, gerResourceEvent(configMap.DeepCopy(), "configmap"))
 	}
+
+	for _, secret := range resources.Secrets {
+		events = append(events, gerResourceEvent(secret.DeepCopy(), "secret"))
+	}
+
+	for _, pvc := range resources.PersistentVolumeClaims {
+		events = append(events, gerResourceEvent(pvc.DeepCopy(), "pvc"))
+	}
+
+	for _, pv := range resources.PersistentVolumes {
+		events = append(events, gerResourceEvent(pv.DeepCopy(), "pv"))
+	}
+
+	//Add endpoints of services
+	for _, endpoints := range resources.Endpoints {
+		events = append(events, gerResourceEvent(endpoints.DeepCopy(), "endpoints"))
+	}
+
+	for _, endpointSlice := range resources.EndpointSlices {
+		events = append(events, gerResourceEvent(endpointSlice.DeepCopy(), "endpointslice"))
+	}
+
+	//Add policies governing pods
+	for _, pdb := range resources.PodDisruptionBudgets {
+		events = append(events, gerResourceEvent(pdb.DeepCopy(), "pdb"))
+	}
+
+	for _, networkPolicy := range resources.NetworkPolicies {
+		events = append(events, gerResourceEvent(networkPolicy.DeepCopy(), "networkpolicy"))
+	}
+
+	//Add service accounts and RBAC objects granting them permissions
+	for _, serviceAccount := range resources.ServiceAccounts {
+		events = append(events, gerResourceEvent(serviceAccount.DeepCopy(), "serviceaccount"))
+	}
+
+	for _, role := range resources.Roles {
+		events = append(events, gerResourceEvent(role.DeepCopy(), "role"))
+	}
+
+	for _, roleBinding := range resources.RoleBindings {
+		events = append(events, gerResourceEvent(roleBinding.DeepCopy(), "rolebinding"))
+	}
+
+	for _, clusterRole := range resources.ClusterRoles {
+		events = append(events, gerResourceEvent(clusterRole.DeepCopy(), "clusterrole"))
+	}
+
+	for _, clusterRoleBinding := range resources.ClusterRoleBindings {
+		events = append(events, gerResourceEvent(clusterRoleBinding.DeepCopy(), "clusterrolebinding"))
+	}
+
+	//Add Gateway API objects
+	for _, gateway := range resources.Gateways {
+		events = append(events, gerResourceEvent(gateway.DeepCopy(), "gateway"))
+	}
+
+	for _, httpRoute := range resources.HTTPRoutes {
+		events = append(events, gerResourceEvent(httpRoute.DeepCopy(), "httproute"))
+	}
+
+	for _, grpcRoute := range resources.GRPCRoutes {
+		events = append(events, gerResourceEvent(grpcRoute.DeepCopy(), "grpcroute"))
+	}
+
+	for _, referenceGrant := range resources.ReferenceGrants {
+		events = append(events, gerResourceEvent(referenceGrant.DeepCopy(), "referencegrant"))
+	}
+
+	//Add nodes running pods
+	for _, node := range resources.Nodes {
+		events = append(events, gerResourceEvent(node.DeepCopy(), "node"))
+	}
+
+	//Add custom resources. Those without a rule cannot be mapped.
+	for _, customResource := range resources.CustomResources {
+		events = append(events, gerResourceEvent(customResource.DeepCopy(), customResourceTypeOf(&customResource)))
+	}
+
+	return events
 }
 
 func gerResourceEvent(obj interface{}, resourceType string) ResourceEvent {
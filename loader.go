@@ -0,0 +1,244 @@
+package kubemap
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"regexp"
+	"strings"
+
+	apps_v1 "k8s.io/api/apps/v1"
+	autoscaling_v1 "k8s.io/api/autoscaling/v1"
+	autoscaling_v2beta2 "k8s.io/api/autoscaling/v2beta2"
+	core_v1 "k8s.io/api/core/v1"
+	network_v1 "k8s.io/api/networking/v1"
+	network_v1beta1 "k8s.io/api/networking/v1beta1"
+	policy_v1beta1 "k8s.io/api/policy/v1beta1"
+	rbac_v1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/yaml"
+)
+
+//yamlDocumentSeparator splits multi document YAML.
+var yamlDocumentSeparator = regexp.MustCompile(`(?m)^---\s*$`)
+
+//LoadKubeResources reads k8s objects from JSON or YAML e.g. output of 'kubectl get -o yaml'.
+//Input may be a single object, a List or multiple YAML documents. Objects of groups which are not built-in
+//are read as custom resources. Other built-in kinds e.g. Namespace are skipped.
+func LoadKubeResources(r io.Reader) (KubeResources, error) {
+	var resources KubeResources
+
+	content, err := ioutil.ReadAll(r)
+	if err != nil {
+		return resources, fmt.Errorf("Cannot read k8s resources - %v", err)
+	}
+
+	for i, document := range yamlDocumentSeparator.Split(string(content), -1) {
+		if strings.TrimSpace(document) == "" {
+			continue
+		}
+
+		documentJSON, err := yaml.YAMLToJSON([]byte(document))
+		if err != nil {
+			return resources, fmt.Errorf("Cannot parse document %d - %v", i+1, err)
+		}
+
+		object := make(map[string]interface{})
+		if err := json.Unmarshal(documentJSON, &object); err != nil {
+			return resources, fmt.Errorf("Cannot parse document %d - %v", i+1, err)
+		}
+		if len(object) == 0 {
+			continue
+		}
+
+		if err := addLoadedObject(&resources, &unstructured.Unstructured{Object: object}); err != nil {
+			return resources, err
+		}
+	}
+
+	return resources, nil
+}
+
+//addLoadedObject adds object, or items of a List, to resources.
+func addLoadedObject(resources *KubeResources, object *unstructured.Unstructured) error {
+	if object.IsList() {
+		return object.EachListItem(func(item runtime.Object) error {
+			return addLoadedObject(resources, item.(*unstructured.Unstructured))
+		})
+	}
+
+	gvk := object.GroupVersionKind()
+	if gvk.Kind == "" {
+		return fmt.Errorf("Object %s has no kind", object.GetName())
+	}
+
+	var err error
+	switch gvk.Group + "/" + gvk.Kind {
+	case "networking.k8s.io/Ingress", "extensions/Ingress":
+		var ingress network_v1beta1.Ingress
+		if gvk.Version == "v1" {
+			convertIngressV1(object.Object)
+		}
+		err = fromUnstructured(object, &ingress)
+		resources.Ingresses = append(resources.Ingresses, ingress)
+	case "/Service":
+		var service core_v1.Service
+		err = fromUnstructured(object, &service)
+		resources.Services = append(resources.Services, service)
+	case "apps/Deployment":
+		var deployment apps_v1.Deployment
+		err = fromUnstructured(object, &deployment)
+		resources.Deployments = append(resources.Deployments, deployment)
+	case "apps/ReplicaSet":
+		var replicaSet apps_v1.ReplicaSet
+		err = fromUnstructured(object, &replicaSet)
+		resources.ReplicaSets = append(resources.ReplicaSets, replicaSet)
+	case "/Pod":
+		var pod core_v1.Pod
+		err = fromUnstructured(object, &pod)
+		resources.Pods = append(resources.Pods, pod)
+	case "autoscaling/HorizontalPodAutoscaler":
+		switch gvk.Version {
+		case "v1":
+			var hpa autoscaling_v1.HorizontalPodAutoscaler
+			err = fromUnstructured(object, &hpa)
+			resources.HorizontalPodAutoscalersV1 = append(resources.HorizontalPodAutoscalersV1, hpa)
+		case "v2", "v2beta2":
+			//autoscaling/v2 has same schema as autoscaling/v2beta2 it graduated from.
+			var hpa autoscaling_v2beta2.HorizontalPodAutoscaler
+			err = fromUnstructured(object, &hpa)
+			resources.HorizontalPodAutoscalers = append(resources.HorizontalPodAutoscalers, hpa)
+		default:
+			err = fmt.Errorf("autoscaling/%s is not supported, only autoscaling/v2, autoscaling/v2beta2 and autoscaling/v1 are", gvk.Version)
+		}
+	case "/ConfigMap":
+		var configMap core_v1.ConfigMap
+		err = fromUnstructured(object, &configMap)
+		resources.ConfigMaps = append(resources.ConfigMaps, configMap)
+	case "/Secret":
+		var secret core_v1.Secret
+		err = fromUnstructured(object, &secret)
+		resources.Secrets = append(resources.Secrets, secret)
+	case "/PersistentVolumeClaim":
+		var pvc core_v1.PersistentVolumeClaim
+		err = fromUnstructured(object, &pvc)
+		resources.PersistentVolumeClaims = append(resources.PersistentVolumeClaims, pvc)
+	case "/PersistentVolume":
+		var pv core_v1.PersistentVolume
+		err = fromUnstructured(object, &pv)
+		resources.PersistentVolumes = append(resources.PersistentVolumes, pv)
+	case "/Node":
+		var node core_v1.Node
+		err = fromUnstructured(object, &node)
+		resources.Nodes = append(resources.Nodes, node)
+	case "/Endpoints":
+		var endpoints core_v1.Endpoints
+		err = fromUnstructured(object, &endpoints)
+		resources.Endpoints = append(resources.Endpoints, endpoints)
+	case "discovery.k8s.io/EndpointSlice":
+		resources.EndpointSlices = append(resources.EndpointSlices, *object)
+	case "policy/PodDisruptionBudget":
+		//policy/v1 is decoded into policy/v1beta1 type. API version is kept, as empty selector selects every pod only
+		//in policy/v1.
+		var pdb policy_v1beta1.PodDisruptionBudget
+		err = fromUnstructured(object, &pdb)
+		resources.PodDisruptionBudgets = append(resources.PodDisruptionBudgets, pdb)
+	case "networking.k8s.io/NetworkPolicy":
+		var networkPolicy network_v1.NetworkPolicy
+		err = fromUnstructured(object, &networkPolicy)
+		resources.NetworkPolicies = append(resources.NetworkPolicies, networkPolicy)
+	case "/ServiceAccount":
+		var serviceAccount core_v1.ServiceAccount
+		err = fromUnstructured(object, &serviceAccount)
+		resources.ServiceAccounts = append(resources.ServiceAccounts, serviceAccount)
+	case "rbac.authorization.k8s.io/Role":
+		var role rbac_v1.Role
+		err = fromUnstructured(object, &role)
+		resources.Roles = append(resources.Roles, role)
+	case "rbac.authorization.k8s.io/RoleBinding":
+		var roleBinding rbac_v1.RoleBinding
+		err = fromUnstructured(object, &roleBinding)
+		resources.RoleBindings = append(resources.RoleBindings, roleBinding)
+	case "rbac.authorization.k8s.io/ClusterRole":
+		var clusterRole rbac_v1.ClusterRole
+		err = fromUnstructured(object, &clusterRole)
+		resources.ClusterRoles = append(resources.ClusterRoles, clusterRole)
+	case "rbac.authorization.k8s.io/ClusterRoleBinding":
+		var clusterRoleBinding rbac_v1.ClusterRoleBinding
+		err = fromUnstructured(object, &clusterRoleBinding)
+		resources.ClusterRoleBindings = append(resources.ClusterRoleBindings, clusterRoleBinding)
+	case GatewayAPIGroup + "/Gateway":
+		resources.Gateways = append(resources.Gateways, *object)
+	case GatewayAPIGroup + "/HTTPRoute":
+		resources.HTTPRoutes = append(resources.HTTPRoutes, *object)
+	case GatewayAPIGroup + "/GRPCRoute":
+		resources.GRPCRoutes = append(resources.GRPCRoutes, *object)
+	case GatewayAPIGroup + "/ReferenceGrant":
+		resources.ReferenceGrants = append(resources.ReferenceGrants, *object)
+	default:
+		//Groups of custom resource definitions always contain a dot.
+		if strings.Contains(gvk.Group, ".") {
+			resources.CustomResources = append(resources.CustomResources, *object)
+		}
+	}
+
+	if err != nil {
+		return fmt.Errorf("Cannot read %s %s/%s - %v", gvk.Kind, object.GetNamespace(), object.GetName(), err)
+	}
+
+	return nil
+}
+
+func fromUnstructured(object *unstructured.Unstructured, out interface{}) error {
+	return runtime.DefaultUnstructuredConverter.FromUnstructured(object.Object, out)
+}
+
+//convertIngressV1 rewrites backends of networking.k8s.io/v1 Ingress into their v1beta1 form in place.
+func convertIngressV1(content map[string]interface{}) {
+	spec, ok := content["spec"].(map[string]interface{})
+	if !ok {
+		return
+	}
+
+	if defaultBackend, ok := spec["defaultBackend"].(map[string]interface{}); ok {
+		spec["backend"] = ingressBackendV1beta1(defaultBackend)
+		delete(spec, "defaultBackend")
+	}
+
+	rules, _ := spec["rules"].([]interface{})
+	for _, item := range rules {
+		rule, _ := item.(map[string]interface{})
+		http, _ := rule["http"].(map[string]interface{})
+		paths, _ := http["paths"].([]interface{})
+		for _, pathItem := range paths {
+			path, ok := pathItem.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			if backend, ok := path["backend"].(map[string]interface{}); ok {
+				path["backend"] = ingressBackendV1beta1(backend)
+			}
+			delete(path, "pathType")
+		}
+	}
+}
+
+func ingressBackendV1beta1(backend map[string]interface{}) map[string]interface{} {
+	service, ok := backend["service"].(map[string]interface{})
+	if !ok {
+		return backend
+	}
+
+	converted := map[string]interface{}{"serviceName": service["name"]}
+	if port, ok := service["port"].(map[string]interface{}); ok {
+		if number, ok := port["number"]; ok {
+			converted["servicePort"] = number
+		} else if name, ok := port["name"]; ok {
+			converted["servicePort"] = name
+		}
+	}
+
+	return converted
+}
@@ -0,0 +1,177 @@
+package kubemap
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+func TestLoadKubeResources(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		check   func(t *testing.T, resources KubeResources)
+	}{
+		{
+			name:    "JSON fixture",
+			content: string(helperGetFileContent("service.json")),
+			check: func(t *testing.T, resources KubeResources) {
+				assert.Len(t, resources.Services, 1)
+				assert.Equal(t, "kube-map", resources.Services[0].Name)
+				assert.Equal(t, int32(8085), resources.Services[0].Spec.Ports[0].Port)
+			},
+		},
+		{
+			name: "multiple documents",
+			content: `
+apiVersion: v1
+kind: ConfigMap
+metadata: {name: config, namespace: shop}
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata: {name: api, namespace: shop}
+spec:
+  replicas: 2
+---
+apiVersion: v1
+kind: Namespace
+metadata: {name: shop}
+`,
+			check: func(t *testing.T, resources KubeResources) {
+				assert.Len(t, resources.ConfigMaps, 1)
+				assert.Len(t, resources.Deployments, 1)
+				assert.Equal(t, int32(2), *resources.Deployments[0].Spec.Replicas)
+			},
+		},
+		{
+			name: "list",
+			content: `
+apiVersion: v1
+kind: List
+items:
+- apiVersion: v1
+  kind: Pod
+  metadata: {name: api, namespace: shop}
+- apiVersion: gateway.networking.k8s.io/v1
+  kind: HTTPRoute
+  metadata: {name: web, namespace: shop}
+- apiVersion: argoproj.io/v1alpha1
+  kind: Rollout
+  metadata: {name: web, namespace: shop}
+`,
+			check: func(t *testing.T, resources KubeResources) {
+				assert.Len(t, resources.Pods, 1)
+				assert.Len(t, resources.HTTPRoutes, 1)
+				assert.Len(t, resources.CustomResources, 1)
+				assert.Equal(t, "rollout.argoproj.io", customResourceTypeOf(&resources.CustomResources[0]))
+			},
+		},
+		{
+			name: "ingress v1",
+			content: `
+apiVersion: networking.k8s.io/v1
+kind: Ingress
+metadata: {name: shop, namespace: shop}
+spec:
+  defaultBackend:
+    service: {name: web, port: {number: 80}}
+  rules:
+  - host: shop.example.com
+    http:
+      paths:
+      - path: /api
+        pathType: Prefix
+        backend:
+          service: {name: api, port: {name: http}}
+`,
+			check: func(t *testing.T, resources KubeResources) {
+				assert.Len(t, resources.Ingresses, 1)
+				spec := resources.Ingresses[0].Spec
+				assert.Equal(t, "web", spec.Backend.ServiceName)
+				assert.Equal(t, intstr.FromInt(80), spec.Backend.ServicePort)
+				assert.Equal(t, "api", spec.Rules[0].HTTP.Paths[0].Backend.ServiceName)
+				assert.Equal(t, intstr.FromString("http"), spec.Rules[0].HTTP.Paths[0].Backend.ServicePort)
+			},
+		},
+		{
+			name: "autoscaler v2",
+			content: `
+apiVersion: autoscaling/v2
+kind: HorizontalPodAutoscaler
+metadata: {name: api, namespace: shop}
+spec:
+  scaleTargetRef: {apiVersion: apps/v1, kind: Deployment, name: api}
+  maxReplicas: 5
+  metrics:
+  - type: Resource
+    resource:
+      name: cpu
+      target: {type: Utilization, averageUtilization: 70}
+`,
+			check: func(t *testing.T, resources KubeResources) {
+				assert.Len(t, resources.HorizontalPodAutoscalers, 1)
+				spec := resources.HorizontalPodAutoscalers[0].Spec
+				assert.Equal(t, "api", spec.ScaleTargetRef.Name)
+				assert.Equal(t, int32(70), *spec.Metrics[0].Resource.Target.AverageUtilization)
+			},
+		},
+		{
+			name: "pod disruption budget v1 with empty selector",
+			content: `
+apiVersion: v1
+kind: Service
+metadata: {name: api, namespace: shop}
+spec:
+  selector: {app: api}
+---
+apiVersion: v1
+kind: Pod
+metadata: {name: api-0, namespace: shop, labels: {app: api}}
+---
+apiVersion: policy/v1
+kind: PodDisruptionBudget
+metadata: {name: all, namespace: shop}
+spec:
+  maxUnavailable: 1
+  selector: {}
+`,
+			check: func(t *testing.T, resources KubeResources) {
+				assert.Len(t, resources.PodDisruptionBudgets, 1)
+				assert.Equal(t, "policy/v1", resources.PodDisruptionBudgets[0].APIVersion)
+
+				mappedResources, err := NewMapper().Map(resources)
+				assert.Nil(t, err)
+				assert.Len(t, mappedResources.MappedResource, 1)
+				coverage := CoverageOfPolicies(mappedResources.MappedResource[0])
+				assert.Equal(t, []string{"all"}, coverage.PodDisruptionBudgets)
+				assert.NotContains(t, coverage.Findings, FindingNoPodDisruptionBudget)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resources, err := LoadKubeResources(strings.NewReader(tt.content))
+			assert.Nil(t, err)
+			tt.check(t, resources)
+		})
+	}
+}
+
+func TestLoadKubeResourcesErrors(t *testing.T) {
+	tests := []string{
+		"kind: [",
+		"apiVersion: v1\nmetadata: {name: nameless}",
+		"apiVersion: v1\nkind: Service\nspec: {ports: 80}",
+		"apiVersion: autoscaling/v2beta1\nkind: HorizontalPodAutoscaler\nmetadata: {name: api}",
+	}
+
+	for _, content := range tests {
+		_, err := LoadKubeResources(strings.NewReader(content))
+		assert.NotNil(t, err, content)
+	}
+}
@@ -10,25 +10,25 @@ import (
 
 func (m *Mapper) debug(msg string) {
 	if m.log.enabled {
-		m.log.logger.Debug(msg)
+		m.log.logger.Debug(m.redactor.redactString(msg))
 	}
 }
 
 func (m *Mapper) info(msg string) {
 	if m.log.enabled {
-		m.log.logger.Info(msg)
+		m.log.logger.Info(m.redactor.redactString(msg))
 	}
 }
 
 func (m *Mapper) warn(msg string) {
 	if m.log.enabled {
-		m.log.logger.Warn(msg)
+		m.log.logger.Warn(m.redactor.redactString(msg))
 	}
 }
 
 func (m *Mapper) error(msg string) {
 	if m.log.enabled {
-		m.log.logger.Error(msg)
+		m.log.logger.Error(m.redactor.redactString(msg))
 	}
 }
 
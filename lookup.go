@@ -0,0 +1,393 @@
+package kubemap
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	core_v1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+const (
+	lookupIP    = "ip"
+	lookupHost  = "host"
+	lookupImage = "image"
+	lookupNode  = "node"
+)
+
+//defaultImageRegistry is registry of image references which do not name one, as resolved by docker.
+const defaultImageRegistry = "docker.io"
+
+//lookupIndex maps IPs, hosts, images and nodes to store keys of mapped resources containing them.
+//It is built from store on first lookup and kept up to date as mapper changes store.
+type lookupIndex struct {
+	mutex   sync.RWMutex
+	built   bool
+	entries map[string]map[string]map[string]bool
+}
+
+//LookupIP returns mapped resources having a pod, service or endpoint with given IP.
+func (m *Mapper) LookupIP(ip string) []MappedResource {
+	return m.lookup(lookupIP, strings.TrimSpace(ip))
+}
+
+//LookupHost returns mapped resources serving given host through an ingress, HTTPRoute or load balancer.
+//Wildcard hosts of ingresses match and in cluster DNS names of services e.g. 'api.shop.svc.cluster.local'
+//resolve to group of service.
+func (m *Mapper) LookupHost(host string) []MappedResource {
+	host = normalizeHost(host)
+	if host == "" {
+		return nil
+	}
+
+	mappedResources := m.lookup(lookupHost, host)
+	if parts := strings.SplitN(host, ".", 2); len(parts) == 2 {
+		mappedResources = append(mappedResources, m.lookup(lookupHost, "*."+parts[1])...)
+	}
+
+	return uniqueMappedResources(mappedResources)
+}
+
+//LookupImage returns mapped resources running given image. Image may be a repository e.g. 'nginx',
+//a tagged reference e.g. 'nginx:1.19', a digest e.g. 'sha256:...' or a reference with digest.
+//Repositories are normalized as docker does, so 'nginx' matches 'docker.io/library/nginx'.
+func (m *Mapper) LookupImage(image string) []MappedResource {
+	image = strings.TrimSpace(image)
+	if image == "" {
+		return nil
+	}
+
+	if strings.HasPrefix(image, "sha256:") {
+		return m.lookup(lookupImage, "digest:"+image)
+	}
+
+	reference := parseImageReference(image)
+	switch {
+	case reference.Digest != "":
+		return m.lookup(lookupImage, "digest:"+reference.Digest)
+	case reference.Tag != "" && strings.HasSuffix(image, ":"+reference.Tag):
+		return m.lookup(lookupImage, "tag:"+reference.Repository+":"+reference.Tag)
+	default:
+		return m.lookup(lookupImage, "repository:"+reference.Repository)
+	}
+}
+
+//LookupNode returns mapped resources having pods scheduled on given node.
+func (m *Mapper) LookupNode(node string) []MappedResource {
+	return m.lookup(lookupNode, strings.TrimSpace(node))
+}
+
+func (m *Mapper) lookup(field, value string) []MappedResource {
+	if value == "" {
+		return nil
+	}
+
+	keys := m.index.keys(m.store, field, value)
+
+	var mappedResources []MappedResource
+	for _, key := range keys {
+		mappedResource, err := getObjectFromStore(key, m.store)
+		if err != nil {
+			m.warn(fmt.Sprintf("Cannot lookup %s %s - %v", field, value, err))
+			continue
+		}
+		mappedResources = append(mappedResources, m.redactor.redactMappedResource(mappedResource))
+	}
+
+	sortMappedResources(mappedResources)
+	return mappedResources
+}
+
+//keys returns sorted store keys of mapped resources having value of field. Index is built from store if needed.
+func (i *lookupIndex) keys(store cache.Store, field, value string) []string {
+	i.mutex.RLock()
+	built := i.built
+	i.mutex.RUnlock()
+
+	if !built {
+		i.mutex.Lock()
+		if !i.built {
+			for _, item := range store.List() {
+				if mappedResource, ok := item.(MappedResource); ok {
+					i.addLocked(mappedResource)
+				}
+			}
+			i.built = true
+		}
+		i.mutex.Unlock()
+	}
+
+	i.mutex.RLock()
+	defer i.mutex.RUnlock()
+
+	var keys []string
+	for key := range i.entries[field][value] {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+//add indexes mapped resource added to store. Nothing is done until index is built.
+func (i *lookupIndex) add(mappedResource MappedResource) {
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+
+	if i.built {
+		i.addLocked(mappedResource)
+	}
+}
+
+func (i *lookupIndex) addLocked(mappedResource MappedResource) {
+	key, err := metaResourceKeyFunc(mappedResource)
+	if err != nil {
+		return
+	}
+
+	if i.entries == nil {
+		i.entries = make(map[string]map[string]map[string]bool)
+	}
+
+	for field, values := range lookupValues(mappedResource) {
+		if i.entries[field] == nil {
+			i.entries[field] = make(map[string]map[string]bool)
+		}
+		for _, value := range values {
+			if i.entries[field][value] == nil {
+				i.entries[field][value] = make(map[string]bool)
+			}
+			i.entries[field][value][key] = true
+		}
+	}
+}
+
+//remove removes mapped resource deleted from store from index.
+func (i *lookupIndex) remove(mappedResource MappedResource) {
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+
+	if !i.built {
+		return
+	}
+
+	key, err := metaResourceKeyFunc(mappedResource)
+	if err != nil {
+		return
+	}
+
+	for field, values := range lookupValues(mappedResource) {
+		for _, value := range values {
+			delete(i.entries[field][value], key)
+			if len(i.entries[field][value]) == 0 {
+				delete(i.entries[field], value)
+			}
+		}
+	}
+}
+
+//lookupValues returns values of every lookup field found in mapped resource.
+func lookupValues(mappedResource MappedResource) map[string][]string {
+	values := make(map[string][]string)
+	add := func(field string, value string) {
+		if value != "" && value != "None" {
+			values[field] = append(values[field], value)
+		}
+	}
+
+	for _, ingress := range mappedResource.Kube.Ingresses {
+		for _, rule := range ingress.Spec.Rules {
+			add(lookupHost, normalizeHost(rule.Host))
+		}
+		for _, tls := range ingress.Spec.TLS {
+			for _, host := range tls.Hosts {
+				add(lookupHost, normalizeHost(host))
+			}
+		}
+		for _, loadBalancer := range ingress.Status.LoadBalancer.Ingress {
+			add(lookupIP, loadBalancer.IP)
+			add(lookupHost, normalizeHost(loadBalancer.Hostname))
+		}
+	}
+
+	for _, route := range mappedResource.Kube.HTTPRoutes {
+		for _, host := range route.Spec.Hostnames {
+			add(lookupHost, normalizeHost(host))
+		}
+	}
+
+	for _, service := range mappedResource.Kube.Services {
+		add(lookupHost, service.Name+"."+service.Namespace+".svc")
+		add(lookupIP, service.Spec.ClusterIP)
+		add(lookupIP, service.Spec.LoadBalancerIP)
+		for _, ip := range service.Spec.ExternalIPs {
+			add(lookupIP, ip)
+		}
+		for _, loadBalancer := range service.Status.LoadBalancer.Ingress {
+			add(lookupIP, loadBalancer.IP)
+			add(lookupHost, normalizeHost(loadBalancer.Hostname))
+		}
+	}
+
+	for _, endpoint := range mappedResource.Kube.ServiceEndpoints {
+		add(lookupIP, endpoint.Address)
+	}
+
+	for _, deployment := range mappedResource.Kube.Deployments {
+		for _, image := range podSpecImages(deployment.Spec.Template.Spec.Containers, deployment.Spec.Template.Spec.InitContainers) {
+			values[lookupImage] = append(values[lookupImage], imageLookupValues(image)...)
+		}
+	}
+
+	for _, replicaSet := range mappedResource.Kube.ReplicaSets {
+		for _, image := range podSpecImages(replicaSet.Spec.Template.Spec.Containers, replicaSet.Spec.Template.Spec.InitContainers) {
+			values[lookupImage] = append(values[lookupImage], imageLookupValues(image)...)
+		}
+	}
+
+	for _, pod := range mappedResource.Kube.Pods {
+		add(lookupIP, pod.Status.PodIP)
+		add(lookupNode, pod.Spec.NodeName)
+		for _, image := range podSpecImages(pod.Spec.Containers, pod.Spec.InitContainers) {
+			values[lookupImage] = append(values[lookupImage], imageLookupValues(image)...)
+		}
+		for _, status := range append(pod.Status.InitContainerStatuses, pod.Status.ContainerStatuses...) {
+			if !strings.HasPrefix(status.Image, "sha256:") {
+				values[lookupImage] = append(values[lookupImage], imageLookupValues(status.Image)...)
+			}
+			if digest := imageIDDigest(status.ImageID); digest != "" {
+				add(lookupImage, "digest:"+digest)
+			}
+		}
+	}
+
+	for field := range values {
+		values[field] = removeDuplicateStrings(values[field])
+	}
+
+	return values
+}
+
+func podSpecImages(containers ...[]core_v1.Container) []string {
+	var images []string
+	for _, list := range containers {
+		for _, container := range list {
+			images = append(images, container.Image)
+		}
+	}
+
+	return images
+}
+
+//imageLookupValues returns index values of repository, tagged reference and digest of image.
+func imageLookupValues(image string) []string {
+	if image == "" {
+		return nil
+	}
+
+	reference := parseImageReference(image)
+	values := []string{"repository:" + reference.Repository}
+	if reference.Tag != "" {
+		values = append(values, "tag:"+reference.Repository+":"+reference.Tag)
+	}
+	if reference.Digest != "" {
+		values = append(values, "digest:"+reference.Digest)
+	}
+
+	return values
+}
+
+//imageReference is a container image reference split into its parts.
+type imageReference struct {
+	//Repository is normalized as docker does e.g. 'docker.io/library/nginx' for 'nginx'.
+	Repository string
+	//Tag is 'latest' if reference has neither tag nor digest.
+	Tag    string
+	Digest string
+}
+
+//parseImageReference splits image e.g. 'registry:5000/team/app:1.0@sha256:...' into repository, tag and digest.
+func parseImageReference(image string) imageReference {
+	var reference imageReference
+
+	name := strings.TrimSpace(image)
+	if index := strings.Index(name, "@"); index >= 0 {
+		reference.Digest = name[index+1:]
+		name = name[:index]
+	}
+
+	//A colon after last slash separates tag. Other colons belong to port of registry.
+	if index := strings.LastIndex(name, ":"); index > strings.LastIndex(name, "/") {
+		reference.Tag = name[index+1:]
+		name = name[:index]
+	}
+	if reference.Tag == "" && reference.Digest == "" {
+		reference.Tag = "latest"
+	}
+
+	parts := strings.SplitN(name, "/", 2)
+	if len(parts) == 1 || !(strings.ContainsAny(parts[0], ".:") || parts[0] == "localhost") {
+		if len(parts) == 1 {
+			name = "library/" + name
+		}
+		name = defaultImageRegistry + "/" + name
+	}
+	reference.Repository = strings.ToLower(name)
+
+	return reference
+}
+
+//imageIDDigest returns digest of image ID of container status e.g. 'docker-pullable://nginx@sha256:...'.
+func imageIDDigest(imageID string) string {
+	if index := strings.LastIndex(imageID, "@"); index >= 0 {
+		return imageID[index+1:]
+	}
+	if index := strings.Index(imageID, "sha256:"); index >= 0 {
+		return imageID[index:]
+	}
+
+	return ""
+}
+
+//normalizeHost lower cases host and strips port and trailing dot. In cluster DNS names of services are
+//shortened to 'name.namespace.svc'.
+func normalizeHost(host string) string {
+	host = strings.ToLower(strings.TrimSpace(host))
+	if index := strings.LastIndex(host, ":"); index >= 0 && !strings.Contains(host[index:], "]") {
+		host = host[:index]
+	}
+	host = strings.TrimSuffix(host, ".")
+
+	if parts := strings.Split(host, "."); len(parts) > 3 && parts[2] == "svc" {
+		host = strings.Join(parts[:3], ".")
+	}
+
+	return host
+}
+
+func uniqueMappedResources(mappedResources []MappedResource) []MappedResource {
+	seen := make(map[string]bool)
+
+	var unique []MappedResource
+	for _, mappedResource := range mappedResources {
+		key, _ := metaResourceKeyFunc(mappedResource)
+		if !seen[key] {
+			seen[key] = true
+			unique = append(unique, mappedResource)
+		}
+	}
+
+	sortMappedResources(unique)
+	return unique
+}
+
+func sortMappedResources(mappedResources []MappedResource) {
+	sort.SliceStable(mappedResources, func(i, j int) bool {
+		if mappedResources[i].Namespace != mappedResources[j].Namespace {
+			return mappedResources[i].Namespace < mappedResources[j].Namespace
+		}
+		return mappedResources[i].CommonLabel < mappedResources[j].CommonLabel
+	})
+}
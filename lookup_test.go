@@ -0,0 +1,137 @@
+package kubemap
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	core_v1 "k8s.io/api/core/v1"
+	network_v1beta1 "k8s.io/api/networking/v1beta1"
+	"k8s.io/client-go/tools/cache"
+)
+
+func TestLookup(t *testing.T) {
+	mapper := NewMapper()
+	_, err := mapper.Map(helperGetLookupResources())
+	assert.Nil(t, err)
+
+	tests := []struct {
+		name   string
+		lookup func(string) []MappedResource
+		value  string
+		found  bool
+	}{
+		{"pod IP", mapper.LookupIP, "10.1.0.5", true},
+		{"cluster IP", mapper.LookupIP, "10.96.0.10", true},
+		{"load balancer IP", mapper.LookupIP, "34.1.2.3", true},
+		{"unknown IP", mapper.LookupIP, "10.1.0.6", false},
+		{"ingress host", mapper.LookupHost, "some.dns.somecompany.com", true},
+		{"ingress host with port", mapper.LookupHost, "SOME.dns.somecompany.com:443", true},
+		{"wildcard TLS host", mapper.LookupHost, "www.shop.example.com", true},
+		{"wildcard matches single label", mapper.LookupHost, "a.www.shop.example.com", false},
+		{"service DNS name", mapper.LookupHost, "kube-map.test-namespace.svc.cluster.local", true},
+		{"unknown host", mapper.LookupHost, "other.somecompany.com", false},
+		{"repository", mapper.LookupImage, "nginx", true},
+		{"normalized repository", mapper.LookupImage, "docker.io/library/nginx", true},
+		{"tag", mapper.LookupImage, "nginx:1.19", true},
+		{"other tag", mapper.LookupImage, "nginx:1.20", false},
+		{"digest", mapper.LookupImage, "sha256:4f2a", true},
+		{"reference with digest", mapper.LookupImage, "nginx@sha256:4f2a", true},
+		{"registry with port", mapper.LookupImage, "registry.local:5000/team/sidecar", true},
+		{"deployment image", mapper.LookupImage, "some/random/image:latest", true},
+		{"node", mapper.LookupNode, "node-1", true},
+		{"unknown node", mapper.LookupNode, "node-2", false},
+		{"empty", mapper.LookupNode, "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mappedResources := tt.lookup(tt.value)
+			if tt.found {
+				assert.Len(t, mappedResources, 1)
+				assert.Equal(t, "kube-map", mappedResources[0].CommonLabel)
+			} else {
+				assert.Empty(t, mappedResources)
+			}
+		})
+	}
+}
+
+func TestLookupIndexFollowsStore(t *testing.T) {
+	resources := helperGetLookupResources()
+	mapper := NewStoreMapper(cache.NewStore(metaResourceKeyFunc))
+	for _, event := range resourceEventsForMapping(resources) {
+		_, err := mapper.StoreMap(event)
+		assert.Nil(t, err)
+	}
+
+	assert.Len(t, mapper.LookupNode("node-1"), 1)
+
+	pod := resources.Pods[0]
+	_, err := mapper.StoreMap(ResourceEvent{
+		EventType:    "DELETED",
+		ResourceType: "pod",
+		Namespace:    pod.Namespace,
+		Name:         pod.Name,
+		Key:          fmt.Sprintf("%s/%s", pod.Namespace, pod.Name),
+	})
+	assert.Nil(t, err)
+	assert.Empty(t, mapper.LookupNode("node-1"))
+	assert.Empty(t, mapper.LookupIP("10.1.0.5"))
+	assert.Len(t, mapper.LookupIP("10.96.0.10"), 1)
+
+	pod.Spec.NodeName = "node-2"
+	_, err = mapper.StoreMap(gerResourceEvent(&pod, "pod"))
+	assert.Nil(t, err)
+	assert.Len(t, mapper.LookupNode("node-2"), 1)
+}
+
+func TestLookupOfExistingStore(t *testing.T) {
+	mapper := NewMapper()
+	_, err := mapper.Map(helperGetLookupResources())
+	assert.Nil(t, err)
+
+	existing := NewStoreMapper(mapper.store)
+	assert.Len(t, existing.LookupIP("10.1.0.5"), 1)
+}
+
+func TestParseImageReference(t *testing.T) {
+	tests := []struct {
+		image string
+		want  imageReference
+	}{
+		{"nginx", imageReference{Repository: "docker.io/library/nginx", Tag: "latest"}},
+		{"nginx:1.19", imageReference{Repository: "docker.io/library/nginx", Tag: "1.19"}},
+		{"team/app:v2", imageReference{Repository: "docker.io/team/app", Tag: "v2"}},
+		{"gcr.io/team/app@sha256:4f2a", imageReference{Repository: "gcr.io/team/app", Digest: "sha256:4f2a"}},
+		{"localhost/app", imageReference{Repository: "localhost/app", Tag: "latest"}},
+		{"registry.local:5000/app:1.0@sha256:4f2a", imageReference{Repository: "registry.local:5000/app", Tag: "1.0", Digest: "sha256:4f2a"}},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, parseImageReference(tt.image), tt.image)
+	}
+}
+
+//helperGetLookupResources returns fixtures with addresses, wildcard TLS host, node and images set.
+func helperGetLookupResources() KubeResources {
+	resources := helperGetK8sResources()
+
+	resources.Ingresses[0].Spec.TLS = []network_v1beta1.IngressTLS{{Hosts: []string{"*.shop.example.com"}}}
+	resources.Services[0].Spec.ClusterIP = "10.96.0.10"
+	resources.Services[0].Status.LoadBalancer.Ingress = []core_v1.LoadBalancerIngress{{IP: "34.1.2.3"}}
+
+	pod := &resources.Pods[0]
+	pod.Spec.NodeName = "node-1"
+	pod.Spec.Containers[0].Image = "nginx:1.19"
+	pod.Spec.InitContainers = []core_v1.Container{{Name: "init", Image: "registry.local:5000/team/sidecar:0.1"}}
+	pod.Status.PodIP = "10.1.0.5"
+	pod.Status.ContainerStatuses = []core_v1.ContainerStatus{{
+		Name:    pod.Spec.Containers[0].Name,
+		Image:   "docker.io/library/nginx:1.19",
+		ImageID: "docker-pullable://nginx@sha256:4f2a",
+	}}
+
+	return resources
+}
@@ -17,9 +17,31 @@ func (m *Mapper) kubemapper(obj interface{}, store cache.Store) ([]MapResult, er
 	object := obj.(ResourceEvent)
 	m.debug(fmt.Sprintf("Processing object - K8s Type - %s Name - %s Namespace - %s", object.ResourceType, object.Name, object.Namespace))
 
-	mappedResource, mapErr := m.resourceMapper(object, store)
-	if mapErr != nil {
-		return []MapResult{}, mapErr
+	projectedEvent, projectionErr := m.projection.project(object.Event)
+	if projectionErr != nil {
+		m.warn(fmt.Sprintf("Cannot project object. Storing it as it is - %v K8s Type - %s Name - %s Namespace - %s", projectionErr, object.ResourceType, object.Name, object.Namespace))
+	}
+	object.Event = projectedEvent
+
+	var mappedResource []MapResult
+	var mapErr error
+	_, isRelated := m.related.kind(object.ResourceType)
+	if isRelated {
+		mappedResource, mapErr = m.mapRelatedObj(object, store)
+		if mapErr != nil {
+			return []MapResult{}, mapErr
+		}
+	} else {
+		if linksChanged(object, store) {
+			mappedResource, mapErr = m.remapRelinkedObj(object, store)
+		} else {
+			mappedResource, mapErr = m.resourceMapper(object, store)
+		}
+		if mapErr != nil {
+			return []MapResult{}, mapErr
+		}
+
+		mappedResource = m.mergeMatchedGroups(object, mappedResource, store)
 	}
 
 	if object.EventType == "DELETED" {
@@ -31,6 +53,14 @@ func (m *Mapper) kubemapper(obj interface{}, store cache.Store) ([]MapResult, er
 		return []MapResult{}, storeErr
 	}
 
+	if object.EventType == "DELETED" && !isRelated {
+		mappedResource, storeErr = m.splitUnlinkedGroups(object, mappedResource, store)
+		if storeErr != nil {
+			m.warn(fmt.Sprintf("Error while splitting groups - %v K8s Type - %s Name - %s Namespace - %s", storeErr, object.ResourceType, object.Name, object.Namespace))
+			return []MapResult{}, storeErr
+		}
+	}
+
 	if object.EventType == "DELETED" {
 		m.info(fmt.Sprintf("Store updated successfully for incoming DELETE event with Resource %s", object.Name))
 	}
@@ -348,7 +378,9 @@ func (m *Mapper) ingressCheck(mappedResource MappedResource, serviceName string,
 					// ingressMappedResource, _ := getObjectFromStore(namespaceKey, store)
 					ingressMappedResource, _ := getObjectFromStore(base64.StdEncoding.EncodeToString([]byte(namespaceKey)), store)
 					for _, loneIngress := range ingressMappedResource.Kube.Ingresses {
-						mappedResource.Kube.Ingresses = append(mappedResource.Kube.Ingresses, loneIngress)
+						if !hasIngress(mappedResource, loneIngress.Name) {
+							mappedResource.Kube.Ingresses = append(mappedResource.Kube.Ingresses, loneIngress)
+						}
 					}
 					oldIngressDeleteKeys = append(oldIngressDeleteKeys, namespaceKey)
 				}
@@ -378,7 +410,7 @@ func (m *Mapper) ingressCheck(mappedResource MappedResource, serviceName string,
 					}
 
 					for _, currentIngressBackendService := range currentIngressBackendServices {
-						if currentIngressBackendService == serviceName {
+						if currentIngressBackendService == serviceName && !hasIngress(mappedResource, mappedIngressResource.Name) {
 							mappedResource.Kube.Ingresses = append(mappedResource.Kube.Ingresses, mappedIngressResource)
 						}
 					}
@@ -392,6 +424,18 @@ func (m *Mapper) ingressCheck(mappedResource MappedResource, serviceName string,
 	return mappedResource, oldIngressDeleteKeys
 }
 
+//hasIngress checks if ingress is already part of mapped resource, which happens once groups of overlapping selectors
+//are merged.
+func hasIngress(mappedResource MappedResource, name string) bool {
+	for _, ingress := range mappedResource.Kube.Ingresses {
+		if ingress.Name == name {
+			return true
+		}
+	}
+
+	return false
+}
+
 func (m *Mapper) mapServiceObj(obj ResourceEvent, store cache.Store) (MapResult, error) {
 	var service core_v1.Service
 	var namespaceKeys []string
@@ -0,0 +1,482 @@
+package kubemap
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	apps_v1 "k8s.io/api/apps/v1"
+	core_v1 "k8s.io/api/core/v1"
+	network_v1beta1 "k8s.io/api/networking/v1beta1"
+	"k8s.io/client-go/tools/cache"
+)
+
+//mergeMatchedGroups merges every other group in namespace which incoming object also matches into its mapped resource.
+//Mappers add object to first group it matches. When selectors overlap e.g. a pod selected by its own service and by a
+//shared one, groups of both services exist by the time pod arrives and pod links them. Batch mapping links them as
+//well, as whichever of them is mapped after pod joins pod's group. Without merging, incremental mapping of same
+//objects would keep them apart depending on order of events, which CheckConsistency reports as divergence.
+//Merged groups are deleted from store through DeleteKeys of result.
+func (m *Mapper) mergeMatchedGroups(obj ResourceEvent, results []MapResult, store cache.Store) []MapResult {
+	if obj.Event == nil {
+		return results
+	}
+
+	for i, result := range results {
+		if !result.IsMapped || result.IsStoreUpdated || (result.Action != "Added" && result.Action != "Updated") {
+			continue
+		}
+
+		ownKeys := map[string]bool{}
+		if result.Key != "" {
+			ownKeys[result.Key] = true
+		}
+		for _, deleteKey := range result.DeleteKeys {
+			ownKeys[deleteKey] = true
+		}
+
+		var mergedKeys []string
+		for _, namespaceKey := range getNamespaceKeys(store, obj.Namespace) {
+			if ownKeys[namespaceKey] {
+				continue
+			}
+
+			metaIdentifier := MetaIdentifier{}
+			json.Unmarshal([]byte(strings.Split(namespaceKey, "$")[1]), &metaIdentifier)
+
+			if !objectMatchesIdentifier(obj.Event, metaIdentifier) {
+				continue
+			}
+
+			otherMappedResource, err := getObjectFromStore(base64.StdEncoding.EncodeToString([]byte(namespaceKey)), store)
+			if err != nil {
+				continue
+			}
+
+			result.MappedResource = mergeMappedResources(result.MappedResource, otherMappedResource)
+			mergedKeys = append(mergedKeys, namespaceKey)
+			m.debug(fmt.Sprintf("%s %s merged Common Label %s into %s", obj.ResourceType, obj.Name, otherMappedResource.CommonLabel, result.MappedResource.CommonLabel))
+		}
+
+		if len(mergedKeys) > 0 {
+			if result.Key != "" {
+				result.DeleteKeys = append(result.DeleteKeys, result.Key)
+				result.Key = ""
+			}
+			result.DeleteKeys = removeDuplicateStrings(append(result.DeleteKeys, mergedKeys...))
+			results[i] = result
+		}
+	}
+
+	return results
+}
+
+//splitUnlinkedGroups maps members of every updated group again once object which may have linked merged groups is
+//deleted. Groups are never split by mappers, so a pod which merged groups of two
+//services would otherwise keep them together after it is gone, while batch mapping of remaining objects keeps them
+//apart. A group which falls apart is replaced in store by its parts, reported as deleted and added results.
+func (m *Mapper) splitUnlinkedGroups(obj ResourceEvent, results []MapResult, store cache.Store) ([]MapResult, error) {
+	var splitResults []MapResult
+
+	for _, result := range results {
+		if !result.IsMapped || result.Action != "Updated" {
+			splitResults = append(splitResults, result)
+			continue
+		}
+
+		key, err := metaResourceKeyFunc(result.MappedResource)
+		if err != nil {
+			return results, err
+		}
+		item, exists, _ := store.GetByKey(key)
+		if !exists {
+			splitResults = append(splitResults, result)
+			continue
+		}
+		mappedResource := item.(MappedResource)
+
+		groups, err := m.remapMembers(mappedResource)
+		if err != nil {
+			return results, err
+		}
+		if len(groups) < 2 {
+			splitResults = append(splitResults, result)
+			continue
+		}
+
+		if err := m.deleteFromStore(mappedResource, store); err != nil {
+			return results, err
+		}
+		decodedKey, _ := base64.StdEncoding.DecodeString(key)
+		splitResults = append(splitResults, MapResult{
+			Action:         "Deleted",
+			Key:            string(decodedKey),
+			IsMapped:       true,
+			IsStoreUpdated: true,
+			CommonLabel:    mappedResource.CommonLabel,
+			MappedResource: mappedResource,
+			Message:        fmt.Sprintf("Common Label %s is split after %s %s changed", mappedResource.CommonLabel, obj.ResourceType, obj.Name),
+		})
+
+		for _, group := range groups {
+			if err := m.addToStore(group, store); err != nil {
+				return results, err
+			}
+			splitResults = append(splitResults, MapResult{
+				Action:         "Added",
+				IsMapped:       true,
+				IsStoreUpdated: true,
+				CommonLabel:    group.CommonLabel,
+				MappedResource: group,
+				Message:        fmt.Sprintf("Common Label %s is split from Common Label %s", group.CommonLabel, mappedResource.CommonLabel),
+			})
+		}
+		m.debug(fmt.Sprintf("%s %s split Common Label %s into %d groups", obj.ResourceType, obj.Name, mappedResource.CommonLabel, len(groups)))
+	}
+
+	return splitResults, nil
+}
+
+//remapRelinkedObj maps update of object which changes groups it links as delete of its stored copy followed by add
+//of updated object. Mappers would otherwise keep object in groups its stored copy was matched to. Results of delete
+//are applied to store right away.
+func (m *Mapper) remapRelinkedObj(obj ResourceEvent, store cache.Store) ([]MapResult, error) {
+	deleted := obj
+	deleted.EventType = "DELETED"
+	deleted.Event = nil
+
+	deleteResults, err := m.resourceMapper(deleted, store)
+	if err != nil {
+		return []MapResult{}, err
+	}
+	if err := m.updateStore(deleteResults, store); err != nil {
+		return []MapResult{}, err
+	}
+	deleteResults, err = m.splitUnlinkedGroups(deleted, deleteResults, store)
+	if err != nil {
+		return []MapResult{}, err
+	}
+	for i := range deleteResults {
+		deleteResults[i].IsStoreUpdated = true
+	}
+
+	added := obj
+	added.EventType = "ADDED"
+	addResults, err := m.resourceMapper(added, store)
+	if err != nil {
+		return []MapResult{}, err
+	}
+
+	return append(addResults, deleteResults...), nil
+}
+
+//remapMembers maps members of mapped resource in a store of their own, as batch mapping of them would.
+func (m *Mapper) remapMembers(mappedResource MappedResource) ([]MappedResource, error) {
+	scratch := &Mapper{log: m.log, options: m.options, redactor: m.redactor, related: m.related}
+	store := cache.NewStore(metaResourceKeyFunc)
+
+	members := KubeResources{
+		Ingresses:   mappedResource.Kube.Ingresses,
+		Services:    mappedResource.Kube.Services,
+		Deployments: mappedResource.Kube.Deployments,
+		ReplicaSets: mappedResource.Kube.ReplicaSets,
+		Pods:        mappedResource.Kube.Pods,
+	}
+	for _, event := range resourceEventsForMapping(members) {
+		results, err := scratch.resourceMapper(event, store)
+		if err != nil {
+			return nil, err
+		}
+
+		results = scratch.mergeMatchedGroups(event, results, store)
+		if err := scratch.updateStore(results, store); err != nil {
+			return nil, err
+		}
+	}
+
+	return getAllMappedResources(store).MappedResource, nil
+}
+
+//linksChanged checks if updated object links groups differently than its copy in store does. Only labels, selectors,
+//owners and ingress backends decide which groups an object belongs to.
+func linksChanged(obj ResourceEvent, store cache.Store) bool {
+	if obj.EventType != "UPDATED" || obj.Event == nil {
+		return false
+	}
+
+	for _, namespaceKey := range getNamespaceKeys(store, obj.Namespace) {
+		mappedResource, err := getObjectFromStore(base64.StdEncoding.EncodeToString([]byte(namespaceKey)), store)
+		if err != nil {
+			continue
+		}
+
+		if stored := memberOfKind(mappedResource, obj.ResourceType, obj.Name); stored != nil {
+			return !reflect.DeepEqual(linkingFields(stored), linkingFields(obj.Event))
+		}
+	}
+
+	return false
+}
+
+//memberOfKind returns ingress, service, deployment, replica set or pod of mapped resource with given name.
+func memberOfKind(mappedResource MappedResource, resourceType, name string) interface{} {
+	switch resourceType {
+	case "ingress":
+		for i := range mappedResource.Kube.Ingresses {
+			if mappedResource.Kube.Ingresses[i].Name == name {
+				return &mappedResource.Kube.Ingresses[i]
+			}
+		}
+	case "service":
+		for i := range mappedResource.Kube.Services {
+			if mappedResource.Kube.Services[i].Name == name {
+				return &mappedResource.Kube.Services[i]
+			}
+		}
+	case "deployment":
+		for i := range mappedResource.Kube.Deployments {
+			if mappedResource.Kube.Deployments[i].Name == name {
+				return &mappedResource.Kube.Deployments[i]
+			}
+		}
+	case "replicaset":
+		for i := range mappedResource.Kube.ReplicaSets {
+			if mappedResource.Kube.ReplicaSets[i].Name == name {
+				return &mappedResource.Kube.ReplicaSets[i]
+			}
+		}
+	case "pod":
+		for i := range mappedResource.Kube.Pods {
+			if mappedResource.Kube.Pods[i].Name == name {
+				return &mappedResource.Kube.Pods[i]
+			}
+		}
+	}
+
+	return nil
+}
+
+//linkingFields returns fields of object which mappers match groups by.
+func linkingFields(obj interface{}) interface{} {
+	switch object := obj.(type) {
+	case *network_v1beta1.Ingress:
+		return []interface{}{object.Spec.Backend, object.Spec.Rules}
+	case *core_v1.Service:
+		return []interface{}{object.Labels, object.Spec.Selector}
+	case *apps_v1.Deployment:
+		return []interface{}{object.Labels, object.Spec.Selector}
+	case *apps_v1.ReplicaSet:
+		return []interface{}{object.Labels, object.Spec.Selector, object.OwnerReferences}
+	case *core_v1.Pod:
+		return []interface{}{object.Labels, object.OwnerReferences}
+	}
+
+	return nil
+}
+
+//objectMatchesIdentifier applies same matching rules which mappers use for each resource type.
+func objectMatchesIdentifier(obj interface{}, metaIdentifier MetaIdentifier) bool {
+	switch object := obj.(type) {
+	case *core_v1.Service:
+		selector := object.Spec.Selector
+		for _, svcID := range metaIdentifier.ServicesIdentifier.MatchLabels {
+			if len(selector) > 0 && reflect.DeepEqual(selector, svcID) {
+				return true
+			}
+		}
+		for _, depID := range metaIdentifier.DeploymentsIdentifier.MatchLabels {
+			if len(selector) > 0 && reflect.DeepEqual(selector, depID) {
+				return true
+			}
+		}
+		for _, rsID := range metaIdentifier.ReplicaSetsIdentifier {
+			if labelsSubset(selector, rsID.MatchLabels) {
+				return true
+			}
+		}
+		for _, podID := range metaIdentifier.PodsIdentifier {
+			if labelsSubset(selector, podID.MatchLabels) {
+				return true
+			}
+		}
+	case *apps_v1.Deployment:
+		var selector map[string]string
+		if object.Spec.Selector != nil {
+			selector = object.Spec.Selector.MatchLabels
+		}
+		for _, svcID := range metaIdentifier.ServicesIdentifier.MatchLabels {
+			if len(selector) > 0 && reflect.DeepEqual(selector, svcID) {
+				return true
+			}
+		}
+		for _, rsID := range metaIdentifier.ReplicaSetsIdentifier {
+			for _, ownerReference := range rsID.OwnerReferences {
+				if ownerReference == object.Name {
+					return true
+				}
+			}
+		}
+		for _, podID := range metaIdentifier.PodsIdentifier {
+			if labelsSubset(selector, podID.MatchLabels) {
+				return true
+			}
+		}
+	case *apps_v1.ReplicaSet:
+		var selector map[string]string
+		if object.Spec.Selector != nil {
+			selector = object.Spec.Selector.MatchLabels
+		}
+		for _, svcID := range metaIdentifier.ServicesIdentifier.MatchLabels {
+			if labelsSubset(svcID, selector) {
+				return true
+			}
+		}
+		for _, depID := range metaIdentifier.DeploymentsIdentifier.MatchLabels {
+			if labelsSubset(depID, selector) {
+				return true
+			}
+		}
+		for _, podID := range metaIdentifier.PodsIdentifier {
+			if labelsSubset(selector, podID.MatchLabels) {
+				return true
+			}
+		}
+	case *core_v1.Pod:
+		for _, svcID := range metaIdentifier.ServicesIdentifier.MatchLabels {
+			if labelsSubset(svcID, object.Labels) {
+				return true
+			}
+		}
+		for _, depID := range metaIdentifier.DeploymentsIdentifier.MatchLabels {
+			if labelsSubset(depID, object.Labels) {
+				return true
+			}
+		}
+		for _, rsID := range metaIdentifier.ReplicaSetsIdentifier {
+			if labelsSubset(rsID.MatchLabels, object.Labels) {
+				return true
+			}
+		}
+		for _, podID := range metaIdentifier.PodsIdentifier {
+			if len(object.Labels) > 0 && reflect.DeepEqual(object.Labels, podID.MatchLabels) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+//labelsSubset checks if every label of non empty selector is present in labels.
+func labelsSubset(selector, labels map[string]string) bool {
+	if len(selector) == 0 {
+		return false
+	}
+
+	for key, value := range selector {
+		if val, ok := labels[key]; !ok || val != value {
+			return false
+		}
+	}
+
+	return true
+}
+
+//mergeMappedResources adds resources of other to mapped resource skipping the ones already present.
+func mergeMappedResources(mappedResource, other MappedResource) MappedResource {
+	for _, ingress := range other.Kube.Ingresses {
+		isPresent := false
+		for _, mappedIngress := range mappedResource.Kube.Ingresses {
+			if mappedIngress.Name == ingress.Name {
+				isPresent = true
+			}
+		}
+		if !isPresent {
+			mappedResource.Kube.Ingresses = append(mappedResource.Kube.Ingresses, ingress)
+		}
+	}
+
+	for _, service := range other.Kube.Services {
+		isPresent := false
+		for _, mappedService := range mappedResource.Kube.Services {
+			if mappedService.Name == service.Name {
+				isPresent = true
+			}
+		}
+		if !isPresent {
+			mappedResource.Kube.Services = append(mappedResource.Kube.Services, service)
+		}
+	}
+
+	for _, deployment := range other.Kube.Deployments {
+		isPresent := false
+		for _, mappedDeployment := range mappedResource.Kube.Deployments {
+			if mappedDeployment.Name == deployment.Name {
+				isPresent = true
+			}
+		}
+		if !isPresent {
+			mappedResource.Kube.Deployments = append(mappedResource.Kube.Deployments, deployment)
+		}
+	}
+
+	for _, replicaSet := range other.Kube.ReplicaSets {
+		isPresent := false
+		for _, mappedReplicaSet := range mappedResource.Kube.ReplicaSets {
+			if mappedReplicaSet.Name == replicaSet.Name {
+				isPresent = true
+			}
+		}
+		if !isPresent {
+			mappedResource.Kube.ReplicaSets = append(mappedResource.Kube.ReplicaSets, replicaSet)
+		}
+	}
+
+	for _, pod := range other.Kube.Pods {
+		isPresent := false
+		for _, mappedPod := range mappedResource.Kube.Pods {
+			if mappedPod.Name == pod.Name {
+				isPresent = true
+			}
+		}
+		if !isPresent {
+			mappedResource.Kube.Pods = append(mappedResource.Kube.Pods, pod)
+		}
+	}
+
+	return mappedResource
+}
+
+//getNamespaceKeys returns decoded store keys of all mapped resources in namespace.
+func getNamespaceKeys(store cache.Store, namespace string) []string {
+	var namespaceKeys []string
+
+	keys := store.ListKeys()
+	for _, b64Key := range keys {
+		encodedKey, _ := base64.StdEncoding.DecodeString(b64Key)
+		key := fmt.Sprintf("%s", encodedKey)
+		if len(strings.Split(key, "$")) > 1 {
+			if strings.Split(key, "$")[0] == namespace {
+				namespaceKeys = append(namespaceKeys, key)
+			}
+		}
+	}
+
+	return namespaceKeys
+}
+
+//getAllKeys returns decoded store keys of all mapped resources.
+func getAllKeys(store cache.Store) []string {
+	var allKeys []string
+
+	for _, b64Key := range store.ListKeys() {
+		encodedKey, _ := base64.StdEncoding.DecodeString(b64Key)
+		allKeys = append(allKeys, fmt.Sprintf("%s", encodedKey))
+	}
+	sort.Strings(allKeys)
+
+	return allKeys
+}
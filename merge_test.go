@@ -0,0 +1,201 @@
+package kubemap
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	apps_v1 "k8s.io/api/apps/v1"
+	core_v1 "k8s.io/api/core/v1"
+	network_v1beta1 "k8s.io/api/networking/v1beta1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+func TestPodMatchingTwoGroupsMergesThem(t *testing.T) {
+	resources := helperGetOverlappingResources()
+
+	mapper := NewMapper()
+	for _, event := range resourceEventsForMapping(KubeResources{Services: resources.Services}) {
+		_, err := mapper.StoreMap(event)
+		assert.Nil(t, err)
+	}
+	assert.Len(t, getAllMappedResources(mapper.store).MappedResource, 3)
+
+	results, err := mapper.StoreMap(gerResourceEvent(resources.Pods[0].DeepCopy(), "pod"))
+	assert.Nil(t, err)
+	assert.Len(t, results, 1)
+	assert.True(t, results[0].IsMapped)
+	assert.ElementsMatch(t, []string{"pod/a-0", "service/a", "service/shared"}, groupMembers(results[0].MappedResource))
+
+	mappedResources := getAllMappedResources(mapper.store).MappedResource
+	assert.Len(t, mappedResources, 2)
+}
+
+func TestDeletingLinkingPodSplitsGroups(t *testing.T) {
+	resources := helperGetOverlappingResources()
+
+	mapper := NewMapper()
+	for _, event := range resourceEventsForMapping(KubeResources{Services: resources.Services, Pods: resources.Pods}) {
+		_, err := mapper.StoreMap(event)
+		assert.Nil(t, err)
+	}
+	assert.Len(t, getAllMappedResources(mapper.store).MappedResource, 1)
+
+	pod := resources.Pods[0]
+	results, err := mapper.StoreMap(ResourceEvent{EventType: "DELETED", ResourceType: "pod", Namespace: pod.Namespace, Name: pod.Name})
+	assert.Nil(t, err)
+	assert.Len(t, results, 3)
+	assert.Equal(t, "Deleted", results[0].Action)
+
+	var groups [][]string
+	for _, mappedResource := range getAllMappedResources(mapper.store).MappedResource {
+		groups = append(groups, groupMembers(mappedResource))
+	}
+	assert.ElementsMatch(t, [][]string{{"service/a"}, {"pod/b-0", "service/b", "service/shared"}}, groups)
+}
+
+func TestRelabelingLinkingPodSplitsGroups(t *testing.T) {
+	resources := helperGetOverlappingResources()
+
+	mapper := NewMapper()
+	for _, event := range resourceEventsForMapping(KubeResources{Services: resources.Services, Pods: resources.Pods[:1]}) {
+		_, err := mapper.StoreMap(event)
+		assert.Nil(t, err)
+	}
+	assert.Len(t, getAllMappedResources(mapper.store).MappedResource, 2)
+
+	pod := resources.Pods[0].DeepCopy()
+	delete(pod.Labels, "tier")
+	event := gerResourceEvent(pod, "pod")
+	event.EventType = "UPDATED"
+	_, err := mapper.StoreMap(event)
+	assert.Nil(t, err)
+
+	var groups [][]string
+	for _, mappedResource := range getAllMappedResources(mapper.store).MappedResource {
+		groups = append(groups, groupMembers(mappedResource))
+	}
+	assert.ElementsMatch(t, [][]string{{"pod/a-0", "service/a"}, {"service/b"}, {"service/shared"}}, groups)
+}
+
+func TestIngressIsNotDuplicatedWhenGroupsMerge(t *testing.T) {
+	resources := helperGetOverlappingResources()
+	events := resourceEventsForMapping(KubeResources{Ingresses: resources.Ingresses})
+	events = append(events, resourceEventsForMapping(KubeResources{Services: resources.Services[2:]})...)
+	events = append(events, resourceEventsForMapping(KubeResources{Pods: resources.Pods})...)
+	events = append(events, resourceEventsForMapping(KubeResources{Services: resources.Services[:2]})...)
+
+	mapper := NewMapper()
+	for _, event := range events {
+		_, err := mapper.StoreMap(event)
+		assert.Nil(t, err)
+	}
+
+	mappedResources := getAllMappedResources(mapper.store).MappedResource
+	assert.Len(t, mappedResources, 1)
+	assert.ElementsMatch(t, []string{
+		"ingress/a", "ingress/b", "pod/a-0", "pod/b-0", "service/a", "service/b", "service/shared",
+	}, groupMembers(mappedResources[0]))
+
+	report, err := CheckConsistency(events, MapOptions{})
+	assert.Nil(t, err)
+	assert.True(t, report.Consistent, "divergences - %+v", report.Divergences)
+}
+
+func TestObjectMatchesIdentifier(t *testing.T) {
+	identifier := MetaIdentifier{}
+	identifier.ServicesIdentifier.MatchLabels = []map[string]string{{"app": "a"}}
+	identifier.ReplicaSetsIdentifier = []ChildSet{{MatchLabels: map[string]string{"app": "a", "pod-template-hash": "1"}, OwnerReferences: []string{"a"}}}
+
+	tests := []struct {
+		name    string
+		obj     interface{}
+		matches bool
+	}{
+		{
+			name:    "pod selected by service",
+			obj:     &core_v1.Pod{ObjectMeta: meta_v1.ObjectMeta{Labels: map[string]string{"app": "a", "tier": "shared"}}},
+			matches: true,
+		},
+		{
+			name: "pod of other app",
+			obj:  &core_v1.Pod{ObjectMeta: meta_v1.ObjectMeta{Labels: map[string]string{"app": "b"}}},
+		},
+		{
+			name:    "deployment owning replica set",
+			obj:     &apps_v1.Deployment{ObjectMeta: meta_v1.ObjectMeta{Name: "a"}},
+			matches: true,
+		},
+		{
+			name:    "service selecting replica set",
+			obj:     &core_v1.Service{Spec: core_v1.ServiceSpec{Selector: map[string]string{"app": "a"}}},
+			matches: true,
+		},
+		{
+			name: "service without selector",
+			obj:  &core_v1.Service{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.matches, objectMatchesIdentifier(tt.obj, identifier))
+		})
+	}
+}
+
+func TestLabelsSubset(t *testing.T) {
+	labels := map[string]string{"app": "a", "tier": "shared"}
+
+	assert.True(t, labelsSubset(map[string]string{"tier": "shared"}, labels))
+	assert.True(t, labelsSubset(labels, labels))
+	assert.False(t, labelsSubset(map[string]string{"tier": "web"}, labels))
+	assert.False(t, labelsSubset(map[string]string{}, labels))
+	assert.False(t, labelsSubset(nil, labels))
+}
+
+//helperGetOverlappingResources returns pods of apps 'a' and 'b' selected by service and ingress of their own app and by
+//service 'shared', so that groups of both apps are merged into one.
+func helperGetOverlappingResources() KubeResources {
+	var resources KubeResources
+	namespace := "overlapping"
+
+	for _, name := range []string{"a", "b"} {
+		resources.Ingresses = append(resources.Ingresses, network_v1beta1.Ingress{
+			ObjectMeta: meta_v1.ObjectMeta{Name: name, Namespace: namespace, UID: helperUID(name, "ingress")},
+			Spec: network_v1beta1.IngressSpec{
+				Backend: &network_v1beta1.IngressBackend{ServiceName: name, ServicePort: intstr.FromInt(80)},
+				Rules: []network_v1beta1.IngressRule{{
+					IngressRuleValue: network_v1beta1.IngressRuleValue{
+						HTTP: &network_v1beta1.HTTPIngressRuleValue{
+							Paths: []network_v1beta1.HTTPIngressPath{{
+								Path:    "/",
+								Backend: network_v1beta1.IngressBackend{ServiceName: name, ServicePort: intstr.FromInt(80)},
+							}},
+						},
+					},
+				}},
+			},
+		})
+		resources.Services = append(resources.Services, core_v1.Service{
+			ObjectMeta: meta_v1.ObjectMeta{Name: name, Namespace: namespace, UID: helperUID(name, "service")},
+			Spec:       core_v1.ServiceSpec{Selector: map[string]string{"app": name}},
+		})
+		resources.Pods = append(resources.Pods, core_v1.Pod{
+			ObjectMeta: meta_v1.ObjectMeta{
+				Name:      name + "-0",
+				Namespace: namespace,
+				Labels:    map[string]string{"app": name, "tier": "shared"},
+				UID:       helperUID(name+"-0", "pod"),
+			},
+		})
+	}
+
+	resources.Services = append(resources.Services, core_v1.Service{
+		ObjectMeta: meta_v1.ObjectMeta{Name: "shared", Namespace: namespace, UID: helperUID("shared", "service")},
+		Spec:       core_v1.ServiceSpec{Selector: map[string]string{"tier": "shared"}},
+	})
+
+	return resources
+}
@@ -0,0 +1,148 @@
+package kubemap
+
+import (
+	"sort"
+)
+
+const (
+	//AppNameLabel is recommended k8s label holding name of application.
+	AppNameLabel = "app.kubernetes.io/name"
+	//AppLabel is commonly used label holding name of application.
+	AppLabel = "app"
+)
+
+//NamingStrategy decides common label of a mapped resource.
+//It is evaluated every time members of a mapped resource change and must return same name for same members.
+//Returning empty string falls back to default naming.
+type NamingStrategy interface {
+	CommonLabel(mappedResource MappedResource) string
+}
+
+//NamingFunc allows to use a function as NamingStrategy.
+type NamingFunc func(mappedResource MappedResource) string
+
+//CommonLabel calls f.
+func (f NamingFunc) CommonLabel(mappedResource MappedResource) string {
+	return f(mappedResource)
+}
+
+//PreferLabel names mapped resource by value of given label on its members.
+//If members have different values, smallest one is used.
+func PreferLabel(key string) NamingStrategy {
+	return NamingFunc(func(mappedResource MappedResource) string {
+		var values []string
+
+		for _, labels := range memberLabels(mappedResource) {
+			if value, ok := labels[key]; ok && value != "" {
+				values = append(values, value)
+			}
+		}
+
+		return smallestString(values)
+	})
+}
+
+//PreferAppNameLabel names mapped resource by 'app.kubernetes.io/name' label of its members.
+func PreferAppNameLabel() NamingStrategy {
+	return PreferLabel(AppNameLabel)
+}
+
+//PreferAppLabel names mapped resource by 'app' label of its members.
+func PreferAppLabel() NamingStrategy {
+	return PreferLabel(AppLabel)
+}
+
+//PreferServiceName names mapped resource by smallest name of its services.
+func PreferServiceName() NamingStrategy {
+	return NamingFunc(func(mappedResource MappedResource) string {
+		var names []string
+		for _, service := range mappedResource.Kube.Services {
+			names = append(names, service.Name)
+		}
+
+		return smallestString(names)
+	})
+}
+
+//PreferWorkloadName names mapped resource by smallest name of its deployments.
+//Without deployments, replica sets are used and then pods.
+func PreferWorkloadName() NamingStrategy {
+	return NamingFunc(func(mappedResource MappedResource) string {
+		var names []string
+		for _, deployment := range mappedResource.Kube.Deployments {
+			names = append(names, deployment.Name)
+		}
+		if len(names) > 0 {
+			return smallestString(names)
+		}
+
+		for _, replicaSet := range mappedResource.Kube.ReplicaSets {
+			names = append(names, replicaSet.Name)
+		}
+		if len(names) > 0 {
+			return smallestString(names)
+		}
+
+		for _, pod := range mappedResource.Kube.Pods {
+			names = append(names, pod.Name)
+		}
+
+		return smallestString(names)
+	})
+}
+
+//defaultCommonLabel returns smallest name of services, deployments, replica sets, pods and ingresses in that order.
+func defaultCommonLabel(mappedResource MappedResource) string {
+	if name := PreferServiceName().CommonLabel(mappedResource); name != "" {
+		return name
+	}
+
+	if name := PreferWorkloadName().CommonLabel(mappedResource); name != "" {
+		return name
+	}
+
+	var names []string
+	for _, ingress := range mappedResource.Kube.Ingresses {
+		names = append(names, ingress.Name)
+	}
+
+	return smallestString(names)
+}
+
+//memberLabels returns labels of every member of mapped resource.
+func memberLabels(mappedResource MappedResource) []map[string]string {
+	var labels []map[string]string
+
+	for _, ingress := range mappedResource.Kube.Ingresses {
+		labels = append(labels, ingress.Labels)
+	}
+
+	for _, service := range mappedResource.Kube.Services {
+		labels = append(labels, service.Labels)
+	}
+
+	for _, deployment := range mappedResource.Kube.Deployments {
+		labels = append(labels, deployment.Labels, deployment.Spec.Template.Labels)
+	}
+
+	for _, replicaSet := range mappedResource.Kube.ReplicaSets {
+		labels = append(labels, replicaSet.Labels)
+	}
+
+	for _, pod := range mappedResource.Kube.Pods {
+		labels = append(labels, pod.Labels)
+	}
+
+	return labels
+}
+
+func smallestString(values []string) string {
+	if len(values) == 0 {
+		return ""
+	}
+
+	sorted := append([]string{}, values...)
+	sort.Strings(sorted)
+
+	return sorted[0]
+}
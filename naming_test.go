@@ -0,0 +1,111 @@
+package kubemap
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	apps_v1 "k8s.io/api/apps/v1"
+	core_v1 "k8s.io/api/core/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestNamingStrategies(t *testing.T) {
+	mappedResource := MappedResource{
+		CommonLabel: "random-pod",
+		Kube: Kube{
+			Services: []core_v1.Service{
+				{ObjectMeta: meta_v1.ObjectMeta{Name: "web-svc", Labels: map[string]string{AppNameLabel: "shop"}}},
+				{ObjectMeta: meta_v1.ObjectMeta{Name: "api-svc", Labels: map[string]string{AppLabel: "shop-api"}}},
+			},
+			Deployments: []apps_v1.Deployment{
+				{ObjectMeta: meta_v1.ObjectMeta{Name: "web", Labels: map[string]string{AppLabel: "shop-web"}}},
+			},
+			Pods: []core_v1.Pod{
+				{ObjectMeta: meta_v1.ObjectMeta{Name: "web-abc", Labels: map[string]string{AppNameLabel: "shop"}}},
+			},
+		},
+	}
+
+	namingTests := map[string]struct {
+		naming      NamingStrategy
+		commonLabel string
+	}{
+		"With_App_Name_Label": {
+			naming:      PreferAppNameLabel(),
+			commonLabel: "shop",
+		},
+		"With_App_Label": {
+			naming:      PreferAppLabel(),
+			commonLabel: "shop-api",
+		},
+		"With_Service_Name": {
+			naming:      PreferServiceName(),
+			commonLabel: "api-svc",
+		},
+		"With_Workload_Name": {
+			naming:      PreferWorkloadName(),
+			commonLabel: "web",
+		},
+		"With_Missing_Label_Falls_Back_To_Default": {
+			naming:      PreferLabel("team"),
+			commonLabel: "api-svc",
+		},
+		"With_User_Function": {
+			naming: NamingFunc(func(mappedResource MappedResource) string {
+				return strings.ToUpper(mappedResource.Kube.Deployments[0].Name)
+			}),
+			commonLabel: "WEB",
+		},
+	}
+
+	for testName, test := range namingTests {
+		t.Run(testName, func(t *testing.T) {
+			mapper, _ := NewMapperWithOptions(MapOptions{
+				Naming: test.naming,
+			})
+
+			assert.Equal(t, test.commonLabel, mapper.finalizeMappedResource(mappedResource).CommonLabel)
+		})
+	}
+}
+
+func TestNamingIsIndependentOfEventOrder(t *testing.T) {
+	random := rand.New(rand.NewSource(31))
+
+	for i := 0; i < 20; i++ {
+		resources := helperGenerateTopology(random, 1+random.Intn(3))
+		events := resourceEventsForMapping(resources)
+		random.Shuffle(len(events), func(a, b int) {
+			events[a], events[b] = events[b], events[a]
+		})
+
+		report, err := CheckConsistency(events, MapOptions{Naming: PreferWorkloadName()})
+		assert.Nil(t, err)
+		assert.True(t, report.Consistent)
+
+		batchLabels := map[string]string{}
+		for _, mappedResource := range report.Batch.MappedResource {
+			batchLabels[groupSignature(mappedResource)] = mappedResource.CommonLabel
+		}
+
+		for _, mappedResource := range report.Incremental.MappedResource {
+			assert.Equal(t, batchLabels[groupSignature(mappedResource)], mappedResource.CommonLabel, "order %s", helperEventOrder(events))
+			assert.True(t, strings.HasPrefix(mappedResource.CommonLabel, "app-"))
+			assert.NotContains(t, mappedResource.CommonLabel[len("app-"):], "-")
+		}
+	}
+}
+
+func TestNamingIsReevaluatedOnMembershipChange(t *testing.T) {
+	resources := helperGetK8sResources()
+	mapper, _ := NewMapperWithOptions(MapOptions{Naming: PreferServiceName()})
+
+	results, _ := mapper.StoreMap(resourceEventsForMapping(KubeResources{Pods: resources.Pods})[0])
+	assert.Equal(t, resources.Pods[0].Name, results[0].MappedResource.CommonLabel)
+
+	results, _ = mapper.StoreMap(resourceEventsForMapping(KubeResources{Services: resources.Services})[0])
+	assert.Equal(t, resources.Services[0].Name, results[0].MappedResource.CommonLabel)
+}
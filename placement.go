@@ -0,0 +1,154 @@
+package kubemap
+
+import (
+	"fmt"
+	"sort"
+
+	core_v1 "k8s.io/api/core/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	//ZoneLabel is well known label of nodes naming their zone.
+	ZoneLabel = "topology.kubernetes.io/zone"
+	//LegacyZoneLabel is zone label of nodes of older clusters.
+	LegacyZoneLabel = "failure-domain.beta.kubernetes.io/zone"
+)
+
+//Placement is spread of pods of a group over nodes and zones. Zones are known only for nodes given as input.
+type Placement struct {
+	Nodes []NodePlacement `json:"nodes,omitempty"`
+	Zones []ZonePlacement `json:"zones,omitempty"`
+	//UnscheduledPods are pods not bound to a node yet.
+	UnscheduledPods int `json:"unscheduledPods,omitempty"`
+	//SingleNode is true if group has more than one scheduled pod and all of them run on one node.
+	SingleNode bool `json:"singleNode,omitempty"`
+	//SingleZone is true if group has more than one scheduled pod, zones of all their nodes are known and all of
+	//them run in one zone.
+	SingleZone bool `json:"singleZone,omitempty"`
+}
+
+//NodePlacement is number of pods of a group running on a node.
+type NodePlacement struct {
+	Node string `json:"node"`
+	//Zone is empty if node is not known or has no zone label.
+	Zone string `json:"zone,omitempty"`
+	Pods int    `json:"pods"`
+}
+
+//ZonePlacement is number of pods of a group running in a zone.
+type ZonePlacement struct {
+	Zone string `json:"zone"`
+	Pods int    `json:"pods"`
+}
+
+//nodeKind attaches nodes which pods of group run on and computes placement of group.
+var nodeKind = relatedKind{
+	clusterScoped: true,
+	indexField:    lookupNode,
+	normalize: func(obj interface{}) (interface{}, error) {
+		node, ok := obj.(*core_v1.Node)
+		if !ok {
+			return nil, fmt.Errorf("Object of type %T is not a Node", obj)
+		}
+		return nodeMetadata(node), nil
+	},
+	attach: attachNodes,
+	attached: func(mappedResource MappedResource) []interface{} {
+		var objects []interface{}
+		for i := range mappedResource.Kube.Nodes {
+			objects = append(objects, mappedResource.Kube.Nodes[i].DeepCopy())
+		}
+		return objects
+	},
+}
+
+//attachNodes attaches nodes of pods of group and sets placement of group, which is computed from node names of pods
+//even if no nodes are known.
+func attachNodes(mappedResource *MappedResource, related *relatedObjects) {
+	mappedResource.Kube.Nodes = nil
+	mappedResource.Kube.Placement = nil
+
+	placement := Placement{}
+	pods := make(map[string]int)
+	for _, pod := range mappedResource.Kube.Pods {
+		if pod.Status.Phase == core_v1.PodSucceeded || pod.Status.Phase == core_v1.PodFailed {
+			continue
+		}
+		if pod.Spec.NodeName == "" {
+			placement.UnscheduledPods++
+			continue
+		}
+		pods[pod.Spec.NodeName]++
+	}
+
+	if len(pods) == 0 && placement.UnscheduledPods == 0 {
+		return
+	}
+
+	scheduled, zonesKnown := 0, true
+	zones := make(map[string]int)
+	for nodeName, count := range pods {
+		nodePlacement := NodePlacement{Node: nodeName, Pods: count}
+		if obj, ok := related.get("node", "", nodeName); ok {
+			node := obj.(*core_v1.Node)
+			mappedResource.Kube.Nodes = append(mappedResource.Kube.Nodes, *node.DeepCopy())
+			nodePlacement.Zone = nodeZone(node.ObjectMeta)
+		}
+
+		if nodePlacement.Zone == "" {
+			zonesKnown = false
+		} else {
+			zones[nodePlacement.Zone] += count
+		}
+		scheduled += count
+		placement.Nodes = append(placement.Nodes, nodePlacement)
+	}
+
+	for zone, count := range zones {
+		placement.Zones = append(placement.Zones, ZonePlacement{Zone: zone, Pods: count})
+	}
+
+	sort.Slice(mappedResource.Kube.Nodes, func(i, j int) bool {
+		return mappedResource.Kube.Nodes[i].Name < mappedResource.Kube.Nodes[j].Name
+	})
+	sort.Slice(placement.Nodes, func(i, j int) bool {
+		return placement.Nodes[i].Node < placement.Nodes[j].Node
+	})
+	sort.Slice(placement.Zones, func(i, j int) bool {
+		return placement.Zones[i].Zone < placement.Zones[j].Zone
+	})
+
+	placement.SingleNode = scheduled > 1 && len(placement.Nodes) == 1
+	placement.SingleZone = scheduled > 1 && zonesKnown && len(placement.Zones) == 1
+	mappedResource.Kube.Placement = &placement
+}
+
+func nodeZone(objectMeta meta_v1.ObjectMeta) string {
+	if zone := objectMeta.Labels[ZoneLabel]; zone != "" {
+		return zone
+	}
+
+	return objectMeta.Labels[LegacyZoneLabel]
+}
+
+//nodeMetadata returns node with only its name, labels and whether it is cordoned. Status and resource version change
+//with every heartbeat of node, which would otherwise update every group placed on it.
+func nodeMetadata(node *core_v1.Node) *core_v1.Node {
+	metadata := &core_v1.Node{
+		ObjectMeta: meta_v1.ObjectMeta{
+			Name:              node.Name,
+			UID:               node.UID,
+			CreationTimestamp: node.CreationTimestamp,
+		},
+		Spec: core_v1.NodeSpec{Unschedulable: node.Spec.Unschedulable},
+	}
+	if node.Labels != nil {
+		metadata.Labels = make(map[string]string, len(node.Labels))
+		for key, value := range node.Labels {
+			metadata.Labels[key] = value
+		}
+	}
+
+	return metadata
+}
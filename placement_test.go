@@ -0,0 +1,241 @@
+package kubemap
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	core_v1 "k8s.io/api/core/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/cache"
+)
+
+func TestPlacement(t *testing.T) {
+	tests := []struct {
+		name       string
+		podNodes   []string
+		nodeZones  map[string]string
+		nodes      []NodePlacement
+		zones      []ZonePlacement
+		unassigned int
+		singleNode bool
+		singleZone bool
+	}{
+		{
+			name:      "spread over zones",
+			podNodes:  []string{"node-a", "node-b", "node-b"},
+			nodeZones: map[string]string{"node-a": "zone-1", "node-b": "zone-2"},
+			nodes:     []NodePlacement{{Node: "node-a", Zone: "zone-1", Pods: 1}, {Node: "node-b", Zone: "zone-2", Pods: 2}},
+			zones:     []ZonePlacement{{Zone: "zone-1", Pods: 1}, {Zone: "zone-2", Pods: 2}},
+		},
+		{
+			name:       "single node",
+			podNodes:   []string{"node-a", "node-a"},
+			nodeZones:  map[string]string{"node-a": "zone-1"},
+			nodes:      []NodePlacement{{Node: "node-a", Zone: "zone-1", Pods: 2}},
+			zones:      []ZonePlacement{{Zone: "zone-1", Pods: 2}},
+			singleNode: true,
+			singleZone: true,
+		},
+		{
+			name:       "single zone",
+			podNodes:   []string{"node-a", "node-b"},
+			nodeZones:  map[string]string{"node-a": "zone-1", "node-b": "zone-1"},
+			nodes:      []NodePlacement{{Node: "node-a", Zone: "zone-1", Pods: 1}, {Node: "node-b", Zone: "zone-1", Pods: 1}},
+			zones:      []ZonePlacement{{Zone: "zone-1", Pods: 2}},
+			singleZone: true,
+		},
+		{
+			name:      "zone of a node unknown",
+			podNodes:  []string{"node-a", "node-b"},
+			nodeZones: map[string]string{"node-a": "zone-1"},
+			nodes:     []NodePlacement{{Node: "node-a", Zone: "zone-1", Pods: 1}, {Node: "node-b", Pods: 1}},
+			zones:     []ZonePlacement{{Zone: "zone-1", Pods: 1}},
+		},
+		{
+			name:       "without nodes",
+			podNodes:   []string{"node-a", "node-a", ""},
+			nodes:      []NodePlacement{{Node: "node-a", Pods: 2}},
+			unassigned: 1,
+			singleNode: true,
+		},
+		{
+			name:     "single replica",
+			podNodes: []string{"node-a"},
+			nodes:    []NodePlacement{{Node: "node-a", Pods: 1}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mappedResources, err := NewMapper().Map(helperGetPlacementResources(tt.podNodes, tt.nodeZones))
+			assert.Nil(t, err)
+			assert.Len(t, mappedResources.MappedResource, 1)
+
+			placement := mappedResources.MappedResource[0].Kube.Placement
+			assert.NotNil(t, placement)
+			assert.Equal(t, tt.nodes, placement.Nodes)
+			assert.Equal(t, tt.zones, placement.Zones)
+			assert.Equal(t, tt.unassigned, placement.UnscheduledPods)
+			assert.Equal(t, tt.singleNode, placement.SingleNode)
+			assert.Equal(t, tt.singleZone, placement.SingleZone)
+			assert.Len(t, mappedResources.MappedResource[0].Kube.Nodes, len(tt.nodeZones))
+		})
+	}
+}
+
+func TestPlacementWithoutPods(t *testing.T) {
+	resources := helperGetK8sResources()
+	resources.Pods = nil
+
+	mappedResources, err := NewMapper().Map(resources)
+	assert.Nil(t, err)
+	assert.Nil(t, mappedResources.MappedResource[0].Kube.Placement)
+}
+
+func TestPlacementLegacyZoneLabel(t *testing.T) {
+	node := core_v1.Node{ObjectMeta: meta_v1.ObjectMeta{Labels: map[string]string{LegacyZoneLabel: "zone-1"}}}
+	assert.Equal(t, "zone-1", nodeZone(node.ObjectMeta))
+
+	node.Labels[ZoneLabel] = "zone-2"
+	assert.Equal(t, "zone-2", nodeZone(node.ObjectMeta))
+}
+
+func TestNodeEventUpdatesPlacement(t *testing.T) {
+	resources := helperGetPlacementResources([]string{"node-a", "node-b"}, nil)
+	store := cache.NewStore(metaResourceKeyFunc)
+	mapper := NewStoreMapper(store)
+
+	for _, event := range resourceEventsForMapping(resources) {
+		_, err := mapper.StoreMap(event)
+		assert.Nil(t, err)
+	}
+
+	for _, nodeName := range []string{"node-a", "node-b"} {
+		node := helperPlacementNode(nodeName, "zone-1")
+		node.ResourceVersion = "1"
+		node.Status.Images = []core_v1.ContainerImage{{Names: []string{"some/random/image"}}}
+
+		results, err := mapper.StoreMap(gerResourceEvent(node, "node"))
+		assert.Nil(t, err)
+		assert.Len(t, results, 1)
+	}
+
+	kube := getAllMappedResources(store).MappedResource[0].Kube
+	assert.Len(t, kube.Nodes, 2)
+	assert.Equal(t, core_v1.NodeStatus{}, kube.Nodes[0].Status)
+	assert.True(t, kube.Placement.SingleZone)
+
+	//Heartbeat of node does not update groups.
+	heartbeat := helperPlacementNode("node-a", "zone-1")
+	heartbeat.ResourceVersion = "2"
+	heartbeat.Status.Conditions = []core_v1.NodeCondition{{
+		Type:              core_v1.NodeReady,
+		Status:            core_v1.ConditionTrue,
+		LastHeartbeatTime: meta_v1.Now(),
+	}}
+	results, err := mapper.StoreMap(gerResourceEvent(heartbeat, "node"))
+	assert.Nil(t, err)
+	assert.Len(t, results, 1)
+	assert.False(t, results[0].IsMapped)
+
+	event := gerResourceEvent(helperPlacementNode("node-b", "zone-1"), "node")
+	event.EventType = "DELETED"
+	_, err = mapper.StoreMap(event)
+	assert.Nil(t, err)
+
+	kube = getAllMappedResources(store).MappedResource[0].Kube
+	assert.Len(t, kube.Nodes, 1)
+	assert.False(t, kube.Placement.SingleZone)
+}
+
+func TestNodeEventRefreshesOnlyGroupsOnNode(t *testing.T) {
+	resources := helperGetPlacementResources([]string{"node-a"}, nil)
+	mapper := NewMapper()
+	for _, event := range resourceEventsForMapping(resources) {
+		_, err := mapper.StoreMap(event)
+		assert.Nil(t, err)
+	}
+	_, err := mapper.StoreMap(gerResourceEvent(helperPlacementNode("node-x", ""), "node"))
+	assert.Nil(t, err)
+
+	//Group on other node whose attachments are out of date is left as it is.
+	other := helperGetPlacementResources([]string{"node-b"}, nil)
+	other.Pods[0].Namespace = "other"
+	stale := MappedResource{CommonLabel: "stale", Namespace: "other", Kube: Kube{Pods: other.Pods}}
+	assert.Nil(t, mapper.store.Add(stale))
+
+	results, err := mapper.StoreMap(gerResourceEvent(helperPlacementNode("node-a", "zone-1"), "node"))
+	assert.Nil(t, err)
+	assert.Len(t, results, 1)
+	assert.True(t, results[0].IsMapped)
+	assert.Len(t, results[0].MappedResource.Kube.Nodes, 1)
+
+	results, err = mapper.StoreMap(gerResourceEvent(helperPlacementNode("node-b", "zone-1"), "node"))
+	assert.Nil(t, err)
+	assert.Len(t, results, 1)
+	assert.True(t, results[0].IsMapped)
+	assert.Equal(t, "stale", results[0].MappedResource.CommonLabel)
+}
+
+func TestNodeHealth(t *testing.T) {
+	node := helperPlacementNode("node-a", "")
+	health, _ := nodeHealth(*node)
+	assert.Equal(t, HealthHealthy, health)
+
+	node.Spec.Unschedulable = true
+	health, reason := nodeHealth(*node)
+	assert.Equal(t, HealthDegraded, health)
+	assert.Equal(t, "Node is cordoned", reason)
+
+}
+
+func TestPlacementConsistency(t *testing.T) {
+	events := resourceEventsForMapping(helperGetPlacementResources([]string{"node-a", "node-b", "node-b"}, map[string]string{"node-a": "zone-1", "node-b": "zone-2"}))
+	random := rand.New(rand.NewSource(47))
+
+	for i := 0; i < 30; i++ {
+		random.Shuffle(len(events), func(a, b int) {
+			events[a], events[b] = events[b], events[a]
+		})
+
+		report, err := CheckConsistency(events, MapOptions{})
+		assert.Nil(t, err)
+		assert.True(t, report.Consistent, "order %s diverged - %+v", helperEventOrder(events), report.Divergences)
+	}
+}
+
+//helperGetPlacementResources returns fixtures with a pod for every node name, where empty name is an unscheduled pod,
+//and nodes labelled with given zones.
+func helperGetPlacementResources(podNodes []string, nodeZones map[string]string) KubeResources {
+	resources := helperGetK8sResources()
+
+	pod := resources.Pods[0]
+	resources.Pods = nil
+	for i, nodeName := range podNodes {
+		copied := pod.DeepCopy()
+		copied.Name = pod.Name + "-" + string(rune('a'+i))
+		copied.UID = types.UID(copied.Name)
+		copied.Spec.NodeName = nodeName
+		resources.Pods = append(resources.Pods, *copied)
+	}
+
+	for nodeName, zone := range nodeZones {
+		resources.Nodes = append(resources.Nodes, *helperPlacementNode(nodeName, zone))
+	}
+
+	return resources
+}
+
+func helperPlacementNode(name, zone string) *core_v1.Node {
+	node := &core_v1.Node{
+		ObjectMeta: meta_v1.ObjectMeta{Name: name, UID: types.UID(name)},
+	}
+	if zone != "" {
+		node.Labels = map[string]string{ZoneLabel: zone}
+	}
+
+	return node
+}
@@ -0,0 +1,269 @@
+package kubemap
+
+import (
+	"fmt"
+	"sort"
+
+	core_v1 "k8s.io/api/core/v1"
+	network_v1 "k8s.io/api/networking/v1"
+	policy_v1beta1 "k8s.io/api/policy/v1beta1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+const (
+	//FindingNoPodDisruptionBudget means no PodDisruptionBudget selects pods of group.
+	FindingNoPodDisruptionBudget = "NoPodDisruptionBudget"
+	//FindingZeroDisruptionsAllowed means a PodDisruptionBudget of group never allows voluntary disruption
+	//which blocks node drains.
+	FindingZeroDisruptionsAllowed = "ZeroDisruptionsAllowed"
+	//FindingNoIngressPolicy means no NetworkPolicy restricts ingress traffic of pods of group.
+	FindingNoIngressPolicy = "NoIngressPolicy"
+	//FindingNoEgressPolicy means no NetworkPolicy restricts egress traffic of pods of group.
+	FindingNoEgressPolicy = "NoEgressPolicy"
+)
+
+//PolicyCoverage summarises PodDisruptionBudgets and NetworkPolicies governing pods of a group.
+type PolicyCoverage struct {
+	CommonLabel            string   `json:"commonLabel,omitempty"`
+	Namespace              string   `json:"namespace,omitempty"`
+	PodDisruptionBudgets   []string `json:"podDisruptionBudgets,omitempty"`
+	IngressNetworkPolicies []string `json:"ingressNetworkPolicies,omitempty"`
+	EgressNetworkPolicies  []string `json:"egressNetworkPolicies,omitempty"`
+	//Findings are gaps in coverage e.g. 'NoPodDisruptionBudget'. Empty if group is fully covered.
+	Findings []string `json:"findings,omitempty"`
+}
+
+//podDisruptionBudgetKind attaches PodDisruptionBudgets whose selector matches pods or pod templates of group.
+var podDisruptionBudgetKind = relatedKind{
+	normalize: func(obj interface{}) (interface{}, error) {
+		pdb, ok := obj.(*policy_v1beta1.PodDisruptionBudget)
+		if !ok {
+			return nil, fmt.Errorf("Object of type %T is not a PodDisruptionBudget", obj)
+		}
+		return pdb.DeepCopy(), nil
+	},
+	attach: func(mappedResource *MappedResource, related *relatedObjects) {
+		mappedResource.Kube.PodDisruptionBudgets = nil
+		for _, obj := range related.list("pdb", mappedResource.Namespace) {
+			pdb := obj.(*policy_v1beta1.PodDisruptionBudget)
+			if !podDisruptionBudgetSelectsNothing(pdb) && selectorMatchesPods(pdb.Spec.Selector, *mappedResource) {
+				mappedResource.Kube.PodDisruptionBudgets = append(mappedResource.Kube.PodDisruptionBudgets, *pdb.DeepCopy())
+			}
+		}
+	},
+	attached: func(mappedResource MappedResource) []interface{} {
+		var objects []interface{}
+		for i := range mappedResource.Kube.PodDisruptionBudgets {
+			objects = append(objects, mappedResource.Kube.PodDisruptionBudgets[i].DeepCopy())
+		}
+		return objects
+	},
+}
+
+//networkPolicyKind attaches NetworkPolicies whose pod selector matches pods or pod templates of group.
+var networkPolicyKind = relatedKind{
+	normalize: func(obj interface{}) (interface{}, error) {
+		networkPolicy, ok := obj.(*network_v1.NetworkPolicy)
+		if !ok {
+			return nil, fmt.Errorf("Object of type %T is not a NetworkPolicy", obj)
+		}
+		return networkPolicy.DeepCopy(), nil
+	},
+	attach: func(mappedResource *MappedResource, related *relatedObjects) {
+		mappedResource.Kube.NetworkPolicies = nil
+		for _, obj := range related.list("networkpolicy", mappedResource.Namespace) {
+			networkPolicy := obj.(*network_v1.NetworkPolicy)
+			if selectorMatchesPods(&networkPolicy.Spec.PodSelector, *mappedResource) {
+				mappedResource.Kube.NetworkPolicies = append(mappedResource.Kube.NetworkPolicies, *networkPolicy.DeepCopy())
+			}
+		}
+	},
+	attached: func(mappedResource MappedResource) []interface{} {
+		var objects []interface{}
+		for i := range mappedResource.Kube.NetworkPolicies {
+			objects = append(objects, mappedResource.Kube.NetworkPolicies[i].DeepCopy())
+		}
+		return objects
+	},
+}
+
+//CoverageOfPolicies returns policy coverage of mapped resource.
+//Groups without pods or pod templates e.g. an ingress and its service are not governed by policies and have no findings.
+func CoverageOfPolicies(mappedResource MappedResource) PolicyCoverage {
+	coverage := PolicyCoverage{
+		CommonLabel: mappedResource.CommonLabel,
+		Namespace:   mappedResource.Namespace,
+	}
+
+	if len(podLabelSets(mappedResource)) == 0 {
+		return coverage
+	}
+
+	zeroDisruptions := false
+	for _, pdb := range mappedResource.Kube.PodDisruptionBudgets {
+		coverage.PodDisruptionBudgets = append(coverage.PodDisruptionBudgets, pdb.Name)
+		zeroDisruptions = zeroDisruptions || allowsZeroDisruptions(pdb, mappedResource)
+	}
+
+	for _, networkPolicy := range mappedResource.Kube.NetworkPolicies {
+		ingress, egress := networkPolicyTypes(networkPolicy)
+		if ingress {
+			coverage.IngressNetworkPolicies = append(coverage.IngressNetworkPolicies, networkPolicy.Name)
+		}
+		if egress {
+			coverage.EgressNetworkPolicies = append(coverage.EgressNetworkPolicies, networkPolicy.Name)
+		}
+	}
+
+	if len(coverage.PodDisruptionBudgets) == 0 {
+		coverage.Findings = append(coverage.Findings, FindingNoPodDisruptionBudget)
+	}
+	if zeroDisruptions {
+		coverage.Findings = append(coverage.Findings, FindingZeroDisruptionsAllowed)
+	}
+	if len(coverage.IngressNetworkPolicies) == 0 {
+		coverage.Findings = append(coverage.Findings, FindingNoIngressPolicy)
+	}
+	if len(coverage.EgressNetworkPolicies) == 0 {
+		coverage.Findings = append(coverage.Findings, FindingNoEgressPolicy)
+	}
+
+	return coverage
+}
+
+//PolicyCoverage returns policy coverage of every mapped resource in store which has pods or pod templates.
+func (m *Mapper) PolicyCoverage() []PolicyCoverage {
+	var coverages []PolicyCoverage
+
+	for _, mappedResource := range getAllMappedResources(m.store).MappedResource {
+		if len(podLabelSets(mappedResource)) == 0 {
+			continue
+		}
+		coverages = append(coverages, CoverageOfPolicies(mappedResource))
+	}
+
+	sort.Slice(coverages, func(i, j int) bool {
+		if coverages[i].Namespace != coverages[j].Namespace {
+			return coverages[i].Namespace < coverages[j].Namespace
+		}
+		return coverages[i].CommonLabel < coverages[j].CommonLabel
+	})
+
+	return coverages
+}
+
+//allowsZeroDisruptions checks if budget can never be satisfied while evicting a pod of group.
+//Observed status is preferred. Without it, budget is evaluated against desired replicas of group.
+func allowsZeroDisruptions(pdb policy_v1beta1.PodDisruptionBudget, mappedResource MappedResource) bool {
+	if pdb.Status.ObservedGeneration > 0 && pdb.Status.ExpectedPods > 0 {
+		return pdb.Status.PodDisruptionsAllowed == 0
+	}
+
+	replicas := desiredPods(mappedResource)
+
+	if pdb.Spec.MaxUnavailable != nil {
+		maxUnavailable, err := intstr.GetValueFromIntOrPercent(pdb.Spec.MaxUnavailable, replicas, true)
+		return err == nil && maxUnavailable == 0
+	}
+
+	if pdb.Spec.MinAvailable != nil {
+		minAvailable, err := intstr.GetValueFromIntOrPercent(pdb.Spec.MinAvailable, replicas, true)
+		return err == nil && minAvailable >= replicas
+	}
+
+	return false
+}
+
+//desiredPods returns desired replicas of deployments of group. Without deployments, number of pods is used.
+func desiredPods(mappedResource MappedResource) int {
+	replicas := 0
+	for _, deployment := range mappedResource.Kube.Deployments {
+		if deployment.Spec.Replicas != nil {
+			replicas += int(*deployment.Spec.Replicas)
+		} else {
+			replicas++
+		}
+	}
+
+	if len(mappedResource.Kube.Deployments) == 0 {
+		replicas = len(mappedResource.Kube.Pods)
+	}
+
+	return replicas
+}
+
+//networkPolicyTypes returns whether policy restricts ingress and egress. Without policy types, ingress is always
+//restricted and egress only if policy has egress rules.
+func networkPolicyTypes(networkPolicy network_v1.NetworkPolicy) (bool, bool) {
+	if len(networkPolicy.Spec.PolicyTypes) == 0 {
+		return true, len(networkPolicy.Spec.Egress) > 0
+	}
+
+	ingress, egress := false, false
+	for _, policyType := range networkPolicy.Spec.PolicyTypes {
+		switch policyType {
+		case network_v1.PolicyTypeIngress:
+			ingress = true
+		case network_v1.PolicyTypeEgress:
+			egress = true
+		}
+	}
+
+	return ingress, egress
+}
+
+//selectorMatchesPods checks if selector matches labels of any pod or pod template of mapped resource.
+func selectorMatchesPods(labelSelector *meta_v1.LabelSelector, mappedResource MappedResource) bool {
+	selector, err := meta_v1.LabelSelectorAsSelector(labelSelector)
+	if err != nil {
+		return false
+	}
+
+	for _, podLabels := range podLabelSets(mappedResource) {
+		if selector.Matches(labels.Set(podLabels)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+//podDisruptionBudgetSelectsNothing checks if PodDisruptionBudget selects no pod at all. Budgets without selector select
+//nothing. Empty selector selects nothing in policy/v1beta1 but every pod of namespace in policy/v1, which is told apart
+//by API version kept from manifests.
+func podDisruptionBudgetSelectsNothing(pdb *policy_v1beta1.PodDisruptionBudget) bool {
+	if pdb.Spec.Selector == nil {
+		return true
+	}
+
+	return pdb.APIVersion != "policy/v1" && isEmptySelector(pdb.Spec.Selector)
+}
+
+//isEmptySelector checks if label selector has neither match labels nor match expressions.
+func isEmptySelector(labelSelector *meta_v1.LabelSelector) bool {
+	return len(labelSelector.MatchLabels) == 0 && len(labelSelector.MatchExpressions) == 0
+}
+
+//podLabelSets returns labels of pods and pod templates of mapped resource.
+func podLabelSets(mappedResource MappedResource) []map[string]string {
+	var labelSets []map[string]string
+
+	for _, deployment := range mappedResource.Kube.Deployments {
+		labelSets = append(labelSets, deployment.Spec.Template.Labels)
+	}
+
+	for _, replicaSet := range mappedResource.Kube.ReplicaSets {
+		labelSets = append(labelSets, replicaSet.Spec.Template.Labels)
+	}
+
+	for _, pod := range mappedResource.Kube.Pods {
+		if pod.Status.Phase == core_v1.PodSucceeded || pod.Status.Phase == core_v1.PodFailed {
+			continue
+		}
+		labelSets = append(labelSets, pod.Labels)
+	}
+
+	return labelSets
+}
@@ -0,0 +1,170 @@
+package kubemap
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	network_v1 "k8s.io/api/networking/v1"
+	policy_v1beta1 "k8s.io/api/policy/v1beta1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+func TestPoliciesAreAttachedBySelector(t *testing.T) {
+	resources := helperGetPolicyResources()
+
+	mapper := NewMapper()
+	mappedResources, err := mapper.Map(resources)
+	assert.Nil(t, err)
+	assert.Len(t, mappedResources.MappedResource, 1)
+
+	kube := mappedResources.MappedResource[0].Kube
+	assert.Len(t, kube.PodDisruptionBudgets, 1)
+	assert.Equal(t, "app-pdb", kube.PodDisruptionBudgets[0].Name)
+	assert.Len(t, kube.NetworkPolicies, 2)
+	assert.Equal(t, "default-deny", kube.NetworkPolicies[0].Name)
+	assert.Equal(t, "egress-to-db", kube.NetworkPolicies[1].Name)
+
+	coverages := mapper.PolicyCoverage()
+	assert.Len(t, coverages, 1)
+	assert.Equal(t, []string{"app-pdb"}, coverages[0].PodDisruptionBudgets)
+	assert.Equal(t, []string{"default-deny"}, coverages[0].IngressNetworkPolicies)
+	assert.Equal(t, []string{"egress-to-db"}, coverages[0].EgressNetworkPolicies)
+	assert.Empty(t, coverages[0].Findings)
+}
+
+func TestEmptyBudgetSelectorSelectsNothing(t *testing.T) {
+	resources := helperGetK8sResources()
+	resources.PodDisruptionBudgets = []policy_v1beta1.PodDisruptionBudget{{
+		ObjectMeta: meta_v1.ObjectMeta{Name: "empty-selector-pdb", Namespace: resources.Pods[0].Namespace},
+		Spec:       policy_v1beta1.PodDisruptionBudgetSpec{Selector: &meta_v1.LabelSelector{}},
+	}}
+
+	mapper := NewMapper()
+	mappedResources, err := mapper.Map(resources)
+	assert.Nil(t, err)
+	assert.Empty(t, mappedResources.MappedResource[0].Kube.PodDisruptionBudgets)
+	assert.Contains(t, mapper.PolicyCoverage()[0].Findings, FindingNoPodDisruptionBudget)
+}
+
+func TestPolicyCoverageFindings(t *testing.T) {
+	resources := helperGetK8sResources()
+
+	mapper := NewMapper()
+	_, err := mapper.Map(resources)
+	assert.Nil(t, err)
+
+	coverages := mapper.PolicyCoverage()
+	assert.Len(t, coverages, 1)
+	assert.Equal(t, []string{FindingNoPodDisruptionBudget, FindingNoIngressPolicy, FindingNoEgressPolicy}, coverages[0].Findings)
+}
+
+func TestPolicyCoverageOfZeroDisruptionBudget(t *testing.T) {
+	budgetTests := map[string]struct {
+		spec   policy_v1beta1.PodDisruptionBudgetSpec
+		status policy_v1beta1.PodDisruptionBudgetStatus
+		zero   bool
+	}{
+		"Max_Unavailable_Zero": {
+			spec: policy_v1beta1.PodDisruptionBudgetSpec{MaxUnavailable: helperIntOrString(intstr.FromInt(0))},
+			zero: true,
+		},
+		"Min_Available_All": {
+			spec: policy_v1beta1.PodDisruptionBudgetSpec{MinAvailable: helperIntOrString(intstr.FromString("100%"))},
+			zero: true,
+		},
+		"Min_Available_Less_Than_Replicas": {
+			spec: policy_v1beta1.PodDisruptionBudgetSpec{MinAvailable: helperIntOrString(intstr.FromString("50%"))},
+			zero: false,
+		},
+		"Observed_Status": {
+			spec:   policy_v1beta1.PodDisruptionBudgetSpec{MaxUnavailable: helperIntOrString(intstr.FromInt(1))},
+			status: policy_v1beta1.PodDisruptionBudgetStatus{ObservedGeneration: 1, ExpectedPods: 3, PodDisruptionsAllowed: 0},
+			zero:   true,
+		},
+	}
+
+	replicas := int32(4)
+	mappedResource := helperMappedDeployment("shop", "api", nil)
+	mappedResource.Kube.Deployments[0].Spec.Replicas = &replicas
+
+	for testName, test := range budgetTests {
+		t.Run(testName, func(t *testing.T) {
+			pdb := policy_v1beta1.PodDisruptionBudget{Spec: test.spec, Status: test.status}
+			assert.Equal(t, test.zero, allowsZeroDisruptions(pdb, mappedResource))
+		})
+	}
+}
+
+func TestPoliciesConsistency(t *testing.T) {
+	events := resourceEventsForMapping(helperGetPolicyResources())
+	random := rand.New(rand.NewSource(38))
+
+	for i := 0; i < 30; i++ {
+		random.Shuffle(len(events), func(a, b int) {
+			events[a], events[b] = events[b], events[a]
+		})
+
+		report, err := CheckConsistency(events, MapOptions{})
+		assert.Nil(t, err)
+		assert.True(t, report.Consistent, "order %s diverged - %+v", helperEventOrder(events), report.Divergences)
+	}
+}
+
+//helperGetPolicyResources returns fixtures with a budget and an ingress policy selecting fixture pod,
+//an egress policy selecting all pods, a budget with empty selector selecting no pods and policies of other pods.
+func helperGetPolicyResources() KubeResources {
+	maxUnavailable := intstr.FromInt(1)
+	resources := helperGetK8sResources()
+	namespace, podLabels := resources.Pods[0].Namespace, resources.Pods[0].Labels
+
+	resources.PodDisruptionBudgets = []policy_v1beta1.PodDisruptionBudget{
+		{
+			ObjectMeta: meta_v1.ObjectMeta{Name: "app-pdb", Namespace: namespace},
+			Spec: policy_v1beta1.PodDisruptionBudgetSpec{
+				MaxUnavailable: &maxUnavailable,
+				Selector:       &meta_v1.LabelSelector{MatchLabels: podLabels},
+			},
+		},
+		{
+			ObjectMeta: meta_v1.ObjectMeta{Name: "empty-selector-pdb", Namespace: namespace},
+			Spec: policy_v1beta1.PodDisruptionBudgetSpec{
+				MaxUnavailable: &maxUnavailable,
+				Selector:       &meta_v1.LabelSelector{},
+			},
+		},
+		{
+			ObjectMeta: meta_v1.ObjectMeta{Name: "other-pdb", Namespace: namespace},
+			Spec: policy_v1beta1.PodDisruptionBudgetSpec{
+				MaxUnavailable: &maxUnavailable,
+				Selector:       &meta_v1.LabelSelector{MatchLabels: map[string]string{"app": "other"}},
+			},
+		},
+	}
+
+	resources.NetworkPolicies = []network_v1.NetworkPolicy{
+		{
+			ObjectMeta: meta_v1.ObjectMeta{Name: "default-deny", Namespace: namespace},
+			Spec: network_v1.NetworkPolicySpec{
+				PodSelector: meta_v1.LabelSelector{MatchLabels: podLabels},
+			},
+		},
+		{
+			ObjectMeta: meta_v1.ObjectMeta{Name: "egress-to-db", Namespace: namespace},
+			Spec: network_v1.NetworkPolicySpec{
+				PolicyTypes: []network_v1.PolicyType{network_v1.PolicyTypeEgress},
+			},
+		},
+		{
+			ObjectMeta: meta_v1.ObjectMeta{Name: "other-namespace", Namespace: "elsewhere"},
+		},
+	}
+
+	return resources
+}
+
+func helperIntOrString(value intstr.IntOrString) *intstr.IntOrString {
+	return &value
+}
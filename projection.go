@@ -0,0 +1,282 @@
+package kubemap
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+const (
+	//ProjectionFull keeps objects as they are received.
+	ProjectionFull = "full"
+	//ProjectionStandard drops bookkeeping fields like managed fields and last applied configuration.
+	ProjectionStandard = "standard"
+	//ProjectionMinimal additionally drops annotations except minimalAnnotations, scheduling constraints and container
+	//details except name, image, ports, resources, env and env from. Volumes, env and annotations which rollouts,
+	//releases, references and dependencies are read from are kept.
+	ProjectionMinimal = "minimal"
+)
+
+//standardProjection is list of field paths removed by ProjectionStandard.
+var standardProjection = []string{
+	"metadata.managedFields",
+	"metadata.annotations[kubectl.kubernetes.io/last-applied-configuration]",
+	"spec.template.metadata.annotations[kubectl.kubernetes.io/last-applied-configuration]",
+}
+
+//minimalProjection is list of field paths removed by ProjectionMinimal on top of standardProjection.
+var minimalProjection = []string{
+	"metadata.annotations",
+	"spec.template.metadata.annotations",
+	"spec.affinity",
+	"spec.tolerations",
+	"spec.template.spec.affinity",
+	"spec.template.spec.tolerations",
+}
+
+//minimalAnnotations are kept by ProjectionMinimal as rollouts and releases are read from them.
+var minimalAnnotations = []string{
+	DeploymentRevisionAnnotation,
+	HelmReleaseNameAnnotation,
+	HelmReleaseNamespaceAnnotation,
+	ArgoCDInstanceKey,
+	ArgoCDTrackingIDAnnotation,
+}
+
+//minimalContainerFields are removed from containers and init containers of pods and pod templates by ProjectionMinimal.
+var minimalContainerFields = []string{
+	"command",
+	"args",
+	"livenessProbe",
+	"readinessProbe",
+	"lifecycle",
+	"securityContext",
+	"volumeMounts",
+	"volumeDevices",
+}
+
+//projection removes denied field paths from objects before they are mapped.
+type projection struct {
+	deny  [][]string
+	allow [][]string
+}
+
+//newProjection validates options and returns nil if objects are to be kept as they are.
+func newProjection(options ProjectionOptions) (*projection, error) {
+	var denyPaths, allowPaths []string
+
+	switch strings.ToLower(options.Preset) {
+	case "", ProjectionFull:
+	case ProjectionStandard:
+		denyPaths = append(denyPaths, standardProjection...)
+	case ProjectionMinimal:
+		denyPaths = append(denyPaths, standardProjection...)
+		denyPaths = append(denyPaths, minimalProjection...)
+		for _, containers := range []string{"spec.containers", "spec.initContainers", "spec.template.spec.containers", "spec.template.spec.initContainers"} {
+			for _, field := range minimalContainerFields {
+				denyPaths = append(denyPaths, containers+"."+field)
+			}
+		}
+		for _, annotation := range minimalAnnotations {
+			allowPaths = append(allowPaths, "metadata.annotations["+annotation+"]")
+		}
+	default:
+		return nil, fmt.Errorf("Cannot instantiate Mapper. Invalid projection preset %s provided. Accepted values are '%s', '%s' & '%s'", options.Preset, ProjectionFull, ProjectionStandard, ProjectionMinimal)
+	}
+	denyPaths = append(denyPaths, options.Deny...)
+
+	if len(denyPaths) == 0 {
+		return nil, nil
+	}
+
+	p := &projection{}
+	for _, path := range denyPaths {
+		segments, err := parseFieldPath(path)
+		if err != nil {
+			return nil, err
+		}
+		p.deny = append(p.deny, segments)
+	}
+
+	for _, path := range append(allowPaths, options.Allow...) {
+		segments, err := parseFieldPath(path)
+		if err != nil {
+			return nil, err
+		}
+		p.allow = append(p.allow, segments)
+	}
+
+	return p, nil
+}
+
+//parseFieldPath splits path like 'metadata.annotations[example.com/key]' into its segments.
+func parseFieldPath(path string) ([]string, error) {
+	var segments []string
+	var current strings.Builder
+	inBracket := false
+
+	for _, char := range path {
+		switch {
+		case char == '[' && !inBracket:
+			if current.Len() > 0 {
+				segments = append(segments, current.String())
+				current.Reset()
+			}
+			inBracket = true
+		case char == ']' && inBracket:
+			segments = append(segments, current.String())
+			current.Reset()
+			inBracket = false
+		case char == '.' && !inBracket:
+			if current.Len() > 0 {
+				segments = append(segments, current.String())
+				current.Reset()
+			}
+		default:
+			current.WriteRune(char)
+		}
+	}
+
+	if inBracket {
+		return nil, fmt.Errorf("Invalid field path %s. Missing ']'", path)
+	}
+	if current.Len() > 0 {
+		segments = append(segments, current.String())
+	}
+	if len(segments) == 0 {
+		return nil, fmt.Errorf("Invalid field path %q", path)
+	}
+
+	return segments, nil
+}
+
+//project returns copy of typed k8s object with denied fields removed.
+func (p *projection) project(obj interface{}) (interface{}, error) {
+	if p == nil || obj == nil {
+		return obj, nil
+	}
+
+	//Unstructured objects e.g. custom resources are copied as is and their copy is projected. Object passed is never
+	//modified.
+	if object, ok := obj.(*unstructured.Unstructured); ok {
+		projected := object.DeepCopy()
+		p.removeDenied(projected.Object)
+		return projected, nil
+	}
+
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+	if err != nil {
+		return obj, err
+	}
+
+	p.removeDenied(content)
+
+	projected := reflect.New(reflect.TypeOf(obj).Elem()).Interface()
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(content, projected); err != nil {
+		return obj, err
+	}
+
+	return projected, nil
+}
+
+//removeDenied removes denied field paths which are not allowed from content.
+func (p *projection) removeDenied(content map[string]interface{}) {
+	for _, denyPath := range p.deny {
+		if p.isAllowed(denyPath) {
+			continue
+		}
+		removeFieldPath(content, denyPath, p.allowedUnder(denyPath))
+	}
+}
+
+//isAllowed checks if path or any of its parents is explicitly allowed.
+func (p *projection) isAllowed(path []string) bool {
+	for _, allowPath := range p.allow {
+		if len(allowPath) <= len(path) && reflect.DeepEqual(allowPath, path[:len(allowPath)]) {
+			return true
+		}
+	}
+
+	return false
+}
+
+//allowedUnder returns allowed paths nested in path, relative to it.
+func (p *projection) allowedUnder(path []string) [][]string {
+	var nested [][]string
+	for _, allowPath := range p.allow {
+		if len(allowPath) > len(path) && reflect.DeepEqual(allowPath[:len(path)], path) {
+			nested = append(nested, allowPath[len(path):])
+		}
+	}
+
+	return nested
+}
+
+//removeFieldPath deletes field at path. Lists on the way are traversed element wise.
+//If keep is set, field is reduced to just those nested paths instead of being deleted.
+func removeFieldPath(value interface{}, path []string, keep [][]string) {
+	switch typedValue := value.(type) {
+	case []interface{}:
+		for _, item := range typedValue {
+			removeFieldPath(item, path, keep)
+		}
+	case map[string]interface{}:
+		field, exists := typedValue[path[0]]
+		if !exists {
+			return
+		}
+
+		if len(path) > 1 {
+			removeFieldPath(field, path[1:], keep)
+			return
+		}
+
+		if len(keep) == 0 {
+			delete(typedValue, path[0])
+			return
+		}
+
+		typedValue[path[0]] = keepFieldPaths(field, keep)
+	}
+}
+
+//keepFieldPaths returns copy of value having only given paths.
+func keepFieldPaths(value interface{}, keep [][]string) interface{} {
+	switch typedValue := value.(type) {
+	case []interface{}:
+		var kept []interface{}
+		for _, item := range typedValue {
+			kept = append(kept, keepFieldPaths(item, keep))
+		}
+		return kept
+	case map[string]interface{}:
+		kept := make(map[string]interface{})
+		for _, keepPath := range keep {
+			field, exists := typedValue[keepPath[0]]
+			if !exists {
+				continue
+			}
+
+			if len(keepPath) == 1 {
+				kept[keepPath[0]] = field
+				continue
+			}
+
+			var nested [][]string
+			for _, otherPath := range keep {
+				if len(otherPath) > 1 && otherPath[0] == keepPath[0] {
+					nested = append(nested, otherPath[1:])
+				}
+			}
+			if _, done := kept[keepPath[0]]; !done {
+				kept[keepPath[0]] = keepFieldPaths(field, nested)
+			}
+		}
+		return kept
+	}
+
+	return value
+}
@@ -0,0 +1,263 @@
+package kubemap
+
+import (
+	"encoding/json"
+	"fmt"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	core_v1 "k8s.io/api/core/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestParseFieldPath(t *testing.T) {
+	parseFieldPathTests := map[string]struct {
+		path     string
+		segments []string
+		isValid  bool
+	}{
+		"With_Dotted_Path": {
+			path:     "spec.containers.env",
+			segments: []string{"spec", "containers", "env"},
+			isValid:  true,
+		},
+		"With_Bracketed_Key": {
+			path:     "metadata.annotations[kubectl.kubernetes.io/last-applied-configuration]",
+			segments: []string{"metadata", "annotations", "kubectl.kubernetes.io/last-applied-configuration"},
+			isValid:  true,
+		},
+		"With_Unclosed_Bracket": {
+			path:    "metadata.annotations[example.com/key",
+			isValid: false,
+		},
+		"With_Empty_Path": {
+			path:    "",
+			isValid: false,
+		},
+	}
+
+	for testName, test := range parseFieldPathTests {
+		t.Run(testName, func(t *testing.T) {
+			segments, err := parseFieldPath(test.path)
+			if test.isValid {
+				assert.Nil(t, err)
+				assert.Equal(t, test.segments, segments)
+			} else {
+				assert.NotNil(t, err)
+			}
+		})
+	}
+}
+
+func TestNewProjectionWithInvalidPreset(t *testing.T) {
+	mapper, err := NewMapperWithOptions(MapOptions{
+		Projection: ProjectionOptions{
+			Preset: "tiny",
+		},
+	})
+
+	assert.Nil(t, mapper)
+	assert.NotNil(t, err)
+}
+
+func TestProjectionPresets(t *testing.T) {
+	pod := helperGetBloatedPod("bloated")
+
+	projectionTests := map[string]struct {
+		options              ProjectionOptions
+		keepsManagedFields   bool
+		keepsLastApplied     bool
+		keepsOtherAnnotation bool
+		keepsEnv             bool
+	}{
+		"With_Full_Preset": {
+			options:              ProjectionOptions{Preset: ProjectionFull},
+			keepsManagedFields:   true,
+			keepsLastApplied:     true,
+			keepsOtherAnnotation: true,
+			keepsEnv:             true,
+		},
+		"With_Standard_Preset": {
+			options:              ProjectionOptions{Preset: ProjectionStandard},
+			keepsOtherAnnotation: true,
+			keepsEnv:             true,
+		},
+		"With_Minimal_Preset": {
+			options:  ProjectionOptions{Preset: ProjectionMinimal},
+			keepsEnv: true,
+		},
+		"With_Minimal_Preset_And_Denied_Env": {
+			options: ProjectionOptions{Preset: ProjectionMinimal, Deny: []string{"spec.containers.env"}},
+		},
+		"With_Minimal_Preset_And_Allowed_Annotation": {
+			options:              ProjectionOptions{Preset: ProjectionMinimal, Allow: []string{"metadata.annotations[example.com/owner]"}},
+			keepsOtherAnnotation: true,
+			keepsEnv:             true,
+		},
+		"With_Custom_Deny_List": {
+			options:              ProjectionOptions{Deny: []string{"metadata.annotations[kubectl.kubernetes.io/last-applied-configuration]", "spec.containers.env"}},
+			keepsManagedFields:   true,
+			keepsOtherAnnotation: true,
+		},
+	}
+
+	for testName, test := range projectionTests {
+		t.Run(testName, func(t *testing.T) {
+			p, err := newProjection(test.options)
+			assert.Nil(t, err)
+
+			projected, err := p.project(pod.DeepCopy())
+			assert.Nil(t, err)
+
+			projectedPod := projected.(*core_v1.Pod)
+			assert.Equal(t, pod.Name, projectedPod.Name)
+			assert.Equal(t, pod.Labels, projectedPod.Labels)
+			assert.Equal(t, pod.Spec.Containers[0].Image, projectedPod.Spec.Containers[0].Image)
+			assert.Equal(t, test.keepsManagedFields, len(projectedPod.ManagedFields) > 0)
+			_, hasLastApplied := projectedPod.Annotations["kubectl.kubernetes.io/last-applied-configuration"]
+			assert.Equal(t, test.keepsLastApplied, hasLastApplied)
+			_, hasOtherAnnotation := projectedPod.Annotations["example.com/owner"]
+			assert.Equal(t, test.keepsOtherAnnotation, hasOtherAnnotation)
+			assert.Equal(t, test.keepsEnv, len(projectedPod.Spec.Containers[0].Env) > 0)
+		})
+	}
+}
+
+func TestMinimalProjectionKeepsRolloutsAndReleases(t *testing.T) {
+	mapper, err := NewMapperWithOptions(MapOptions{Projection: ProjectionOptions{Preset: ProjectionMinimal}})
+	assert.Nil(t, err)
+
+	resources := helperGetRolloutResources()
+	resources.Deployments[0].Annotations[HelmReleaseNameAnnotation] = "suite"
+	resources.Deployments[0].Annotations["example.com/owner"] = "team-a"
+
+	mappedResources, err := mapper.Map(resources)
+	assert.Nil(t, err)
+	assert.Len(t, mappedResources.MappedResource, 1)
+
+	mappedResource := mappedResources.MappedResource[0]
+	assert.Equal(t, map[string]string{DeploymentRevisionAnnotation: "2", HelmReleaseNameAnnotation: "suite"}, mappedResource.Kube.Deployments[0].Annotations)
+	assert.Len(t, mappedResource.Rollouts, 1)
+	assert.Equal(t, int64(2), mappedResource.Rollouts[0].CurrentRevision)
+	assert.Equal(t, int64(1), mappedResource.Rollouts[0].PreviousRevision)
+
+	releases := mapper.Releases()
+	assert.Len(t, releases, 1)
+	assert.Equal(t, ReleaseManagerHelm, releases[0].Manager)
+	assert.Equal(t, "suite", releases[0].Name)
+}
+
+func TestProjectionDoesNotModifyObject(t *testing.T) {
+	p, err := newProjection(ProjectionOptions{Preset: ProjectionMinimal})
+	assert.Nil(t, err)
+
+	pod := helperGetBloatedPod("bloated")
+	projected, err := p.project(&pod)
+	assert.Nil(t, err)
+	assert.Empty(t, projected.(*core_v1.Pod).ManagedFields)
+	assert.Equal(t, helperGetBloatedPod("bloated"), pod)
+
+	rollout := helperCustomResource("argoproj.io/v1alpha1", "Rollout", "api", "shop", map[string]interface{}{
+		"spec": map[string]interface{}{"affinity": map[string]interface{}{}},
+	})
+	original := rollout.DeepCopy()
+	projected, err = p.project(&rollout)
+	assert.Nil(t, err)
+	assert.NotContains(t, projected.(*unstructured.Unstructured).Object["spec"], "affinity")
+	assert.Equal(t, original, &rollout)
+}
+
+func TestKeepFieldPaths(t *testing.T) {
+	value := map[string]interface{}{
+		"name":  "app",
+		"image": "app:1",
+		"env":   []interface{}{map[string]interface{}{"name": "A", "value": "1"}},
+	}
+
+	kept := keepFieldPaths(value, [][]string{{"image"}, {"env", "name"}})
+	assert.Equal(t, map[string]interface{}{
+		"image": "app:1",
+		"env":   []interface{}{map[string]interface{}{"name": "A"}},
+	}, kept)
+}
+
+func TestMapperAppliesProjection(t *testing.T) {
+	mapper, _ := NewMapperWithOptions(MapOptions{
+		Projection: ProjectionOptions{
+			Preset: ProjectionStandard,
+		},
+	})
+
+	resources := helperGetK8sResources()
+	resources.Pods = []core_v1.Pod{helperGetBloatedPod(resources.Pods[0].Name)}
+
+	mappedResources, _ := mapper.Map(resources)
+	assert.Len(t, mappedResources.MappedResource, 1)
+	assert.Len(t, mappedResources.MappedResource[0].Kube.Pods, 1)
+	assert.Empty(t, mappedResources.MappedResource[0].Kube.Pods[0].ManagedFields)
+}
+
+func BenchmarkMapWithProjection(b *testing.B) {
+	for _, preset := range []string{ProjectionFull, ProjectionStandard, ProjectionMinimal} {
+		b.Run(preset, func(b *testing.B) {
+			resources := helperGetK8sResources()
+			resources.Pods = nil
+			for i := 0; i < 50; i++ {
+				resources.Pods = append(resources.Pods, helperGetBloatedPod(fmt.Sprintf("kube-map-644c5c58fc-%d", i)))
+			}
+
+			options := MapOptions{
+				Projection: ProjectionOptions{
+					Preset: preset,
+				},
+			}
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				mapper, _ := NewMapperWithOptions(options)
+				mapper.Map(resources)
+			}
+			b.StopTimer()
+
+			//Store keeps objects unredacted, so it is measured rather than redacted output of Map.
+			var before, after runtime.MemStats
+			runtime.GC()
+			runtime.ReadMemStats(&before)
+			mapper, _ := NewMapperWithOptions(options)
+			mapper.Map(resources)
+			runtime.GC()
+			runtime.ReadMemStats(&after)
+
+			content, _ := json.Marshal(getAllMappedResources(mapper.store))
+			b.ReportMetric(float64(len(content)), "stored-bytes")
+			b.ReportMetric(float64(int64(after.HeapAlloc)-int64(before.HeapAlloc)), "heap-bytes")
+			runtime.KeepAlive(mapper)
+		})
+	}
+}
+
+//helperGetBloatedPod returns fixture pod with fields which are typically present in live clusters.
+func helperGetBloatedPod(name string) core_v1.Pod {
+	pod := helperGetK8sResources().Pods[0]
+	pod.Name = name
+
+	lastApplied, _ := json.Marshal(pod)
+	pod.Annotations = map[string]string{
+		"kubectl.kubernetes.io/last-applied-configuration": string(lastApplied),
+		"example.com/owner": "team-a",
+	}
+	pod.ManagedFields = []meta_v1.ManagedFieldsEntry{{
+		Manager:   "kube-controller-manager",
+		Operation: meta_v1.ManagedFieldsOperationUpdate,
+	}}
+	pod.Spec.Containers[0].Env = []core_v1.EnvVar{
+		{Name: "DB_HOST", Value: "db.test-namespace.svc.cluster.local"},
+		{Name: "DB_PASSWORD", Value: "hunter2"},
+	}
+
+	return pod
+}
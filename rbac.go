@@ -0,0 +1,364 @@
+package kubemap
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	core_v1 "k8s.io/api/core/v1"
+	rbac_v1 "k8s.io/api/rbac/v1"
+)
+
+//clusterAdminRole is name of built-in ClusterRole granting every permission.
+const clusterAdminRole = "cluster-admin"
+
+//Access is what pods of a group can do in the cluster through their service accounts.
+type Access struct {
+	ServiceAccounts []string     `json:"serviceAccounts,omitempty"`
+	Permissions     []Permission `json:"permissions,omitempty"`
+	//ClusterAdminLike is set if group is granted every verb on every resource cluster wide.
+	ClusterAdminLike bool `json:"clusterAdminLike,omitempty"`
+}
+
+//Permission is set of verbs allowed on a resource.
+type Permission struct {
+	//Namespace where permission applies. Empty for cluster wide permissions.
+	Namespace string `json:"namespace,omitempty"`
+	APIGroup  string `json:"apiGroup,omitempty"`
+	//Resource is a resource e.g. 'pods' or 'deployments/scale', or a non resource URL e.g. '/healthz'.
+	Resource string `json:"resource"`
+	//ResourceNames restricts permission to objects of given names. Empty if every object of resource is allowed.
+	ResourceNames []string `json:"resourceNames,omitempty"`
+	Verbs         []string `json:"verbs"`
+}
+
+//RBAC kinds are attached by attachAccess of service accounts, which resolves service accounts, bindings and roles
+//of group and their access at once. Hence other RBAC kinds have no attach of their own.
+var (
+	serviceAccountKind = relatedKind{
+		normalize: func(obj interface{}) (interface{}, error) {
+			serviceAccount, ok := obj.(*core_v1.ServiceAccount)
+			if !ok {
+				return nil, fmt.Errorf("Object of type %T is not a ServiceAccount", obj)
+			}
+			return serviceAccount.DeepCopy(), nil
+		},
+		attach: attachAccess,
+		attached: func(mappedResource MappedResource) []interface{} {
+			var objects []interface{}
+			for i := range mappedResource.Kube.ServiceAccounts {
+				objects = append(objects, mappedResource.Kube.ServiceAccounts[i].DeepCopy())
+			}
+			return objects
+		},
+	}
+
+	roleKind = relatedKind{
+		normalize: func(obj interface{}) (interface{}, error) {
+			role, ok := obj.(*rbac_v1.Role)
+			if !ok {
+				return nil, fmt.Errorf("Object of type %T is not a Role", obj)
+			}
+			return role.DeepCopy(), nil
+		},
+		attached: func(mappedResource MappedResource) []interface{} {
+			var objects []interface{}
+			for i := range mappedResource.Kube.Roles {
+				objects = append(objects, mappedResource.Kube.Roles[i].DeepCopy())
+			}
+			return objects
+		},
+	}
+
+	roleBindingKind = relatedKind{
+		normalize: func(obj interface{}) (interface{}, error) {
+			roleBinding, ok := obj.(*rbac_v1.RoleBinding)
+			if !ok {
+				return nil, fmt.Errorf("Object of type %T is not a RoleBinding", obj)
+			}
+			return roleBinding.DeepCopy(), nil
+		},
+		attached: func(mappedResource MappedResource) []interface{} {
+			var objects []interface{}
+			for i := range mappedResource.Kube.RoleBindings {
+				objects = append(objects, mappedResource.Kube.RoleBindings[i].DeepCopy())
+			}
+			return objects
+		},
+	}
+
+	clusterRoleKind = relatedKind{
+		clusterScoped: true,
+		normalize: func(obj interface{}) (interface{}, error) {
+			clusterRole, ok := obj.(*rbac_v1.ClusterRole)
+			if !ok {
+				return nil, fmt.Errorf("Object of type %T is not a ClusterRole", obj)
+			}
+			return clusterRole.DeepCopy(), nil
+		},
+		attached: func(mappedResource MappedResource) []interface{} {
+			var objects []interface{}
+			for i := range mappedResource.Kube.ClusterRoles {
+				objects = append(objects, mappedResource.Kube.ClusterRoles[i].DeepCopy())
+			}
+			return objects
+		},
+	}
+
+	clusterRoleBindingKind = relatedKind{
+		clusterScoped: true,
+		normalize: func(obj interface{}) (interface{}, error) {
+			clusterRoleBinding, ok := obj.(*rbac_v1.ClusterRoleBinding)
+			if !ok {
+				return nil, fmt.Errorf("Object of type %T is not a ClusterRoleBinding", obj)
+			}
+			return clusterRoleBinding.DeepCopy(), nil
+		},
+		attached: func(mappedResource MappedResource) []interface{} {
+			var objects []interface{}
+			for i := range mappedResource.Kube.ClusterRoleBindings {
+				objects = append(objects, mappedResource.Kube.ClusterRoleBindings[i].DeepCopy())
+			}
+			return objects
+		},
+	}
+)
+
+//attachAccess attaches service accounts of pods and pod templates of group, bindings naming those service accounts,
+//roles of those bindings and summary of permissions they grant.
+func attachAccess(mappedResource *MappedResource, related *relatedObjects) {
+	kube := &mappedResource.Kube
+	kube.ServiceAccounts, kube.RoleBindings, kube.ClusterRoleBindings, kube.Roles, kube.ClusterRoles = nil, nil, nil, nil, nil
+	kube.Access = nil
+
+	serviceAccountNames := podServiceAccounts(*mappedResource)
+	if len(serviceAccountNames) == 0 {
+		return
+	}
+
+	access := &Access{ServiceAccounts: serviceAccountNames}
+	permissions := make(map[permissionKey]map[string]bool)
+	clusterRoles := make(map[string]bool)
+
+	for _, name := range serviceAccountNames {
+		if obj, ok := related.get("serviceaccount", mappedResource.Namespace, name); ok {
+			kube.ServiceAccounts = append(kube.ServiceAccounts, *obj.(*core_v1.ServiceAccount).DeepCopy())
+		}
+	}
+
+	for _, obj := range related.list("rolebinding", mappedResource.Namespace) {
+		roleBinding := obj.(*rbac_v1.RoleBinding)
+		if !subjectsInclude(roleBinding.Subjects, roleBinding.Namespace, mappedResource.Namespace, serviceAccountNames) {
+			continue
+		}
+		kube.RoleBindings = append(kube.RoleBindings, *roleBinding.DeepCopy())
+
+		var rules []rbac_v1.PolicyRule
+		switch roleBinding.RoleRef.Kind {
+		case "Role":
+			if obj, ok := related.get("role", roleBinding.Namespace, roleBinding.RoleRef.Name); ok {
+				role := obj.(*rbac_v1.Role)
+				kube.Roles = append(kube.Roles, *role.DeepCopy())
+				rules = role.Rules
+			}
+		case "ClusterRole":
+			rules = clusterRoleRules(related, roleBinding.RoleRef.Name, clusterRoles)
+		}
+		addPermissions(permissions, roleBinding.Namespace, rules)
+	}
+
+	for _, obj := range related.list("clusterrolebinding", "") {
+		clusterRoleBinding := obj.(*rbac_v1.ClusterRoleBinding)
+		if !subjectsInclude(clusterRoleBinding.Subjects, "", mappedResource.Namespace, serviceAccountNames) {
+			continue
+		}
+		kube.ClusterRoleBindings = append(kube.ClusterRoleBindings, *clusterRoleBinding.DeepCopy())
+
+		if clusterRoleBinding.RoleRef.Name == clusterAdminRole {
+			access.ClusterAdminLike = true
+		}
+
+		rules := clusterRoleRules(related, clusterRoleBinding.RoleRef.Name, clusterRoles)
+		if grantsEverything(rules) {
+			access.ClusterAdminLike = true
+		}
+		addPermissions(permissions, "", rules)
+	}
+
+	var clusterRoleNames []string
+	for name := range clusterRoles {
+		clusterRoleNames = append(clusterRoleNames, name)
+	}
+	sort.Strings(clusterRoleNames)
+	for _, name := range clusterRoleNames {
+		obj, _ := related.get("clusterrole", "", name)
+		kube.ClusterRoles = append(kube.ClusterRoles, *obj.(*rbac_v1.ClusterRole).DeepCopy())
+	}
+
+	for key, verbs := range permissions {
+		permission := Permission{Namespace: key.namespace, APIGroup: key.apiGroup, Resource: key.resource}
+		if key.resourceNames != "" {
+			permission.ResourceNames = strings.Split(key.resourceNames, ",")
+		}
+		for verb := range verbs {
+			permission.Verbs = append(permission.Verbs, verb)
+		}
+		sort.Strings(permission.Verbs)
+		access.Permissions = append(access.Permissions, permission)
+	}
+	sort.Slice(access.Permissions, func(i, j int) bool {
+		a, b := access.Permissions[i], access.Permissions[j]
+		if a.Namespace != b.Namespace {
+			return a.Namespace < b.Namespace
+		}
+		if a.APIGroup != b.APIGroup {
+			return a.APIGroup < b.APIGroup
+		}
+		if a.Resource != b.Resource {
+			return a.Resource < b.Resource
+		}
+		return strings.Join(a.ResourceNames, ",") < strings.Join(b.ResourceNames, ",")
+	})
+
+	kube.Access = access
+}
+
+//clusterRoleRules returns rules of cluster role and records it as attached if it exists.
+func clusterRoleRules(related *relatedObjects, name string, clusterRoles map[string]bool) []rbac_v1.PolicyRule {
+	obj, ok := related.get("clusterrole", "", name)
+	if !ok {
+		return nil
+	}
+	clusterRoles[name] = true
+
+	return obj.(*rbac_v1.ClusterRole).Rules
+}
+
+//permissionKey identifies a Permission whose verbs are being collected. Resource names are sorted and joined by
+//commas, which names of objects cannot contain.
+type permissionKey struct {
+	namespace, apiGroup, resource, resourceNames string
+}
+
+//addPermissions adds verbs of every resource and non resource URL of rules. Permissions of rules restricted to
+//named objects are kept apart from permissions on every object of resource.
+func addPermissions(permissions map[permissionKey]map[string]bool, namespace string, rules []rbac_v1.PolicyRule) {
+	add := func(permission permissionKey, verbs []string) {
+		if permissions[permission] == nil {
+			permissions[permission] = make(map[string]bool)
+		}
+		for _, verb := range verbs {
+			permissions[permission][verb] = true
+		}
+	}
+
+	for _, rule := range rules {
+		apiGroups := rule.APIGroups
+		if len(apiGroups) == 0 {
+			apiGroups = []string{""}
+		}
+
+		resourceNames := append([]string(nil), rule.ResourceNames...)
+		sort.Strings(resourceNames)
+
+		for _, apiGroup := range apiGroups {
+			for _, resource := range rule.Resources {
+				add(permissionKey{namespace: namespace, apiGroup: apiGroup, resource: resource, resourceNames: strings.Join(resourceNames, ",")}, rule.Verbs)
+			}
+		}
+
+		for _, url := range rule.NonResourceURLs {
+			add(permissionKey{namespace: namespace, resource: url}, rule.Verbs)
+		}
+	}
+}
+
+//grantsEverything checks if any rule allows every verb on every object of every resource of every api group.
+func grantsEverything(rules []rbac_v1.PolicyRule) bool {
+	for _, rule := range rules {
+		if len(rule.ResourceNames) == 0 && containsString(rule.Verbs, rbac_v1.VerbAll) && containsString(rule.Resources, rbac_v1.ResourceAll) && containsString(rule.APIGroups, rbac_v1.APIGroupAll) {
+			return true
+		}
+	}
+
+	return false
+}
+
+//subjectsInclude checks if subjects name any of service accounts of namespace either directly, as users they
+//authenticate as, or through groups of service accounts or authenticated users. Service account subjects without
+//namespace default to namespace of binding.
+func subjectsInclude(subjects []rbac_v1.Subject, bindingNamespace, namespace string, serviceAccounts []string) bool {
+	for _, subject := range subjects {
+		switch subject.Kind {
+		case rbac_v1.ServiceAccountKind:
+			subjectNamespace := subject.Namespace
+			if subjectNamespace == "" {
+				subjectNamespace = bindingNamespace
+			}
+			if subjectNamespace == namespace && containsString(serviceAccounts, subject.Name) {
+				return true
+			}
+		case rbac_v1.UserKind:
+			for _, serviceAccount := range serviceAccounts {
+				if subject.Name == "system:serviceaccount:"+namespace+":"+serviceAccount {
+					return true
+				}
+			}
+		case rbac_v1.GroupKind:
+			switch subject.Name {
+			case "system:authenticated", "system:serviceaccounts", "system:serviceaccounts:" + namespace:
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+//podServiceAccounts returns sorted names of service accounts of pods and pod templates of mapped resource.
+func podServiceAccounts(mappedResource MappedResource) []string {
+	var podSpecs []core_v1.PodSpec
+
+	for _, deployment := range mappedResource.Kube.Deployments {
+		podSpecs = append(podSpecs, deployment.Spec.Template.Spec)
+	}
+
+	for _, replicaSet := range mappedResource.Kube.ReplicaSets {
+		podSpecs = append(podSpecs, replicaSet.Spec.Template.Spec)
+	}
+
+	for _, pod := range mappedResource.Kube.Pods {
+		podSpecs = append(podSpecs, pod.Spec)
+	}
+
+	var names []string
+	for _, podSpec := range podSpecs {
+		name := podSpec.ServiceAccountName
+		if name == "" {
+			name = podSpec.DeprecatedServiceAccount
+		}
+		if name == "" {
+			name = "default"
+		}
+		names = append(names, name)
+	}
+
+	if len(names) == 0 {
+		return nil
+	}
+
+	names = removeDuplicateStrings(names)
+	sort.Strings(names)
+
+	return names
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,264 @@
+package kubemap
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	core_v1 "k8s.io/api/core/v1"
+	rbac_v1 "k8s.io/api/rbac/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestAccessOfServiceAccount(t *testing.T) {
+	resources := helperGetRBACResources()
+	namespace := resources.Pods[0].Namespace
+
+	mappedResources, err := NewMapper().Map(resources)
+	assert.Nil(t, err)
+	assert.Len(t, mappedResources.MappedResource, 1)
+
+	kube := mappedResources.MappedResource[0].Kube
+	assert.Len(t, kube.ServiceAccounts, 1)
+	assert.Equal(t, "app", kube.ServiceAccounts[0].Name)
+	assert.Len(t, kube.RoleBindings, 2)
+	assert.Len(t, kube.Roles, 1)
+	assert.Len(t, kube.ClusterRoleBindings, 0)
+	assert.Len(t, kube.ClusterRoles, 1)
+	assert.Equal(t, "view", kube.ClusterRoles[0].Name)
+
+	assert.Equal(t, &Access{
+		ServiceAccounts: []string{"app"},
+		Permissions: []Permission{
+			{Namespace: namespace, Resource: "configmaps", Verbs: []string{"get", "list", "watch"}},
+			{Namespace: namespace, Resource: "pods", Verbs: []string{"get", "list", "watch"}},
+			{Namespace: namespace, APIGroup: "apps", Resource: "deployments", Verbs: []string{"list"}},
+		},
+	}, kube.Access)
+}
+
+func TestAccessIsAttachedOnceByServiceAccounts(t *testing.T) {
+	for _, resourceType := range []string{"role", "rolebinding", "clusterrole", "clusterrolebinding"} {
+		assert.Nil(t, relatedKinds[resourceType].attach, resourceType)
+	}
+	assert.NotNil(t, relatedKinds["serviceaccount"].attach)
+
+	//Access is still updated by events of other RBAC kinds.
+	resources := helperGetRBACResources()
+	roles := resources.Roles
+	resources.Roles = nil
+
+	mapper := NewMapper()
+	for _, event := range resourceEventsForMapping(resources) {
+		_, err := mapper.StoreMap(event)
+		assert.Nil(t, err)
+	}
+
+	results, err := mapper.StoreMap(resourceEventsForMapping(KubeResources{Roles: roles})[0])
+	assert.Nil(t, err)
+	assert.True(t, results[0].IsMapped)
+	assert.Len(t, results[0].MappedResource.Kube.Roles, 1)
+	assert.Len(t, results[0].MappedResource.Kube.Access.Permissions, 3)
+}
+
+func TestClusterAdminLikeAccess(t *testing.T) {
+	adminTests := map[string]struct {
+		roleRef string
+		rules   []rbac_v1.PolicyRule
+	}{
+		"Bound_To_Cluster_Admin": {
+			roleRef: clusterAdminRole,
+		},
+		"Wildcard_Rule": {
+			roleRef: "everything",
+			rules:   []rbac_v1.PolicyRule{{APIGroups: []string{"*"}, Resources: []string{"*"}, Verbs: []string{"*"}}},
+		},
+	}
+
+	for testName, test := range adminTests {
+		t.Run(testName, func(t *testing.T) {
+			resources := helperGetRBACResources()
+			namespace := resources.Pods[0].Namespace
+
+			resources.ClusterRoles = append(resources.ClusterRoles, rbac_v1.ClusterRole{
+				ObjectMeta: meta_v1.ObjectMeta{Name: test.roleRef},
+				Rules:      test.rules,
+			})
+			resources.ClusterRoleBindings = []rbac_v1.ClusterRoleBinding{{
+				ObjectMeta: meta_v1.ObjectMeta{Name: "app-admin"},
+				Subjects:   []rbac_v1.Subject{{Kind: rbac_v1.ServiceAccountKind, Name: "app", Namespace: namespace}},
+				RoleRef:    rbac_v1.RoleRef{Kind: "ClusterRole", Name: test.roleRef},
+			}}
+
+			mappedResources, err := NewMapper().Map(resources)
+			assert.Nil(t, err)
+
+			kube := mappedResources.MappedResource[0].Kube
+			assert.Len(t, kube.ClusterRoleBindings, 1)
+			assert.True(t, kube.Access.ClusterAdminLike)
+		})
+	}
+}
+
+func TestAccessRestrictedToResourceNames(t *testing.T) {
+	resources := helperGetRBACResources()
+	namespace := resources.Pods[0].Namespace
+	resources.Roles[0].Rules = []rbac_v1.PolicyRule{
+		{APIGroups: []string{""}, Resources: []string{"secrets"}, ResourceNames: []string{"app-tls", "app-db"}, Verbs: []string{"get"}},
+		{APIGroups: []string{""}, Resources: []string{"secrets"}, Verbs: []string{"list"}},
+		{APIGroups: []string{"*"}, Resources: []string{"*"}, ResourceNames: []string{"app"}, Verbs: []string{"*"}},
+	}
+	resources.ClusterRoles = append(resources.ClusterRoles, rbac_v1.ClusterRole{
+		ObjectMeta: meta_v1.ObjectMeta{Name: "named-everything"},
+		Rules:      []rbac_v1.PolicyRule{{APIGroups: []string{"*"}, Resources: []string{"*"}, ResourceNames: []string{"app"}, Verbs: []string{"*"}}},
+	})
+	resources.ClusterRoleBindings = []rbac_v1.ClusterRoleBinding{{
+		ObjectMeta: meta_v1.ObjectMeta{Name: "app-named-everything"},
+		Subjects:   []rbac_v1.Subject{{Kind: rbac_v1.ServiceAccountKind, Name: "app", Namespace: namespace}},
+		RoleRef:    rbac_v1.RoleRef{Kind: "ClusterRole", Name: "named-everything"},
+	}}
+
+	mappedResources, err := NewMapper().Map(resources)
+	assert.Nil(t, err)
+
+	access := mappedResources.MappedResource[0].Kube.Access
+	assert.False(t, access.ClusterAdminLike)
+	assert.Contains(t, access.Permissions, Permission{Namespace: namespace, Resource: "secrets", Verbs: []string{"list"}})
+	assert.Contains(t, access.Permissions, Permission{Namespace: namespace, Resource: "secrets", ResourceNames: []string{"app-db", "app-tls"}, Verbs: []string{"get"}})
+	assert.Contains(t, access.Permissions, Permission{APIGroup: "*", Resource: "*", ResourceNames: []string{"app"}, Verbs: []string{"*"}})
+}
+
+func TestDefaultServiceAccount(t *testing.T) {
+	resources := helperGetK8sResources()
+
+	mappedResources, err := NewMapper().Map(resources)
+	assert.Nil(t, err)
+	assert.Equal(t, &Access{ServiceAccounts: []string{"default"}}, mappedResources.MappedResource[0].Kube.Access)
+}
+
+func TestSubjectsInclude(t *testing.T) {
+	subjectTests := map[string]struct {
+		subject  rbac_v1.Subject
+		included bool
+	}{
+		"Service_Account_In_Binding_Namespace": {
+			subject:  rbac_v1.Subject{Kind: rbac_v1.ServiceAccountKind, Name: "app"},
+			included: true,
+		},
+		"Service_Account_In_Other_Namespace": {
+			subject:  rbac_v1.Subject{Kind: rbac_v1.ServiceAccountKind, Name: "app", Namespace: "other"},
+			included: false,
+		},
+		"Other_Service_Account": {
+			subject:  rbac_v1.Subject{Kind: rbac_v1.ServiceAccountKind, Name: "builder"},
+			included: false,
+		},
+		"Service_Accounts_Of_Namespace": {
+			subject:  rbac_v1.Subject{Kind: rbac_v1.GroupKind, Name: "system:serviceaccounts:shop"},
+			included: true,
+		},
+		"Service_Accounts_Of_Cluster": {
+			subject:  rbac_v1.Subject{Kind: rbac_v1.GroupKind, Name: "system:serviceaccounts"},
+			included: true,
+		},
+		"Service_Accounts_Of_Other_Namespace": {
+			subject:  rbac_v1.Subject{Kind: rbac_v1.GroupKind, Name: "system:serviceaccounts:other"},
+			included: false,
+		},
+		"Authenticated_Users": {
+			subject:  rbac_v1.Subject{Kind: rbac_v1.GroupKind, Name: "system:authenticated"},
+			included: true,
+		},
+		"Unauthenticated_Users": {
+			subject:  rbac_v1.Subject{Kind: rbac_v1.GroupKind, Name: "system:unauthenticated"},
+			included: false,
+		},
+		"User": {
+			subject:  rbac_v1.Subject{Kind: rbac_v1.UserKind, Name: "app"},
+			included: false,
+		},
+		"User_Of_Service_Account": {
+			subject:  rbac_v1.Subject{Kind: rbac_v1.UserKind, Name: "system:serviceaccount:shop:app"},
+			included: true,
+		},
+	}
+
+	for testName, test := range subjectTests {
+		t.Run(testName, func(t *testing.T) {
+			assert.Equal(t, test.included, subjectsInclude([]rbac_v1.Subject{test.subject}, "shop", "shop", []string{"app"}))
+		})
+	}
+}
+
+func TestRBACConsistency(t *testing.T) {
+	events := resourceEventsForMapping(helperGetRBACResources())
+	random := rand.New(rand.NewSource(39))
+
+	for i := 0; i < 30; i++ {
+		random.Shuffle(len(events), func(a, b int) {
+			events[a], events[b] = events[b], events[a]
+		})
+
+		report, err := CheckConsistency(events, MapOptions{})
+		assert.Nil(t, err)
+		assert.True(t, report.Consistent, "order %s diverged - %+v", helperEventOrder(events), report.Divergences)
+	}
+}
+
+//helperGetRBACResources returns fixtures whose pods run as service account 'app' which is bound to a role and to
+//cluster role 'view', along with a binding of another service account.
+func helperGetRBACResources() KubeResources {
+	resources := helperGetK8sResources()
+	namespace := resources.Pods[0].Namespace
+
+	for i := range resources.Deployments {
+		resources.Deployments[i].Spec.Template.Spec.ServiceAccountName = "app"
+	}
+	for i := range resources.ReplicaSets {
+		resources.ReplicaSets[i].Spec.Template.Spec.ServiceAccountName = "app"
+	}
+	for i := range resources.Pods {
+		resources.Pods[i].Spec.ServiceAccountName = "app"
+	}
+
+	resources.ServiceAccounts = []core_v1.ServiceAccount{
+		{ObjectMeta: meta_v1.ObjectMeta{Name: "app", Namespace: namespace}},
+		{ObjectMeta: meta_v1.ObjectMeta{Name: "builder", Namespace: namespace}},
+	}
+
+	resources.Roles = []rbac_v1.Role{{
+		ObjectMeta: meta_v1.ObjectMeta{Name: "pod-reader", Namespace: namespace},
+		Rules: []rbac_v1.PolicyRule{
+			{APIGroups: []string{""}, Resources: []string{"pods"}, Verbs: []string{"get", "list"}},
+			{APIGroups: []string{"apps"}, Resources: []string{"deployments"}, Verbs: []string{"list"}},
+		},
+	}}
+
+	resources.ClusterRoles = []rbac_v1.ClusterRole{{
+		ObjectMeta: meta_v1.ObjectMeta{Name: "view"},
+		Rules: []rbac_v1.PolicyRule{
+			{APIGroups: []string{""}, Resources: []string{"configmaps", "pods"}, Verbs: []string{"get", "list", "watch"}},
+		},
+	}}
+
+	resources.RoleBindings = []rbac_v1.RoleBinding{
+		{
+			ObjectMeta: meta_v1.ObjectMeta{Name: "app-pod-reader", Namespace: namespace},
+			Subjects:   []rbac_v1.Subject{{Kind: rbac_v1.ServiceAccountKind, Name: "app"}},
+			RoleRef:    rbac_v1.RoleRef{Kind: "Role", Name: "pod-reader"},
+		},
+		{
+			ObjectMeta: meta_v1.ObjectMeta{Name: "app-view", Namespace: namespace},
+			Subjects:   []rbac_v1.Subject{{Kind: rbac_v1.ServiceAccountKind, Name: "app", Namespace: namespace}},
+			RoleRef:    rbac_v1.RoleRef{Kind: "ClusterRole", Name: "view"},
+		},
+		{
+			ObjectMeta: meta_v1.ObjectMeta{Name: "builder-edit", Namespace: namespace},
+			Subjects:   []rbac_v1.Subject{{Kind: rbac_v1.ServiceAccountKind, Name: "builder"}},
+			RoleRef:    rbac_v1.RoleRef{Kind: "ClusterRole", Name: "edit"},
+		},
+	}
+
+	return resources
+}
@@ -0,0 +1,402 @@
+package kubemap
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	core_v1 "k8s.io/api/core/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+//defaultRedactionReplacement replaces redacted values unless RedactionOptions.Replacement is set.
+const defaultRedactionReplacement = "[REDACTED]"
+
+//defaultSecretKeyPatterns match names of env vars, annotations, flags and log fields which hold secrets.
+var defaultSecretKeyPatterns = []string{
+	`(?i)passw(or)?d`,
+	`(?i)secret`,
+	`(?i)token`,
+	`(?i)api[_-]?key`,
+	`(?i)credential`,
+	`(?i)private[_-]?key`,
+	`(?i)(^|[_.-])auth([_.-]|$)`,
+	`(?i)authorization`,
+	`(?i)auth[_-]?token`,
+}
+
+//defaultAnnotationPatterns match annotations whose values are redacted irrespective of their name.
+//Last applied configuration holds complete manifest including env values.
+var defaultAnnotationPatterns = []string{
+	`^kubectl\.kubernetes\.io/last-applied-configuration$`,
+}
+
+//redactor removes sensitive values from mapped resources and log messages.
+type redactor struct {
+	replacement        string
+	keepEnvValues      bool
+	secretKeys         []*regexp.Regexp
+	annotationPatterns []*regexp.Regexp
+	//secretAssignment matches 'key=value' or 'key: value' pairs in free text.
+	secretAssignment *regexp.Regexp
+}
+
+//newRedactor compiles redaction options. It returns nil if redaction is disabled.
+func newRedactor(options RedactionOptions) (*redactor, error) {
+	if options.Disabled {
+		return nil, nil
+	}
+
+	r := &redactor{
+		replacement:   options.Replacement,
+		keepEnvValues: options.KeepEnvValues,
+	}
+	if r.replacement == "" {
+		r.replacement = defaultRedactionReplacement
+	}
+
+	secretKeyPatterns := append(append([]string{}, defaultSecretKeyPatterns...), options.SecretKeyPatterns...)
+	for _, pattern := range secretKeyPatterns {
+		compiled, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("Cannot instantiate Mapper. Invalid secret key pattern %s - %v", pattern, err)
+		}
+		r.secretKeys = append(r.secretKeys, compiled)
+	}
+
+	annotationPatterns := append(append([]string{}, defaultAnnotationPatterns...), options.AnnotationPatterns...)
+	for _, pattern := range annotationPatterns {
+		compiled, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("Cannot instantiate Mapper. Invalid annotation pattern %s - %v", pattern, err)
+		}
+		r.annotationPatterns = append(r.annotationPatterns, compiled)
+	}
+
+	r.secretAssignment = regexp.MustCompile(`([A-Za-z0-9_.\-]+)(\s*[=:]\s*)("[^"]*"|'[^']*'|[^\s,;&"']+)`)
+
+	return r, nil
+}
+
+//isSecretKey checks if name looks like it holds a secret.
+func (r *redactor) isSecretKey(name string) bool {
+	for _, secretKey := range r.secretKeys {
+		if secretKey.MatchString(name) {
+			return true
+		}
+	}
+
+	return false
+}
+
+//redactString replaces values assigned to secret looking keys in text.
+func (r *redactor) redactString(text string) string {
+	if r == nil {
+		return text
+	}
+
+	return r.secretAssignment.ReplaceAllStringFunc(text, func(assignment string) string {
+		parts := r.secretAssignment.FindStringSubmatch(assignment)
+		if !r.isSecretKey(parts[1]) {
+			return assignment
+		}
+		return parts[1] + parts[2] + r.replacement
+	})
+}
+
+//redactMappedResource returns copy of mapped resource with sensitive values replaced.
+//Mapped resource in store is never modified.
+func (r *redactor) redactMappedResource(mappedResource MappedResource) MappedResource {
+	if r == nil {
+		return mappedResource
+	}
+
+	redacted := copyMappedResource(mappedResource)
+
+	for i := range redacted.Kube.Ingresses {
+		r.redactObjectMeta(&redacted.Kube.Ingresses[i].ObjectMeta)
+	}
+
+	for i := range redacted.Kube.Services {
+		r.redactObjectMeta(&redacted.Kube.Services[i].ObjectMeta)
+	}
+
+	for i := range redacted.Kube.Deployments {
+		r.redactObjectMeta(&redacted.Kube.Deployments[i].ObjectMeta)
+		r.redactPodTemplate(&redacted.Kube.Deployments[i].Spec.Template)
+	}
+
+	for i := range redacted.Kube.ReplicaSets {
+		r.redactObjectMeta(&redacted.Kube.ReplicaSets[i].ObjectMeta)
+		r.redactPodTemplate(&redacted.Kube.ReplicaSets[i].Spec.Template)
+	}
+
+	for i := range redacted.Kube.Pods {
+		r.redactObjectMeta(&redacted.Kube.Pods[i].ObjectMeta)
+		r.redactPodSpec(&redacted.Kube.Pods[i].Spec)
+	}
+
+	for i := range redacted.Kube.HorizontalPodAutoscalers {
+		r.redactObjectMeta(&redacted.Kube.HorizontalPodAutoscalers[i].ObjectMeta)
+	}
+
+	for i := range redacted.Kube.ConfigMaps {
+		r.redactObjectMeta(&redacted.Kube.ConfigMaps[i].ObjectMeta)
+		for key := range redacted.Kube.ConfigMaps[i].Data {
+			if r.isSecretKey(key) {
+				redacted.Kube.ConfigMaps[i].Data[key] = r.replacement
+			}
+		}
+	}
+
+	for i := range redacted.Kube.Secrets {
+		r.redactObjectMeta(&redacted.Kube.Secrets[i].ObjectMeta)
+	}
+
+	for i := range redacted.Kube.PersistentVolumeClaims {
+		r.redactObjectMeta(&redacted.Kube.PersistentVolumeClaims[i].ObjectMeta)
+	}
+
+	for i := range redacted.Kube.PersistentVolumes {
+		r.redactObjectMeta(&redacted.Kube.PersistentVolumes[i].ObjectMeta)
+	}
+
+	for i := range redacted.Kube.Endpoints {
+		r.redactObjectMeta(&redacted.Kube.Endpoints[i].ObjectMeta)
+	}
+
+	for i := range redacted.Kube.EndpointSlices {
+		r.redactObjectMeta(&redacted.Kube.EndpointSlices[i].ObjectMeta)
+	}
+
+	for i := range redacted.Kube.PodDisruptionBudgets {
+		r.redactObjectMeta(&redacted.Kube.PodDisruptionBudgets[i].ObjectMeta)
+	}
+
+	for i := range redacted.Kube.NetworkPolicies {
+		r.redactObjectMeta(&redacted.Kube.NetworkPolicies[i].ObjectMeta)
+	}
+
+	for i := range redacted.Kube.ServiceAccounts {
+		r.redactObjectMeta(&redacted.Kube.ServiceAccounts[i].ObjectMeta)
+	}
+
+	for i := range redacted.Kube.Roles {
+		r.redactObjectMeta(&redacted.Kube.Roles[i].ObjectMeta)
+	}
+
+	for i := range redacted.Kube.RoleBindings {
+		r.redactObjectMeta(&redacted.Kube.RoleBindings[i].ObjectMeta)
+	}
+
+	for i := range redacted.Kube.ClusterRoles {
+		r.redactObjectMeta(&redacted.Kube.ClusterRoles[i].ObjectMeta)
+	}
+
+	for i := range redacted.Kube.ClusterRoleBindings {
+		r.redactObjectMeta(&redacted.Kube.ClusterRoleBindings[i].ObjectMeta)
+	}
+
+	for i := range redacted.Kube.Gateways {
+		r.redactObjectMeta(&redacted.Kube.Gateways[i].ObjectMeta)
+	}
+
+	for i := range redacted.Kube.HTTPRoutes {
+		r.redactObjectMeta(&redacted.Kube.HTTPRoutes[i].ObjectMeta)
+	}
+
+	for i := range redacted.Kube.GRPCRoutes {
+		r.redactObjectMeta(&redacted.Kube.GRPCRoutes[i].ObjectMeta)
+	}
+
+	for i := range redacted.Kube.ReferenceGrants {
+		r.redactObjectMeta(&redacted.Kube.ReferenceGrants[i].ObjectMeta)
+	}
+
+	for i := range redacted.Kube.Nodes {
+		r.redactObjectMeta(&redacted.Kube.Nodes[i].ObjectMeta)
+	}
+
+	for i := range redacted.Kube.CustomResources {
+		r.redactUnstructured(&redacted.Kube.CustomResources[i])
+	}
+
+	for i := range redacted.Kube.Events {
+		r.redactObjectMeta(&redacted.Kube.Events[i].ObjectMeta)
+		redacted.Kube.Events[i].Message = r.redactString(redacted.Kube.Events[i].Message)
+	}
+
+	return redacted
+}
+
+//redactMappedResources redacts each of mapped resources.
+func (r *redactor) redactMappedResources(mappedResources MappedResources) MappedResources {
+	if r == nil {
+		return mappedResources
+	}
+
+	redacted := MappedResources{
+		Applications: mappedResources.Applications,
+	}
+	for _, mappedResource := range mappedResources.MappedResource {
+		redacted.MappedResource = append(redacted.MappedResource, r.redactMappedResource(mappedResource))
+	}
+
+	return redacted
+}
+
+//redactMapResults redacts mapped resource of each result.
+func (r *redactor) redactMapResults(mapResults []MapResult) []MapResult {
+	if r == nil {
+		return mapResults
+	}
+
+	redacted := make([]MapResult, 0, len(mapResults))
+	for _, mapResult := range mapResults {
+		mapResult.Message = r.redactString(mapResult.Message)
+		mapResult.MappedResource = r.redactMappedResource(mapResult.MappedResource)
+		redacted = append(redacted, mapResult)
+	}
+
+	return redacted
+}
+
+func (r *redactor) redactObjectMeta(objectMeta *meta_v1.ObjectMeta) {
+	for key := range objectMeta.Annotations {
+		if r.isRedactedAnnotation(key) {
+			objectMeta.Annotations[key] = r.replacement
+		}
+	}
+}
+
+//isRedactedAnnotation checks if value of annotation is redacted.
+func (r *redactor) isRedactedAnnotation(key string) bool {
+	if r.isSecretKey(key) {
+		return true
+	}
+
+	for _, annotationPattern := range r.annotationPatterns {
+		if annotationPattern.MatchString(key) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (r *redactor) redactPodTemplate(template *core_v1.PodTemplateSpec) {
+	r.redactObjectMeta(&template.ObjectMeta)
+	r.redactPodSpec(&template.Spec)
+}
+
+func (r *redactor) redactPodSpec(podSpec *core_v1.PodSpec) {
+	for i := range podSpec.InitContainers {
+		r.redactContainer(&podSpec.InitContainers[i])
+	}
+
+	for i := range podSpec.Containers {
+		r.redactContainer(&podSpec.Containers[i])
+	}
+}
+
+func (r *redactor) redactContainer(container *core_v1.Container) {
+	for i, env := range container.Env {
+		if env.Value != "" && (!r.keepEnvValues || r.isSecretKey(env.Name)) {
+			container.Env[i].Value = r.replacement
+		}
+	}
+
+	container.Command = r.redactArgs(container.Command)
+	container.Args = r.redactArgs(container.Args)
+}
+
+//redactArgs redacts '--password=value' style args as well as value following a bare '--password' flag.
+func (r *redactor) redactArgs(args []string) []string {
+	for i, arg := range args {
+		args[i] = r.redactString(arg)
+
+		if i > 0 && strings.HasPrefix(args[i-1], "-") && !strings.ContainsAny(args[i-1], "=:") && r.isSecretKey(args[i-1]) {
+			args[i] = r.replacement
+		}
+	}
+
+	return args
+}
+
+//redactUnstructured redacts annotations of custom resource as well as containers of its pod template or of its spec
+//e.g. 'spec.template.spec.containers' of an Argo Rollout.
+func (r *redactor) redactUnstructured(obj *unstructured.Unstructured) {
+	if metadata, ok := obj.Object["metadata"].(map[string]interface{}); ok {
+		r.redactUnstructuredMetadata(metadata)
+	}
+
+	spec, ok := obj.Object["spec"].(map[string]interface{})
+	if !ok {
+		return
+	}
+	r.redactUnstructuredPodSpec(spec)
+
+	template, ok := spec["template"].(map[string]interface{})
+	if !ok {
+		return
+	}
+	if metadata, ok := template["metadata"].(map[string]interface{}); ok {
+		r.redactUnstructuredMetadata(metadata)
+	}
+	if podSpec, ok := template["spec"].(map[string]interface{}); ok {
+		r.redactUnstructuredPodSpec(podSpec)
+	}
+}
+
+func (r *redactor) redactUnstructuredMetadata(metadata map[string]interface{}) {
+	annotations, _ := metadata["annotations"].(map[string]interface{})
+	for key := range annotations {
+		if r.isRedactedAnnotation(key) {
+			annotations[key] = r.replacement
+		}
+	}
+}
+
+//redactUnstructuredPodSpec redacts containers and init containers of pod spec of an unstructured object in place.
+func (r *redactor) redactUnstructuredPodSpec(podSpec map[string]interface{}) {
+	for _, field := range []string{"initContainers", "containers"} {
+		containers, _ := podSpec[field].([]interface{})
+		for _, item := range containers {
+			if container, ok := item.(map[string]interface{}); ok {
+				r.redactUnstructuredContainer(container)
+			}
+		}
+	}
+}
+
+func (r *redactor) redactUnstructuredContainer(container map[string]interface{}) {
+	env, _ := container["env"].([]interface{})
+	for _, item := range env {
+		envVar, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		name, _ := envVar["name"].(string)
+		if value, _ := envVar["value"].(string); value != "" && (!r.keepEnvValues || r.isSecretKey(name)) {
+			envVar["value"] = r.replacement
+		}
+	}
+
+	for _, field := range []string{"command", "args"} {
+		items, ok := container[field].([]interface{})
+		if !ok {
+			continue
+		}
+
+		args := make([]string, len(items))
+		for i, item := range items {
+			args[i], _ = item.(string)
+		}
+		for i, arg := range r.redactArgs(args) {
+			if _, ok := items[i].(string); ok {
+				items[i] = arg
+			}
+		}
+	}
+}
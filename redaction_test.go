@@ -0,0 +1,210 @@
+package kubemap
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	core_v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestRedactString(t *testing.T) {
+	r, _ := newRedactor(RedactionOptions{})
+
+	redactStringTests := map[string]struct {
+		text     string
+		expected string
+	}{
+		"With_Password_Assignment": {
+			text:     "connecting with password=hunter2 to db",
+			expected: "connecting with password=[REDACTED] to db",
+		},
+		"With_Quoted_Token": {
+			text:     `api_token: "abc def"`,
+			expected: "api_token: [REDACTED]",
+		},
+		"With_Non_Secret_Assignment": {
+			text:     "replicas=3 host=db",
+			expected: "replicas=3 host=db",
+		},
+		"With_Connection_String": {
+			text:     "postgres://app@db?sslmode=disable&password=hunter2",
+			expected: "postgres://app@db?sslmode=disable&password=[REDACTED]",
+		},
+	}
+
+	for testName, test := range redactStringTests {
+		t.Run(testName, func(t *testing.T) {
+			assert.Equal(t, test.expected, r.redactString(test.text))
+		})
+	}
+}
+
+func TestIsSecretKey(t *testing.T) {
+	r, _ := newRedactor(RedactionOptions{})
+
+	secretKeys := map[string]bool{
+		"DB_PASSWORD":         true,
+		"BASIC_AUTH":          true,
+		"auth":                true,
+		"--auth":              true,
+		"x-auth-header":       true,
+		"AUTHORIZATION":       true,
+		"GITHUB_AUTHTOKEN":    true,
+		"example.com/api-key": true,
+		"AUTHOR":              false,
+		"AUTHORITY_URL":       false,
+		"oauth-proxy-image":   false,
+		"LOG_LEVEL":           false,
+	}
+
+	for key, secret := range secretKeys {
+		t.Run(key, func(t *testing.T) {
+			assert.Equal(t, secret, r.isSecretKey(key))
+		})
+	}
+}
+
+func TestRedactMappedResource(t *testing.T) {
+	redactionTests := map[string]struct {
+		options        RedactionOptions
+		dbHost         string
+		dbPassword     string
+		ownerAnnotated string
+	}{
+		"With_Default_Options": {
+			options:        RedactionOptions{},
+			dbHost:         defaultRedactionReplacement,
+			dbPassword:     defaultRedactionReplacement,
+			ownerAnnotated: "team-a",
+		},
+		"With_Env_Values_Kept": {
+			options:        RedactionOptions{KeepEnvValues: true},
+			dbHost:         "db.test-namespace.svc.cluster.local",
+			dbPassword:     defaultRedactionReplacement,
+			ownerAnnotated: "team-a",
+		},
+		"With_Custom_Annotation_Pattern_And_Replacement": {
+			options:        RedactionOptions{KeepEnvValues: true, AnnotationPatterns: []string{`^example\.com/`}, Replacement: "***"},
+			dbHost:         "db.test-namespace.svc.cluster.local",
+			dbPassword:     "***",
+			ownerAnnotated: "***",
+		},
+	}
+
+	for testName, test := range redactionTests {
+		t.Run(testName, func(t *testing.T) {
+			r, err := newRedactor(test.options)
+			assert.Nil(t, err)
+
+			pod := helperGetBloatedPod("bloated")
+			pod.Spec.Containers[0].Args = []string{"--db-password", "hunter2", "--token=abc", "--port", "8080"}
+			mappedResource := MappedResource{Kube: Kube{Pods: []core_v1.Pod{pod}}}
+
+			redacted := r.redactMappedResource(mappedResource)
+			redactedPod := redacted.Kube.Pods[0]
+
+			assert.Equal(t, test.dbHost, redactedPod.Spec.Containers[0].Env[0].Value)
+			assert.Equal(t, test.dbPassword, redactedPod.Spec.Containers[0].Env[1].Value)
+			assert.Equal(t, test.ownerAnnotated, redactedPod.Annotations["example.com/owner"])
+			assert.NotContains(t, redactedPod.Annotations["kubectl.kubernetes.io/last-applied-configuration"], "hunter2")
+			assert.Equal(t, []string{"--db-password", test.dbPassword, "--token=" + test.dbPassword, "--port", "8080"}, redactedPod.Spec.Containers[0].Args)
+
+			//Original is untouched
+			assert.Equal(t, "hunter2", mappedResource.Kube.Pods[0].Spec.Containers[0].Env[1].Value)
+		})
+	}
+}
+
+func TestRedactCustomResource(t *testing.T) {
+	r, _ := newRedactor(RedactionOptions{KeepEnvValues: true})
+
+	rollout := helperCustomResource("argoproj.io/v1alpha1", "Rollout", "api", "shop", map[string]interface{}{
+		"spec": map[string]interface{}{
+			"template": map[string]interface{}{
+				"metadata": map[string]interface{}{
+					"annotations": map[string]interface{}{"example.com/api-token": "abc"},
+				},
+				"spec": map[string]interface{}{
+					"containers": []interface{}{map[string]interface{}{
+						"name": "api",
+						"env": []interface{}{
+							map[string]interface{}{"name": "DB_HOST", "value": "db"},
+							map[string]interface{}{"name": "DB_PASSWORD", "value": "hunter2"},
+						},
+						"args": []interface{}{"--token", "abc", "--port", int64(8080)},
+					}},
+				},
+			},
+		},
+	})
+	job := helperCustomResource("example.com/v1", "Job", "migrate", "shop", map[string]interface{}{
+		"spec": map[string]interface{}{
+			"initContainers": []interface{}{map[string]interface{}{
+				"name": "migrate",
+				"env":  []interface{}{map[string]interface{}{"name": "API_SECRET", "value": "hunter2"}},
+			}},
+		},
+	})
+	mappedResource := MappedResource{Kube: Kube{CustomResources: []unstructured.Unstructured{rollout, job}}}
+
+	redacted := r.redactMappedResource(mappedResource)
+	content, _ := json.Marshal(redacted)
+	assert.NotContains(t, string(content), "hunter2")
+	assert.NotContains(t, string(content), `"abc"`)
+
+	container, _, _ := unstructured.NestedSlice(redacted.Kube.CustomResources[0].Object, "spec", "template", "spec", "containers")
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{"name": "DB_HOST", "value": "db"},
+		map[string]interface{}{"name": "DB_PASSWORD", "value": defaultRedactionReplacement},
+	}, container[0].(map[string]interface{})["env"])
+	assert.Equal(t, []interface{}{"--token", defaultRedactionReplacement, "--port", int64(8080)}, container[0].(map[string]interface{})["args"])
+
+	//Original is untouched
+	content, _ = json.Marshal(mappedResource)
+	assert.Contains(t, string(content), "hunter2")
+}
+
+func TestMapperRedactsOutput(t *testing.T) {
+	resources := helperGetK8sResources()
+	resources.Pods = []core_v1.Pod{helperGetBloatedPod(resources.Pods[0].Name)}
+
+	mapper := NewMapper()
+	mappedResources, _ := mapper.Map(resources)
+
+	content, _ := json.Marshal(mappedResources)
+	assert.NotContains(t, string(content), "hunter2")
+
+	//Store keeps original values
+	storedResources := getAllMappedResources(mapper.store)
+	content, _ = json.Marshal(storedResources)
+	assert.Contains(t, string(content), "hunter2")
+}
+
+func TestMapperWithRedactionDisabled(t *testing.T) {
+	resources := helperGetK8sResources()
+	resources.Pods = []core_v1.Pod{helperGetBloatedPod(resources.Pods[0].Name)}
+
+	mapper, _ := NewMapperWithOptions(MapOptions{
+		Redaction: RedactionOptions{
+			Disabled: true,
+		},
+	})
+	mappedResources, _ := mapper.Map(resources)
+
+	content, _ := json.Marshal(mappedResources)
+	assert.Contains(t, string(content), "hunter2")
+}
+
+func TestNewRedactorWithInvalidPattern(t *testing.T) {
+	mapper, err := NewMapperWithOptions(MapOptions{
+		Redaction: RedactionOptions{
+			SecretKeyPatterns: []string{"("},
+		},
+	})
+
+	assert.Nil(t, mapper)
+	assert.NotNil(t, err)
+}
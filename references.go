@@ -0,0 +1,304 @@
+package kubemap
+
+import (
+	"fmt"
+	"sort"
+
+	core_v1 "k8s.io/api/core/v1"
+)
+
+//lastAppliedAnnotation holds complete manifest of object as last applied by kubectl.
+const lastAppliedAnnotation = "kubectl.kubernetes.io/last-applied-configuration"
+
+//MissingReference is a reference from members of a group to an object which does not exist.
+//Optional references are never reported.
+type MissingReference struct {
+	Kind string `json:"kind"`
+	Name string `json:"name"`
+	//ReferencedBy is 'kind/name' of members referring to missing object.
+	ReferencedBy []string `json:"referencedBy,omitempty"`
+}
+
+//objectReference is a reference from pod spec of a member to a namespaced object.
+type objectReference struct {
+	resourceType string
+	name         string
+	referencedBy string
+	optional     bool
+}
+
+var configMapKind = relatedKind{
+	normalize: func(obj interface{}) (interface{}, error) {
+		configMap, ok := obj.(*core_v1.ConfigMap)
+		if !ok {
+			return nil, fmt.Errorf("Object of type %T is not a ConfigMap", obj)
+		}
+		return configMap.DeepCopy(), nil
+	},
+	attach: func(mappedResource *MappedResource, related *relatedObjects) {
+		mappedResource.Kube.ConfigMaps = nil
+		for _, obj := range resolveReferences(mappedResource, related, "configmap") {
+			mappedResource.Kube.ConfigMaps = append(mappedResource.Kube.ConfigMaps, *obj.(*core_v1.ConfigMap).DeepCopy())
+		}
+	},
+	attached: func(mappedResource MappedResource) []interface{} {
+		var objects []interface{}
+		for i := range mappedResource.Kube.ConfigMaps {
+			objects = append(objects, mappedResource.Kube.ConfigMaps[i].DeepCopy())
+		}
+		return objects
+	},
+}
+
+//secretKind keeps metadata and type of secrets. Their data never enters mapper.
+var secretKind = relatedKind{
+	normalize: func(obj interface{}) (interface{}, error) {
+		secret, ok := obj.(*core_v1.Secret)
+		if !ok {
+			return nil, fmt.Errorf("Object of type %T is not a Secret", obj)
+		}
+		return secretMetadata(secret), nil
+	},
+	attach: func(mappedResource *MappedResource, related *relatedObjects) {
+		mappedResource.Kube.Secrets = nil
+		for _, obj := range resolveReferences(mappedResource, related, "secret") {
+			mappedResource.Kube.Secrets = append(mappedResource.Kube.Secrets, *obj.(*core_v1.Secret).DeepCopy())
+		}
+	},
+	attached: func(mappedResource MappedResource) []interface{} {
+		var objects []interface{}
+		for i := range mappedResource.Kube.Secrets {
+			objects = append(objects, secretMetadata(&mappedResource.Kube.Secrets[i]))
+		}
+		return objects
+	},
+}
+
+var persistentVolumeClaimKind = relatedKind{
+	normalize: func(obj interface{}) (interface{}, error) {
+		pvc, ok := obj.(*core_v1.PersistentVolumeClaim)
+		if !ok {
+			return nil, fmt.Errorf("Object of type %T is not a PersistentVolumeClaim", obj)
+		}
+		return pvc.DeepCopy(), nil
+	},
+	attach: func(mappedResource *MappedResource, related *relatedObjects) {
+		mappedResource.Kube.PersistentVolumeClaims = nil
+		for _, obj := range resolveReferences(mappedResource, related, "pvc") {
+			mappedResource.Kube.PersistentVolumeClaims = append(mappedResource.Kube.PersistentVolumeClaims, *obj.(*core_v1.PersistentVolumeClaim).DeepCopy())
+		}
+	},
+	attached: func(mappedResource MappedResource) []interface{} {
+		var objects []interface{}
+		for i := range mappedResource.Kube.PersistentVolumeClaims {
+			objects = append(objects, mappedResource.Kube.PersistentVolumeClaims[i].DeepCopy())
+		}
+		return objects
+	},
+}
+
+//persistentVolumeKind attaches volumes bound to claims referenced by members.
+var persistentVolumeKind = relatedKind{
+	clusterScoped: true,
+	normalize: func(obj interface{}) (interface{}, error) {
+		pv, ok := obj.(*core_v1.PersistentVolume)
+		if !ok {
+			return nil, fmt.Errorf("Object of type %T is not a PersistentVolume", obj)
+		}
+		return pv.DeepCopy(), nil
+	},
+	attach: attachPersistentVolumes,
+	attached: func(mappedResource MappedResource) []interface{} {
+		var objects []interface{}
+		for i := range mappedResource.Kube.PersistentVolumes {
+			objects = append(objects, mappedResource.Kube.PersistentVolumes[i].DeepCopy())
+		}
+		return objects
+	},
+}
+
+//attachPersistentVolumes attaches volume named by each referenced claim or, for claims not yet bound,
+//volume whose claim reference points to it.
+func attachPersistentVolumes(mappedResource *MappedResource, related *relatedObjects) {
+	mappedResource.Kube.PersistentVolumes = nil
+
+	claims := make(map[string]bool)
+	for _, reference := range podSpecReferences(*mappedResource) {
+		if reference.resourceType == "pvc" {
+			claims[reference.name] = true
+		}
+	}
+
+	for _, obj := range related.list("pv", "") {
+		pv := obj.(*core_v1.PersistentVolume)
+
+		bound := false
+		if pv.Spec.ClaimRef != nil && pv.Spec.ClaimRef.Namespace == mappedResource.Namespace && claims[pv.Spec.ClaimRef.Name] {
+			bound = true
+		}
+		for claim := range claims {
+			if pvc, ok := related.get("pvc", mappedResource.Namespace, claim); ok && pvc.(*core_v1.PersistentVolumeClaim).Spec.VolumeName == pv.Name {
+				bound = true
+			}
+		}
+
+		if bound {
+			mappedResource.Kube.PersistentVolumes = append(mappedResource.Kube.PersistentVolumes, *pv.DeepCopy())
+		}
+	}
+}
+
+//resolveReferences returns objects of resource type referenced by members of mapped resource sorted by name.
+//Missing references of resource type are replaced with those which cannot be resolved now. References are only
+//missing once any object of resource type was supplied, so baseline input without e.g. secrets reports none.
+func resolveReferences(mappedResource *MappedResource, related *relatedObjects, resourceType string) []interface{} {
+	referencedBy := make(map[string][]string)
+	required := make(map[string]bool)
+	var names []string
+
+	for _, reference := range podSpecReferences(*mappedResource) {
+		if reference.resourceType != resourceType {
+			continue
+		}
+
+		if _, exists := referencedBy[reference.name]; !exists {
+			names = append(names, reference.name)
+		}
+		referencedBy[reference.name] = append(referencedBy[reference.name], reference.referencedBy)
+		required[reference.name] = required[reference.name] || !reference.optional
+	}
+	sort.Strings(names)
+
+	var missingReferences []MissingReference
+	for _, missingReference := range mappedResource.Kube.MissingReferences {
+		if missingReference.Kind != resourceType {
+			missingReferences = append(missingReferences, missingReference)
+		}
+	}
+
+	var objects []interface{}
+	for _, name := range names {
+		if obj, ok := related.get(resourceType, mappedResource.Namespace, name); ok {
+			objects = append(objects, obj)
+			continue
+		}
+
+		if required[name] && related.observed(resourceType) {
+			referencingMembers := removeDuplicateStrings(referencedBy[name])
+			sort.Strings(referencingMembers)
+			missingReferences = append(missingReferences, MissingReference{
+				Kind:         resourceType,
+				Name:         name,
+				ReferencedBy: referencingMembers,
+			})
+		}
+	}
+
+	sort.Slice(missingReferences, func(i, j int) bool {
+		if missingReferences[i].Kind != missingReferences[j].Kind {
+			return missingReferences[i].Kind < missingReferences[j].Kind
+		}
+		return missingReferences[i].Name < missingReferences[j].Name
+	})
+	mappedResource.Kube.MissingReferences = missingReferences
+
+	return objects
+}
+
+//podSpecReferences returns references to config maps, secrets and claims from pod specs of pods, deployments and replica sets.
+func podSpecReferences(mappedResource MappedResource) []objectReference {
+	var references []objectReference
+
+	for _, deployment := range mappedResource.Kube.Deployments {
+		references = append(references, podSpecObjectReferences(deployment.Spec.Template.Spec, "deployment/"+deployment.Name)...)
+	}
+
+	for _, replicaSet := range mappedResource.Kube.ReplicaSets {
+		references = append(references, podSpecObjectReferences(replicaSet.Spec.Template.Spec, "replicaset/"+replicaSet.Name)...)
+	}
+
+	for _, pod := range mappedResource.Kube.Pods {
+		references = append(references, podSpecObjectReferences(pod.Spec, "pod/"+pod.Name)...)
+	}
+
+	return references
+}
+
+func podSpecObjectReferences(podSpec core_v1.PodSpec, referencedBy string) []objectReference {
+	var references []objectReference
+	add := func(resourceType, name string, optional *bool) {
+		if name == "" {
+			return
+		}
+		references = append(references, objectReference{
+			resourceType: resourceType,
+			name:         name,
+			referencedBy: referencedBy,
+			optional:     optional != nil && *optional,
+		})
+	}
+
+	for _, volume := range podSpec.Volumes {
+		if volume.ConfigMap != nil {
+			add("configmap", volume.ConfigMap.Name, volume.ConfigMap.Optional)
+		}
+		if volume.Secret != nil {
+			add("secret", volume.Secret.SecretName, volume.Secret.Optional)
+		}
+		if volume.PersistentVolumeClaim != nil {
+			add("pvc", volume.PersistentVolumeClaim.ClaimName, nil)
+		}
+		if volume.Projected != nil {
+			for _, source := range volume.Projected.Sources {
+				if source.ConfigMap != nil {
+					add("configmap", source.ConfigMap.Name, source.ConfigMap.Optional)
+				}
+				if source.Secret != nil {
+					add("secret", source.Secret.Name, source.Secret.Optional)
+				}
+			}
+		}
+	}
+
+	containers := append(append([]core_v1.Container{}, podSpec.InitContainers...), podSpec.Containers...)
+	for _, container := range containers {
+		for _, envFrom := range container.EnvFrom {
+			if envFrom.ConfigMapRef != nil {
+				add("configmap", envFrom.ConfigMapRef.Name, envFrom.ConfigMapRef.Optional)
+			}
+			if envFrom.SecretRef != nil {
+				add("secret", envFrom.SecretRef.Name, envFrom.SecretRef.Optional)
+			}
+		}
+
+		for _, env := range container.Env {
+			if env.ValueFrom == nil {
+				continue
+			}
+			if env.ValueFrom.ConfigMapKeyRef != nil {
+				add("configmap", env.ValueFrom.ConfigMapKeyRef.Name, env.ValueFrom.ConfigMapKeyRef.Optional)
+			}
+			if env.ValueFrom.SecretKeyRef != nil {
+				add("secret", env.ValueFrom.SecretKeyRef.Name, env.ValueFrom.SecretKeyRef.Optional)
+			}
+		}
+	}
+
+	for _, imagePullSecret := range podSpec.ImagePullSecrets {
+		add("secret", imagePullSecret.Name, nil)
+	}
+
+	return references
+}
+
+//secretMetadata returns copy of secret without data. Last applied configuration is dropped as it holds data as well.
+func secretMetadata(secret *core_v1.Secret) *core_v1.Secret {
+	metadata := &core_v1.Secret{
+		TypeMeta:   secret.TypeMeta,
+		ObjectMeta: *secret.ObjectMeta.DeepCopy(),
+		Type:       secret.Type,
+	}
+	delete(metadata.Annotations, lastAppliedAnnotation)
+
+	return metadata
+}
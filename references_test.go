@@ -0,0 +1,194 @@
+package kubemap
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	core_v1 "k8s.io/api/core/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestReferencedObjectsAreAttached(t *testing.T) {
+	resources := helperGetReferencingResources()
+
+	mappedResources, err := NewMapper().Map(resources)
+	assert.Nil(t, err)
+	assert.Len(t, mappedResources.MappedResource, 1)
+
+	kube := mappedResources.MappedResource[0].Kube
+	assert.Len(t, kube.ConfigMaps, 2)
+	assert.Equal(t, "app-config", kube.ConfigMaps[0].Name)
+	assert.Equal(t, "app-env", kube.ConfigMaps[1].Name)
+
+	assert.Len(t, kube.Secrets, 1)
+	assert.Equal(t, "db-credentials", kube.Secrets[0].Name)
+	assert.Nil(t, kube.Secrets[0].Data)
+	assert.Equal(t, core_v1.SecretTypeOpaque, kube.Secrets[0].Type)
+	assert.NotContains(t, kube.Secrets[0].Annotations, lastAppliedAnnotation)
+
+	assert.Len(t, kube.PersistentVolumeClaims, 1)
+	assert.Len(t, kube.PersistentVolumes, 1)
+	assert.Equal(t, "pv-data", kube.PersistentVolumes[0].Name)
+
+	assert.Equal(t, []MissingReference{{
+		Kind:         "secret",
+		Name:         "registry-pull",
+		ReferencedBy: []string{"pod/" + resources.Pods[0].Name},
+	}}, kube.MissingReferences)
+}
+
+func TestSecretDataNeverEntersStore(t *testing.T) {
+	resources := helperGetReferencingResources()
+
+	mapper, _ := NewMapperWithOptions(MapOptions{Redaction: RedactionOptions{Disabled: true}})
+	_, err := mapper.Map(resources)
+	assert.Nil(t, err)
+
+	for _, mappedResource := range getAllMappedResources(mapper.store).MappedResource {
+		for _, secret := range mappedResource.Kube.Secrets {
+			assert.Nil(t, secret.Data)
+			assert.Nil(t, secret.StringData)
+		}
+	}
+}
+
+func TestMissingReferenceIsResolvedWhenObjectArrives(t *testing.T) {
+	resources := helperGetReferencingResources()
+	pullSecret := core_v1.Secret{ObjectMeta: meta_v1.ObjectMeta{Name: "registry-pull", Namespace: resources.Pods[0].Namespace}}
+
+	mapper := NewMapper()
+	for _, event := range resourceEventsForMapping(resources) {
+		_, err := mapper.StoreMap(event)
+		assert.Nil(t, err)
+	}
+
+	results, err := mapper.StoreMap(gerResourceEvent(pullSecret.DeepCopy(), "secret"))
+	assert.Nil(t, err)
+	assert.True(t, results[0].IsMapped)
+	assert.Empty(t, results[0].MappedResource.Kube.MissingReferences)
+	assert.Len(t, results[0].MappedResource.Kube.Secrets, 2)
+}
+
+func TestReferencesOfKindsNotSuppliedAreNotMissing(t *testing.T) {
+	resources := helperGetK8sResources()
+	resources.Pods[0].Spec.Volumes = append(resources.Pods[0].Spec.Volumes, core_v1.Volume{
+		Name:         "default-token-abc",
+		VolumeSource: core_v1.VolumeSource{Secret: &core_v1.SecretVolumeSource{SecretName: "default-token-abc"}},
+	})
+
+	mappedResources, err := NewMapper().Map(resources)
+	assert.Nil(t, err)
+	assert.Len(t, mappedResources.MappedResource, 1)
+	assert.Empty(t, mappedResources.MappedResource[0].Kube.MissingReferences)
+	assert.NotEqual(t, HealthDegraded, BuildHierarchy(mappedResources).Health)
+
+	//Once a secret of any namespace is supplied, the token is known to be missing.
+	mapper := NewMapper()
+	for _, event := range resourceEventsForMapping(resources) {
+		_, err := mapper.StoreMap(event)
+		assert.Nil(t, err)
+	}
+	other := core_v1.Secret{ObjectMeta: meta_v1.ObjectMeta{Name: "unrelated", Namespace: "other"}}
+	results, err := mapper.StoreMap(gerResourceEvent(other.DeepCopy(), "secret"))
+	assert.Nil(t, err)
+	assert.True(t, results[0].IsMapped)
+	assert.Equal(t, []MissingReference{{
+		Kind:         "secret",
+		Name:         "default-token-abc",
+		ReferencedBy: []string{"pod/" + resources.Pods[0].Name},
+	}}, results[0].MappedResource.Kube.MissingReferences)
+}
+
+func TestReferencesConsistency(t *testing.T) {
+	events := resourceEventsForMapping(helperGetReferencingResources())
+	random := rand.New(rand.NewSource(36))
+
+	for i := 0; i < 30; i++ {
+		random.Shuffle(len(events), func(a, b int) {
+			events[a], events[b] = events[b], events[a]
+		})
+
+		report, err := CheckConsistency(events, MapOptions{})
+		assert.Nil(t, err)
+		assert.True(t, report.Consistent, "order %s diverged - %+v", helperEventOrder(events), report.Divergences)
+	}
+}
+
+func TestOptionalReferenceIsNotMissing(t *testing.T) {
+	optional := true
+	podSpec := core_v1.PodSpec{
+		Containers: []core_v1.Container{{
+			EnvFrom: []core_v1.EnvFromSource{{
+				ConfigMapRef: &core_v1.ConfigMapEnvSource{
+					LocalObjectReference: core_v1.LocalObjectReference{Name: "feature-flags"},
+					Optional:             &optional,
+				},
+			}},
+		}},
+	}
+	mappedResource := MappedResource{
+		Namespace: "default",
+		Kube: Kube{
+			Pods: []core_v1.Pod{{ObjectMeta: meta_v1.ObjectMeta{Name: "app"}, Spec: podSpec}},
+		},
+	}
+
+	objects := resolveReferences(&mappedResource, newRelatedObjects(), "configmap")
+	assert.Empty(t, objects)
+	assert.Empty(t, mappedResource.Kube.MissingReferences)
+}
+
+//helperGetReferencingResources returns fixtures whose pod refers to config maps, a secret, a claim and a missing pull secret.
+func helperGetReferencingResources() KubeResources {
+	resources := helperGetK8sResources()
+	namespace := resources.Pods[0].Namespace
+
+	podSpec := &resources.Pods[0].Spec
+	podSpec.Volumes = append(podSpec.Volumes,
+		core_v1.Volume{Name: "config", VolumeSource: core_v1.VolumeSource{ConfigMap: &core_v1.ConfigMapVolumeSource{
+			LocalObjectReference: core_v1.LocalObjectReference{Name: "app-config"},
+		}}},
+		core_v1.Volume{Name: "data", VolumeSource: core_v1.VolumeSource{PersistentVolumeClaim: &core_v1.PersistentVolumeClaimVolumeSource{
+			ClaimName: "data",
+		}}},
+	)
+	podSpec.Containers[0].EnvFrom = append(podSpec.Containers[0].EnvFrom, core_v1.EnvFromSource{
+		ConfigMapRef: &core_v1.ConfigMapEnvSource{LocalObjectReference: core_v1.LocalObjectReference{Name: "app-env"}},
+	})
+	podSpec.Containers[0].Env = append(podSpec.Containers[0].Env, core_v1.EnvVar{
+		Name: "DB_PASSWORD",
+		ValueFrom: &core_v1.EnvVarSource{SecretKeyRef: &core_v1.SecretKeySelector{
+			LocalObjectReference: core_v1.LocalObjectReference{Name: "db-credentials"},
+			Key:                  "password",
+		}},
+	})
+	podSpec.ImagePullSecrets = append(podSpec.ImagePullSecrets, core_v1.LocalObjectReference{Name: "registry-pull"})
+
+	resources.ConfigMaps = []core_v1.ConfigMap{
+		{ObjectMeta: meta_v1.ObjectMeta{Name: "app-config", Namespace: namespace}, Data: map[string]string{"LOG_LEVEL": "debug"}},
+		{ObjectMeta: meta_v1.ObjectMeta{Name: "app-env", Namespace: namespace}},
+		{ObjectMeta: meta_v1.ObjectMeta{Name: "unused", Namespace: namespace}},
+	}
+	resources.Secrets = []core_v1.Secret{{
+		ObjectMeta: meta_v1.ObjectMeta{
+			Name:        "db-credentials",
+			Namespace:   namespace,
+			Annotations: map[string]string{lastAppliedAnnotation: `{"data":{"password":"aHVudGVyMg=="}}`},
+		},
+		Type: core_v1.SecretTypeOpaque,
+		Data: map[string][]byte{"password": []byte("hunter2")},
+	}}
+	resources.PersistentVolumeClaims = []core_v1.PersistentVolumeClaim{{
+		ObjectMeta: meta_v1.ObjectMeta{Name: "data", Namespace: namespace},
+		Spec:       core_v1.PersistentVolumeClaimSpec{VolumeName: "pv-data"},
+		Status:     core_v1.PersistentVolumeClaimStatus{Phase: core_v1.ClaimBound},
+	}}
+	resources.PersistentVolumes = []core_v1.PersistentVolume{
+		{ObjectMeta: meta_v1.ObjectMeta{Name: "pv-data"}},
+		{ObjectMeta: meta_v1.ObjectMeta{Name: "pv-other"}},
+	}
+
+	return resources
+}
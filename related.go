@@ -0,0 +1,305 @@
+package kubemap
+
+import (
+	"encoding/base64"
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+
+	"k8s.io/client-go/tools/cache"
+)
+
+//relatedKind describes a k8s resource type which does not form groups by itself.
+//Objects of a related kind are kept aside and attached to every group they refer to, or which refers to them,
+//whenever that group is stored. Thus result does not depend on whether object arrives before or after its group.
+type relatedKind struct {
+	//clusterScoped objects may be attached to groups of any namespace.
+	clusterScoped bool
+	//indexField is lookup field of index whose value is name of object, if object may only be attached to groups
+	//having that value e.g. node of pods. Only those groups are refreshed by events of object.
+	indexField string
+	//normalize converts object of event into object kept in registry.
+	normalize func(obj interface{}) (interface{}, error)
+	//attach sets objects of this kind on mapped resource. Previously attached objects are replaced.
+	//It is nil if objects of this kind are attached by attach of another kind.
+	attach func(mappedResource *MappedResource, related *relatedObjects)
+	//attached returns objects of this kind which are attached to mapped resource.
+	attached func(mappedResource MappedResource) []interface{}
+}
+
+//relatedKinds by resource type.
+var relatedKinds = map[string]relatedKind{
+	"hpa":                autoscalerKind,
+	"configmap":          configMapKind,
+	"secret":             secretKind,
+	"pvc":                persistentVolumeClaimKind,
+	"pv":                 persistentVolumeKind,
+	"endpoints":          endpointsKind,
+	"endpointslice":      endpointSliceKind,
+	"pdb":                podDisruptionBudgetKind,
+	"networkpolicy":      networkPolicyKind,
+	"serviceaccount":     serviceAccountKind,
+	"role":               roleKind,
+	"rolebinding":        roleBindingKind,
+	"clusterrole":        clusterRoleKind,
+	"clusterrolebinding": clusterRoleBindingKind,
+	"gateway":            gatewayKind,
+	"httproute":          httpRouteKind,
+	"grpcroute":          grpcRouteKind,
+	"referencegrant":     referenceGrantKind,
+	"node":               nodeKind,
+}
+
+//relatedObjects is thread safe registry of objects of related kinds by resource type and 'namespace/name'.
+//Besides relatedKinds, it knows kinds of custom resources for which Mapper has rules.
+//Objects are held in memory whichever store Mapper uses, as attaching them needs every object of a kind at hand.
+type relatedObjects struct {
+	sync.RWMutex
+	kinds   map[string]relatedKind
+	objects map[string]map[string]interface{}
+	//seen resource types are those of which any object was supplied, even if it was deleted since.
+	seen map[string]bool
+}
+
+func newRelatedObjects() *relatedObjects {
+	kinds := make(map[string]relatedKind)
+	for resourceType, kind := range relatedKinds {
+		kinds[resourceType] = kind
+	}
+
+	return &relatedObjects{
+		kinds:   kinds,
+		objects: make(map[string]map[string]interface{}),
+		seen:    make(map[string]bool),
+	}
+}
+
+//newRelatedObjectsFromStore registers objects already attached to mapped resources of store.
+func newRelatedObjectsFromStore(store cache.Store) *relatedObjects {
+	related := newRelatedObjects()
+	related.addStore(store)
+
+	return related
+}
+
+//addStore registers objects attached to mapped resources of store.
+func (r *relatedObjects) addStore(store cache.Store) {
+	for _, item := range store.List() {
+		r.addAttached(item.(MappedResource))
+	}
+}
+
+//kind returns related kind of resource type. It is false if resource type is mapped instead of being attached.
+func (r *relatedObjects) kind(resourceType string) (relatedKind, bool) {
+	if r == nil {
+		kind, ok := relatedKinds[resourceType]
+		return kind, ok
+	}
+
+	kind, ok := r.kinds[resourceType]
+	return kind, ok
+}
+
+//addKind registers a related kind which is not one of relatedKinds e.g. that of a custom resource.
+func (r *relatedObjects) addKind(resourceType string, kind relatedKind) error {
+	if _, ok := r.kinds[resourceType]; ok {
+		return fmt.Errorf("Resource type '%s' is already registered", resourceType)
+	}
+	r.kinds[resourceType] = kind
+
+	return nil
+}
+
+//resourceTypes returns sorted resource types of all related kinds.
+func (r *relatedObjects) resourceTypes() []string {
+	kinds := relatedKinds
+	if r != nil {
+		kinds = r.kinds
+	}
+
+	var resourceTypes []string
+	for resourceType := range kinds {
+		resourceTypes = append(resourceTypes, resourceType)
+	}
+	sort.Strings(resourceTypes)
+
+	return resourceTypes
+}
+
+//addAttached registers objects attached to mapped resource.
+func (r *relatedObjects) addAttached(mappedResource MappedResource) {
+	for resourceType, kind := range r.kinds {
+		for _, obj := range kind.attached(mappedResource) {
+			objMeta := objectMetaData(obj)
+			r.set(resourceType, objMeta.Namespace, objMeta.Name, obj)
+		}
+	}
+
+	r.Lock()
+	defer r.Unlock()
+	for _, missingReference := range mappedResource.Kube.MissingReferences {
+		r.seen[missingReference.Kind] = true
+	}
+}
+
+func (r *relatedObjects) set(resourceType, namespace, name string, obj interface{}) {
+	r.Lock()
+	defer r.Unlock()
+
+	if r.objects[resourceType] == nil {
+		r.objects[resourceType] = make(map[string]interface{})
+	}
+	r.objects[resourceType][namespace+"/"+name] = obj
+	r.seen[resourceType] = true
+}
+
+func (r *relatedObjects) remove(resourceType, namespace, name string) {
+	r.Lock()
+	defer r.Unlock()
+
+	delete(r.objects[resourceType], namespace+"/"+name)
+	r.seen[resourceType] = true
+}
+
+//observed is true if any object of resource type was supplied. Otherwise its objects are unknown rather than missing.
+func (r *relatedObjects) observed(resourceType string) bool {
+	if r == nil {
+		return false
+	}
+
+	r.RLock()
+	defer r.RUnlock()
+
+	return r.seen[resourceType]
+}
+
+//get returns object of resource type with given namespace and name.
+func (r *relatedObjects) get(resourceType, namespace, name string) (interface{}, bool) {
+	if r == nil {
+		return nil, false
+	}
+
+	r.RLock()
+	defer r.RUnlock()
+
+	obj, ok := r.objects[resourceType][namespace+"/"+name]
+	return obj, ok
+}
+
+//list returns objects of resource type in namespace sorted by name. Empty namespace lists all namespaces.
+func (r *relatedObjects) list(resourceType, namespace string) []interface{} {
+	if r == nil {
+		return nil
+	}
+
+	r.RLock()
+	defer r.RUnlock()
+
+	var keys []string
+	for key, obj := range r.objects[resourceType] {
+		if namespace == "" || objectMetaData(obj).Namespace == namespace {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	var objects []interface{}
+	for _, key := range keys {
+		objects = append(objects, r.objects[resourceType][key])
+	}
+
+	return objects
+}
+
+//attachRelatedObjects sets objects of every related kind on mapped resource.
+func (m *Mapper) attachRelatedObjects(mappedResource MappedResource) MappedResource {
+	for _, resourceType := range m.related.resourceTypes() {
+		kind, _ := m.related.kind(resourceType)
+		if kind.attach != nil {
+			kind.attach(&mappedResource, m.related)
+		}
+	}
+
+	return mappedResource
+}
+
+//mapRelatedObj keeps object of related kind in registry and updates groups whose attachments change.
+func (m *Mapper) mapRelatedObj(obj ResourceEvent, store cache.Store) ([]MapResult, error) {
+	kind, _ := m.related.kind(obj.ResourceType)
+	//References of groups in every namespace become missing once first object of a kind is supplied.
+	firstOfKind := !m.related.observed(obj.ResourceType)
+
+	unchanged := []MapResult{{
+		Action:   "Updated",
+		IsMapped: false,
+		Message:  fmt.Sprintf("%s %s does not change attachments of any Common Label", obj.ResourceType, obj.Name),
+	}}
+
+	if obj.EventType == "DELETED" || obj.Event == nil {
+		m.related.remove(obj.ResourceType, obj.Namespace, obj.Name)
+	} else {
+		related, err := kind.normalize(obj.Event)
+		if err != nil {
+			return []MapResult{}, err
+		}
+		//Attachments depend on registry only, so they cannot change if normalized object is same as before.
+		if previous, ok := m.related.get(obj.ResourceType, obj.Namespace, obj.Name); ok && reflect.DeepEqual(previous, related) {
+			return unchanged, nil
+		}
+		m.related.set(obj.ResourceType, obj.Namespace, obj.Name, related)
+	}
+
+	var keys []string
+	switch {
+	case firstOfKind:
+		keys = getAllKeys(store)
+	case kind.indexField != "":
+		for _, b64Key := range m.index.keys(store, kind.indexField, obj.Name) {
+			key, _ := base64.StdEncoding.DecodeString(b64Key)
+			keys = append(keys, string(key))
+		}
+	case kind.clusterScoped:
+		keys = getAllKeys(store)
+	default:
+		keys = getNamespaceKeys(store, obj.Namespace)
+	}
+
+	results := m.refreshAttachments(store, keys)
+	for i := range results {
+		results[i].Message = fmt.Sprintf("Attachments of Common Label %s are updated after %s %s is %s", results[i].MappedResource.CommonLabel, obj.ResourceType, obj.Name, obj.EventType)
+	}
+
+	if len(results) == 0 {
+		return unchanged, nil
+	}
+
+	return results, nil
+}
+
+//refreshAttachments re-attaches related objects to mapped resources of given decoded store keys and returns those
+//which changed.
+func (m *Mapper) refreshAttachments(store cache.Store, keys []string) []MapResult {
+	var results []MapResult
+	for _, key := range keys {
+		mappedResource, err := getObjectFromStore(base64.StdEncoding.EncodeToString([]byte(key)), store)
+		if err != nil {
+			continue
+		}
+
+		finalized := m.finalizeMappedResource(copyMappedResource(mappedResource))
+		if reflect.DeepEqual(finalized, mappedResource) {
+			continue
+		}
+
+		results = append(results, MapResult{
+			Action:         "Updated",
+			Key:            key,
+			CommonLabel:    finalized.CommonLabel,
+			IsMapped:       true,
+			MappedResource: finalized,
+		})
+	}
+
+	return results
+}
@@ -0,0 +1,192 @@
+package kubemap
+
+import (
+	"sort"
+	"strings"
+)
+
+const (
+	//HelmReleaseNameAnnotation is set by Helm on every object it installs.
+	HelmReleaseNameAnnotation = "meta.helm.sh/release-name"
+	//HelmReleaseNamespaceAnnotation is set by Helm along with release name.
+	HelmReleaseNamespaceAnnotation = "meta.helm.sh/release-namespace"
+	//ArgoCDInstanceKey is annotation or label Argo CD sets to name of owning application.
+	ArgoCDInstanceKey = "argocd.argoproj.io/instance"
+	//ArgoCDTrackingIDAnnotation is set by Argo CD annotation based tracking as 'application:group/kind:namespace/name'.
+	ArgoCDTrackingIDAnnotation = "argocd.argoproj.io/tracking-id"
+)
+
+const (
+	//ReleaseManagerHelm manages releases through Helm.
+	ReleaseManagerHelm = "helm"
+	//ReleaseManagerArgoCD manages releases through Argo CD applications.
+	ReleaseManagerArgoCD = "argocd"
+)
+
+//Release is set of mapped resources installed by one Helm release or Argo CD application.
+type Release struct {
+	Manager   string `json:"manager,omitempty"`
+	Name      string `json:"name,omitempty"`
+	Namespace string `json:"namespace,omitempty"`
+	//CommonLabels are common labels of mapped resources belonging to this release.
+	CommonLabels []string `json:"commonLabels,omitempty"`
+	//Conflicts are members of this release's mapped resources which are annotated with another release.
+	Conflicts []ReleaseConflict `json:"conflicts,omitempty"`
+}
+
+//ReleaseConflict is an object whose release annotation disagrees with release of its mapped resource.
+type ReleaseConflict struct {
+	CommonLabel string `json:"commonLabel,omitempty"`
+	Kind        string `json:"kind,omitempty"`
+	Name        string `json:"name,omitempty"`
+	Namespace   string `json:"namespace,omitempty"`
+	//Release is name of release annotated on object.
+	Release string `json:"release,omitempty"`
+}
+
+//releaseRef identifies release of a single object.
+type releaseRef struct {
+	manager   string
+	name      string
+	namespace string
+}
+
+//GroupByRelease builds Helm and Argo CD release views of mapped resources.
+//Release of a mapped resource is the one annotated on most of its members. Members annotated with
+//a different release of same manager are reported as conflicts. Members without annotations e.g.
+//pods belong to release of their mapped resource.
+func GroupByRelease(mappedResources MappedResources) []Release {
+	releases := make(map[releaseRef]*Release)
+
+	for _, mappedResource := range mappedResources.MappedResource {
+		members := memberObjects(mappedResource)
+
+		for _, manager := range []string{ReleaseManagerHelm, ReleaseManagerArgoCD} {
+			var refs []releaseRef
+			memberRefs := make([]releaseRef, len(members))
+
+			for i, member := range members {
+				if ref, ok := objectRelease(manager, member); ok {
+					refs = append(refs, ref)
+					memberRefs[i] = ref
+				}
+			}
+
+			if len(refs) == 0 {
+				continue
+			}
+
+			groupRef := majorityRelease(refs)
+			release, exists := releases[groupRef]
+			if !exists {
+				release = &Release{
+					Manager:   groupRef.manager,
+					Name:      groupRef.name,
+					Namespace: groupRef.namespace,
+				}
+				releases[groupRef] = release
+			}
+			release.CommonLabels = append(release.CommonLabels, mappedResource.CommonLabel)
+
+			for i, member := range members {
+				if memberRefs[i].name == "" || memberRefs[i] == groupRef {
+					continue
+				}
+
+				release.Conflicts = append(release.Conflicts, ReleaseConflict{
+					CommonLabel: mappedResource.CommonLabel,
+					Kind:        member.Kind,
+					Name:        member.ObjectMeta.Name,
+					Namespace:   member.ObjectMeta.Namespace,
+					Release:     memberRefs[i].name,
+				})
+			}
+		}
+	}
+
+	var result []Release
+	for _, release := range releases {
+		sort.Strings(release.CommonLabels)
+		sort.Slice(release.Conflicts, func(i, j int) bool {
+			a, b := release.Conflicts[i], release.Conflicts[j]
+			if a.CommonLabel != b.CommonLabel {
+				return a.CommonLabel < b.CommonLabel
+			}
+			if a.Kind != b.Kind {
+				return a.Kind < b.Kind
+			}
+			return a.Name < b.Name
+		})
+		result = append(result, *release)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Manager != result[j].Manager {
+			return result[i].Manager < result[j].Manager
+		}
+		if result[i].Namespace != result[j].Namespace {
+			return result[i].Namespace < result[j].Namespace
+		}
+		return result[i].Name < result[j].Name
+	})
+
+	return result
+}
+
+//Releases returns Helm and Argo CD release views of mapped resources in store.
+func (m *Mapper) Releases() []Release {
+	return GroupByRelease(getAllMappedResources(m.store))
+}
+
+//objectRelease returns release of given manager annotated on member.
+func objectRelease(manager string, member memberObject) (releaseRef, bool) {
+	annotations := member.ObjectMeta.Annotations
+
+	switch manager {
+	case ReleaseManagerHelm:
+		name := annotations[HelmReleaseNameAnnotation]
+		if name == "" {
+			return releaseRef{}, false
+		}
+
+		namespace := annotations[HelmReleaseNamespaceAnnotation]
+		if namespace == "" {
+			namespace = member.ObjectMeta.Namespace
+		}
+
+		return releaseRef{manager: manager, name: name, namespace: namespace}, true
+	case ReleaseManagerArgoCD:
+		name := annotations[ArgoCDInstanceKey]
+		if name == "" {
+			name = member.ObjectMeta.Labels[ArgoCDInstanceKey]
+		}
+		if name == "" && annotations[ArgoCDTrackingIDAnnotation] != "" {
+			name = strings.SplitN(annotations[ArgoCDTrackingIDAnnotation], ":", 2)[0]
+		}
+		if name == "" {
+			return releaseRef{}, false
+		}
+
+		return releaseRef{manager: manager, name: name, namespace: member.ObjectMeta.Namespace}, true
+	}
+
+	return releaseRef{}, false
+}
+
+//majorityRelease returns most frequent of refs. Ties are broken by smallest namespace and name.
+func majorityRelease(refs []releaseRef) releaseRef {
+	counts := make(map[releaseRef]int)
+	for _, ref := range refs {
+		counts[ref]++
+	}
+
+	var majority releaseRef
+	for ref, count := range counts {
+		if majority.name == "" || count > counts[majority] ||
+			(count == counts[majority] && (ref.namespace < majority.namespace || (ref.namespace == majority.namespace && ref.name < majority.name))) {
+			majority = ref
+		}
+	}
+
+	return majority
+}
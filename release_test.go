@@ -0,0 +1,93 @@
+package kubemap
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	apps_v1 "k8s.io/api/apps/v1"
+	core_v1 "k8s.io/api/core/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestGroupByRelease(t *testing.T) {
+	helmShop := map[string]string{HelmReleaseNameAnnotation: "shop", HelmReleaseNamespaceAnnotation: "prod"}
+	helmLegacy := map[string]string{HelmReleaseNameAnnotation: "legacy", HelmReleaseNamespaceAnnotation: "prod"}
+
+	mappedResources := MappedResources{MappedResource: []MappedResource{
+		helperReleaseMappedResource("api", helmShop, helmShop, nil),
+		helperReleaseMappedResource("worker", helmShop, helmShop, helmLegacy),
+		helperReleaseMappedResource("cron", map[string]string{ArgoCDInstanceKey: "platform"}, nil, nil),
+		helperReleaseMappedResource("ui", map[string]string{ArgoCDTrackingIDAnnotation: "platform:apps/Deployment:prod/ui"}, nil, nil),
+		helperReleaseMappedResource("manual", nil, nil, nil),
+	}}
+
+	releases := GroupByRelease(mappedResources)
+
+	assert.Equal(t, []Release{
+		{
+			Manager:      ReleaseManagerArgoCD,
+			Name:         "platform",
+			Namespace:    "prod",
+			CommonLabels: []string{"cron", "ui"},
+		},
+		{
+			Manager:      ReleaseManagerHelm,
+			Name:         "shop",
+			Namespace:    "prod",
+			CommonLabels: []string{"api", "worker"},
+			Conflicts: []ReleaseConflict{
+				{CommonLabel: "worker", Kind: "service", Name: "worker", Namespace: "prod", Release: "legacy"},
+			},
+		},
+	}, releases)
+}
+
+func TestGroupByReleaseWithArgoCDLabel(t *testing.T) {
+	mappedResource := helperReleaseMappedResource("api", nil, nil, nil)
+	mappedResource.Kube.Deployments[0].Labels = map[string]string{ArgoCDInstanceKey: "shop"}
+
+	releases := GroupByRelease(MappedResources{MappedResource: []MappedResource{mappedResource}})
+
+	assert.Len(t, releases, 1)
+	assert.Equal(t, "shop", releases[0].Name)
+	assert.Empty(t, releases[0].Conflicts)
+}
+
+func TestMapperReleases(t *testing.T) {
+	resources := helperGetK8sResources()
+	for i := range resources.Services {
+		resources.Services[i].Annotations = map[string]string{HelmReleaseNameAnnotation: "suite"}
+	}
+
+	mapper := NewMapper()
+	_, err := mapper.Map(resources)
+	assert.Nil(t, err)
+
+	releases := mapper.Releases()
+	assert.Len(t, releases, 1)
+	assert.Equal(t, ReleaseManagerHelm, releases[0].Manager)
+	assert.Equal(t, "suite", releases[0].Name)
+	assert.Equal(t, resources.Services[0].Namespace, releases[0].Namespace)
+}
+
+func helperReleaseMappedResource(name string, deploymentAnnotations, replicaSetAnnotations, serviceAnnotations map[string]string) MappedResource {
+	return MappedResource{
+		CommonLabel: name,
+		Namespace:   "prod",
+		Kube: Kube{
+			Services: []core_v1.Service{{
+				ObjectMeta: meta_v1.ObjectMeta{Name: name, Namespace: "prod", Annotations: serviceAnnotations},
+			}},
+			Deployments: []apps_v1.Deployment{{
+				ObjectMeta: meta_v1.ObjectMeta{Name: name, Namespace: "prod", Annotations: deploymentAnnotations},
+			}},
+			ReplicaSets: []apps_v1.ReplicaSet{{
+				ObjectMeta: meta_v1.ObjectMeta{Name: name + "-abc", Namespace: "prod", Annotations: replicaSetAnnotations},
+			}},
+			Pods: []core_v1.Pod{{
+				ObjectMeta: meta_v1.ObjectMeta{Name: name + "-abc-xyz", Namespace: "prod"},
+			}},
+		},
+	}
+}
@@ -0,0 +1,181 @@
+package kubemap
+
+import (
+	"sort"
+	"strconv"
+
+	apps_v1 "k8s.io/api/apps/v1"
+	core_v1 "k8s.io/api/core/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+//DeploymentRevisionAnnotation is set by deployment controller on Deployments and their ReplicaSets.
+const DeploymentRevisionAnnotation = "deployment.kubernetes.io/revision"
+
+//progressDeadlineExceeded is reason of Progressing condition of a Deployment whose rollout stalled.
+const progressDeadlineExceeded = "ProgressDeadlineExceeded"
+
+const (
+	//RolloutComplete means all desired replicas run current revision and no old replicas are left.
+	RolloutComplete = "Complete"
+	//RolloutProgressing means replicas of current revision are being created or old replicas removed.
+	RolloutProgressing = "Progressing"
+	//RolloutPaused means rollout of Deployment is paused.
+	RolloutPaused = "Paused"
+	//RolloutStalled means Deployment exceeded its progress deadline.
+	RolloutStalled = "Stalled"
+)
+
+//Rollout is rollout state of a Deployment derived from revisions of its ReplicaSets.
+type Rollout struct {
+	Deployment string `json:"deployment"`
+	//State is one of RolloutComplete, RolloutProgressing, RolloutPaused or RolloutStalled.
+	State   string `json:"state"`
+	Message string `json:"message,omitempty"`
+	//CurrentRevision is zero if no revision is known.
+	CurrentRevision int64 `json:"currentRevision,omitempty"`
+	//PreviousRevision is zero if Deployment was never rolled out before.
+	PreviousRevision int64 `json:"previousRevision,omitempty"`
+	//Progress is percentage of desired replicas available with current revision.
+	Progress          int   `json:"progress"`
+	DesiredReplicas   int32 `json:"desiredReplicas"`
+	UpdatedReplicas   int32 `json:"updatedReplicas"`
+	AvailableReplicas int32 `json:"availableReplicas"`
+	//OldReplicas are replicas of previous revisions still running.
+	OldReplicas int32 `json:"oldReplicas"`
+	//History is revisions of ReplicaSets of Deployment, newest first.
+	History []RolloutRevision `json:"history,omitempty"`
+}
+
+//RolloutRevision is a revision of a Deployment and its ReplicaSet.
+type RolloutRevision struct {
+	Revision   int64  `json:"revision"`
+	ReplicaSet string `json:"replicaSet"`
+	//Images are images of revision by container name, init containers included.
+	Images            map[string]string `json:"images,omitempty"`
+	Replicas          int32             `json:"replicas"`
+	AvailableReplicas int32             `json:"availableReplicas"`
+	CreatedAt         meta_v1.Time      `json:"createdAt,omitempty"`
+}
+
+//RolloutStatus returns rollout state of every Deployment of mapped resource sorted by name.
+func RolloutStatus(mappedResource MappedResource) []Rollout {
+	var rollouts []Rollout
+	for _, deployment := range mappedResource.Kube.Deployments {
+		rollouts = append(rollouts, deploymentRollout(deployment, deploymentReplicaSets(deployment, mappedResource.Kube.ReplicaSets)))
+	}
+
+	sort.SliceStable(rollouts, func(i, j int) bool {
+		return rollouts[i].Deployment < rollouts[j].Deployment
+	})
+
+	return rollouts
+}
+
+func deploymentRollout(deployment apps_v1.Deployment, replicaSets []apps_v1.ReplicaSet) Rollout {
+	rollout := Rollout{Deployment: deployment.Name, DesiredReplicas: 1}
+	if deployment.Spec.Replicas != nil {
+		rollout.DesiredReplicas = *deployment.Spec.Replicas
+	}
+
+	for _, replicaSet := range replicaSets {
+		rollout.History = append(rollout.History, RolloutRevision{
+			Revision:          objectRevision(replicaSet.ObjectMeta),
+			ReplicaSet:        replicaSet.Name,
+			Images:            podSpecImagesByContainer(replicaSet.Spec.Template.Spec),
+			Replicas:          replicaSet.Status.Replicas,
+			AvailableReplicas: replicaSet.Status.AvailableReplicas,
+			CreatedAt:         replicaSet.CreationTimestamp,
+		})
+	}
+	sort.SliceStable(rollout.History, func(i, j int) bool {
+		return rollout.History[i].Revision > rollout.History[j].Revision
+	})
+
+	//Deployment is annotated with revision of its newest ReplicaSet. It may lag behind a ReplicaSet just created.
+	rollout.CurrentRevision = objectRevision(deployment.ObjectMeta)
+	if len(rollout.History) > 0 && rollout.History[0].Revision > rollout.CurrentRevision {
+		rollout.CurrentRevision = rollout.History[0].Revision
+	}
+
+	currentFound := false
+	for _, revision := range rollout.History {
+		switch {
+		case revision.Revision == rollout.CurrentRevision && !currentFound:
+			currentFound = true
+			rollout.UpdatedReplicas = revision.Replicas
+			rollout.AvailableReplicas = revision.AvailableReplicas
+		case revision.Revision < rollout.CurrentRevision:
+			if rollout.PreviousRevision == 0 {
+				rollout.PreviousRevision = revision.Revision
+			}
+			rollout.OldReplicas += revision.Replicas
+		}
+	}
+	if !currentFound {
+		rollout.UpdatedReplicas = deployment.Status.UpdatedReplicas
+		rollout.AvailableReplicas = deployment.Status.AvailableReplicas
+	}
+
+	rollout.Progress = 100
+	if rollout.DesiredReplicas > 0 {
+		available := rollout.AvailableReplicas
+		if available > rollout.DesiredReplicas {
+			available = rollout.DesiredReplicas
+		}
+		rollout.Progress = int(available * 100 / rollout.DesiredReplicas)
+	}
+
+	rollout.State = RolloutProgressing
+	for _, condition := range deployment.Status.Conditions {
+		if condition.Type == apps_v1.DeploymentProgressing && condition.Status == core_v1.ConditionFalse && condition.Reason == progressDeadlineExceeded {
+			rollout.State, rollout.Message = RolloutStalled, condition.Message
+		}
+	}
+
+	switch {
+	case rollout.State == RolloutStalled:
+	case deployment.Spec.Paused:
+		rollout.State = RolloutPaused
+	case rollout.Progress == 100 && rollout.OldReplicas == 0 && deployment.Status.ObservedGeneration >= deployment.Generation:
+		rollout.State = RolloutComplete
+	}
+
+	return rollout
+}
+
+//deploymentReplicaSets returns ReplicaSets controlled by deployment. ReplicaSets without owner are matched by selector
+//of Deployment.
+func deploymentReplicaSets(deployment apps_v1.Deployment, replicaSets []apps_v1.ReplicaSet) []apps_v1.ReplicaSet {
+	selector := labels.Nothing()
+	if deployment.Spec.Selector != nil {
+		if deploymentSelector, err := meta_v1.LabelSelectorAsSelector(deployment.Spec.Selector); err == nil {
+			selector = deploymentSelector
+		}
+	}
+
+	var owned []apps_v1.ReplicaSet
+	for _, replicaSet := range replicaSets {
+		if replicaSet.Namespace != deployment.Namespace {
+			continue
+		}
+
+		owner := controllerName(replicaSet.ObjectMeta, "Deployment")
+		if owner == deployment.Name || (owner == "" && selector.Matches(labels.Set(replicaSet.Spec.Template.Labels))) {
+			owned = append(owned, replicaSet)
+		}
+	}
+
+	return owned
+}
+
+//objectRevision returns revision annotated on object. Zero if not annotated.
+func objectRevision(objectMeta meta_v1.ObjectMeta) int64 {
+	revision, err := strconv.ParseInt(objectMeta.Annotations[DeploymentRevisionAnnotation], 10, 64)
+	if err != nil {
+		return 0
+	}
+
+	return revision
+}
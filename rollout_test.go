@@ -0,0 +1,179 @@
+package kubemap
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	apps_v1 "k8s.io/api/apps/v1"
+	core_v1 "k8s.io/api/core/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestRolloutStatus(t *testing.T) {
+	tests := []struct {
+		name     string
+		modify   func(resources *KubeResources)
+		state    string
+		current  int64
+		previous int64
+		progress int
+		old      int32
+		message  string
+	}{
+		{
+			name:     "complete",
+			modify:   func(resources *KubeResources) {},
+			state:    RolloutComplete,
+			current:  2,
+			previous: 1,
+			progress: 100,
+		},
+		{
+			name: "progressing",
+			modify: func(resources *KubeResources) {
+				resources.ReplicaSets[0].Status.AvailableReplicas = 0
+				resources.ReplicaSets[1].Status.Replicas = 1
+			},
+			state:    RolloutProgressing,
+			current:  2,
+			previous: 1,
+			old:      1,
+		},
+		{
+			name: "old replicas left",
+			modify: func(resources *KubeResources) {
+				resources.ReplicaSets[1].Status.Replicas = 1
+			},
+			state:    RolloutProgressing,
+			current:  2,
+			previous: 1,
+			progress: 100,
+			old:      1,
+		},
+		{
+			name: "stalled",
+			modify: func(resources *KubeResources) {
+				resources.ReplicaSets[0].Status.AvailableReplicas = 0
+				resources.Deployments[0].Status.Conditions = []apps_v1.DeploymentCondition{{
+					Type:    apps_v1.DeploymentProgressing,
+					Status:  core_v1.ConditionFalse,
+					Reason:  progressDeadlineExceeded,
+					Message: `ReplicaSet "kube-map-644c5c58fc" has timed out progressing.`,
+				}}
+			},
+			state:    RolloutStalled,
+			current:  2,
+			previous: 1,
+			message:  `ReplicaSet "kube-map-644c5c58fc" has timed out progressing.`,
+		},
+		{
+			name: "paused",
+			modify: func(resources *KubeResources) {
+				resources.Deployments[0].Spec.Paused = true
+			},
+			state:    RolloutPaused,
+			current:  2,
+			previous: 1,
+			progress: 100,
+		},
+		{
+			name: "deployment annotation lags behind",
+			modify: func(resources *KubeResources) {
+				resources.Deployments[0].Annotations[DeploymentRevisionAnnotation] = "1"
+			},
+			state:    RolloutComplete,
+			current:  2,
+			previous: 1,
+			progress: 100,
+		},
+		{
+			name: "generation not observed",
+			modify: func(resources *KubeResources) {
+				resources.Deployments[0].Generation = 3
+				resources.Deployments[0].Status.ObservedGeneration = 2
+			},
+			state:    RolloutProgressing,
+			current:  2,
+			previous: 1,
+			progress: 100,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resources := helperGetRolloutResources()
+			tt.modify(&resources)
+
+			mappedResources, err := NewMapper().Map(resources)
+			assert.Nil(t, err)
+			assert.Len(t, mappedResources.MappedResource, 1)
+
+			rollouts := mappedResources.MappedResource[0].Rollouts
+			assert.Len(t, rollouts, 1)
+			assert.Equal(t, "kube-map", rollouts[0].Deployment)
+			assert.Equal(t, tt.state, rollouts[0].State)
+			assert.Equal(t, tt.current, rollouts[0].CurrentRevision)
+			assert.Equal(t, tt.previous, rollouts[0].PreviousRevision)
+			assert.Equal(t, tt.progress, rollouts[0].Progress)
+			assert.Equal(t, tt.old, rollouts[0].OldReplicas)
+			assert.Equal(t, tt.message, rollouts[0].Message)
+		})
+	}
+}
+
+func TestRolloutHistory(t *testing.T) {
+	resources := helperGetRolloutResources()
+	//ReplicaSets created without owner are matched by selector of Deployment.
+	resources.ReplicaSets[1].OwnerReferences = nil
+
+	rollouts := RolloutStatus(MappedResource{Kube: Kube{Deployments: resources.Deployments, ReplicaSets: resources.ReplicaSets}})
+	assert.Len(t, rollouts, 1)
+	assert.Equal(t, []RolloutRevision{
+		{Revision: 2, ReplicaSet: "kube-map-644c5c58fc", Images: map[string]string{"kube-map": "some/random/image"}, Replicas: 1, AvailableReplicas: 1, CreatedAt: resources.ReplicaSets[0].CreationTimestamp},
+		{Revision: 1, ReplicaSet: "kube-map-5d8f7b", Images: map[string]string{"kube-map": "some/random/image:v1"}},
+	}, rollouts[0].History)
+}
+
+func TestRolloutWithoutRevisions(t *testing.T) {
+	resources := helperGetK8sResources()
+	resources.Deployments[0].Annotations = nil
+	resources.Deployments[0].Status.AvailableReplicas = 1
+	resources.Deployments[0].Status.ObservedGeneration = resources.Deployments[0].Generation
+
+	rollouts := RolloutStatus(MappedResource{Kube: Kube{Deployments: resources.Deployments}})
+	assert.Len(t, rollouts, 1)
+	assert.Equal(t, int64(0), rollouts[0].CurrentRevision)
+	assert.Equal(t, 100, rollouts[0].Progress)
+	assert.Equal(t, RolloutComplete, rollouts[0].State)
+}
+
+//helperGetRolloutResources returns fixtures where deployment is at revision 2, fully available, with a scaled down
+//ReplicaSet of revision 1.
+func helperGetRolloutResources() KubeResources {
+	resources := helperGetK8sResources()
+
+	deployment := &resources.Deployments[0]
+	deployment.Annotations = map[string]string{DeploymentRevisionAnnotation: "2"}
+	deployment.Status.AvailableReplicas = 1
+	deployment.Status.ObservedGeneration = deployment.Generation
+
+	replicaSet := &resources.ReplicaSets[0]
+	replicaSet.Annotations = map[string]string{DeploymentRevisionAnnotation: "2"}
+	replicaSet.Status.Replicas = 1
+	replicaSet.Status.AvailableReplicas = 1
+
+	replicas := int32(0)
+	oldReplicaSet := replicaSet.DeepCopy()
+	oldReplicaSet.Name = "kube-map-5d8f7b"
+	oldReplicaSet.UID = types.UID("kube-map-5d8f7b")
+	oldReplicaSet.CreationTimestamp = meta_v1.Time{}
+	oldReplicaSet.Annotations = map[string]string{DeploymentRevisionAnnotation: "1"}
+	oldReplicaSet.Spec.Replicas = &replicas
+	oldReplicaSet.Spec.Template.Spec.Containers[0].Image = "some/random/image:v1"
+	oldReplicaSet.Status = apps_v1.ReplicaSetStatus{}
+	resources.ReplicaSets = append(resources.ReplicaSets, *oldReplicaSet)
+
+	return resources
+}
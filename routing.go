@@ -0,0 +1,330 @@
+package kubemap
+
+import (
+	"sort"
+
+	core_v1 "k8s.io/api/core/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+const (
+	//RouteSourceIngress marks routes of ingress rules.
+	RouteSourceIngress = "ingress"
+	//RouteSourceHTTPRoute marks routes of HTTPRoute rules.
+	RouteSourceHTTPRoute = "httproute"
+)
+
+const (
+	//RouteProblemServiceNotFound means backend service is not a member of group.
+	RouteProblemServiceNotFound = "ServiceNotFound"
+	//RouteProblemServicePortNotFound means backend port is not exposed by service.
+	RouteProblemServicePortNotFound = "ServicePortNotFound"
+	//RouteProblemNamedPortNotFound means target port of service is a name which some pods do not define.
+	RouteProblemNamedPortNotFound = "NamedPortNotFound"
+	//RouteProblemNoPods means selector of service matches no pod of group.
+	RouteProblemNoPods = "NoPods"
+	//RouteProblemNoReadyPods means no pod behind route is ready, which usually answers with 503.
+	RouteProblemNoReadyPods = "NoReadyPods"
+	//RouteProblemReferenceNotPermitted means an HTTPRoute of other namespace has no ReferenceGrant for service.
+	RouteProblemReferenceNotPermitted = "ReferenceNotPermitted"
+)
+
+//Route is path of a request from host and path to pods of group.
+type Route struct {
+	//Host is '*' if rule matches any host.
+	Host string `json:"host"`
+	//Path is empty if rule matches any path.
+	Path string `json:"path,omitempty"`
+	//Source is either 'ingress' or 'httproute'.
+	Source string `json:"source"`
+	//Name is name of ingress or HTTPRoute.
+	Name    string `json:"name"`
+	Service string `json:"service"`
+	//ServicePort is backend port as given by rule, either a number or a name.
+	ServicePort string `json:"servicePort,omitempty"`
+	//Port is resolved port of service. Zero if it cannot be resolved.
+	Port int32 `json:"port,omitempty"`
+	//TargetPort is target port of service port, either a number or a name of a container port.
+	TargetPort string     `json:"targetPort,omitempty"`
+	Pods       []RoutePod `json:"pods,omitempty"`
+	Problems   []string   `json:"problems,omitempty"`
+}
+
+//RoutePod is a pod which receives traffic of a route.
+type RoutePod struct {
+	Name  string `json:"name"`
+	Ready bool   `json:"ready"`
+	//Port is container port receiving traffic. Zero if named target port is not defined by pod.
+	Port int32 `json:"port,omitempty"`
+}
+
+//routeBackend is a service and its port referred by a rule.
+type routeBackend struct {
+	host, path, source, name string
+	service                  string
+	servicePort              intstr.IntOrString
+	problems                 []string
+}
+
+//RoutingTable returns routes of ingresses and HTTPRoutes of mapped resource sorted by host and path.
+func RoutingTable(mappedResource MappedResource) []Route {
+	var routes []Route
+	for _, backend := range routeBackends(mappedResource) {
+		routes = append(routes, resolveRoute(backend, mappedResource))
+	}
+
+	sort.SliceStable(routes, func(i, j int) bool {
+		a, b := routes[i], routes[j]
+		if a.Host != b.Host {
+			return a.Host < b.Host
+		}
+		if a.Path != b.Path {
+			return a.Path < b.Path
+		}
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		return a.Service < b.Service
+	})
+
+	return routes
+}
+
+func routeBackends(mappedResource MappedResource) []routeBackend {
+	var backends []routeBackend
+
+	for _, ingress := range mappedResource.Kube.Ingresses {
+		if ingress.Spec.Backend != nil {
+			backends = append(backends, routeBackend{
+				host:        "*",
+				source:      RouteSourceIngress,
+				name:        ingress.Name,
+				service:     ingress.Spec.Backend.ServiceName,
+				servicePort: ingress.Spec.Backend.ServicePort,
+			})
+		}
+
+		for _, rule := range ingress.Spec.Rules {
+			if rule.HTTP == nil {
+				continue
+			}
+			host := rule.Host
+			if host == "" {
+				host = "*"
+			}
+
+			for _, path := range rule.HTTP.Paths {
+				backends = append(backends, routeBackend{
+					host:        host,
+					path:        path.Path,
+					source:      RouteSourceIngress,
+					name:        ingress.Name,
+					service:     path.Backend.ServiceName,
+					servicePort: path.Backend.ServicePort,
+				})
+			}
+		}
+	}
+
+	//Only backends of HTTPRoutes which are services of group are known.
+	for _, route := range mappedResource.Kube.HTTPRoutes {
+		hosts := route.Spec.Hostnames
+		if len(hosts) == 0 {
+			hosts = []string{"*"}
+		}
+
+		for _, rule := range route.Spec.Rules {
+			paths := []string{"/"}
+			if len(rule.Matches) > 0 {
+				paths = nil
+				for _, match := range rule.Matches {
+					path := "/"
+					if match.Path != nil && match.Path.Value != nil {
+						path = *match.Path.Value
+					}
+					paths = append(paths, path)
+				}
+			}
+
+			for _, backendRef := range rule.BackendRefs {
+				resolved, ok := resolveBackendRef("HTTPRoute", route.ObjectMeta, backendRef, mappedResource, nil)
+				if !ok {
+					continue
+				}
+
+				var problems []string
+				if !httpRouteBackendPermitted(route.ObjectMeta, backendRef.Name, mappedResource.Kube.RouteBackends) {
+					problems = append(problems, RouteProblemReferenceNotPermitted)
+				}
+
+				var servicePort intstr.IntOrString
+				if resolved.Port != nil {
+					servicePort = intstr.FromInt(int(*resolved.Port))
+				}
+
+				for _, host := range hosts {
+					for _, path := range removeDuplicateStrings(paths) {
+						backends = append(backends, routeBackend{
+							host:        host,
+							path:        path,
+							source:      RouteSourceHTTPRoute,
+							name:        route.Name,
+							service:     resolved.Service,
+							servicePort: servicePort,
+							problems:    problems,
+						})
+					}
+				}
+			}
+		}
+	}
+
+	return backends
+}
+
+//httpRouteBackendPermitted checks resolution of backend done when route was attached.
+func httpRouteBackendPermitted(routeMeta meta_v1.ObjectMeta, service string, routeBackends []RouteBackend) bool {
+	for _, routeBackend := range routeBackends {
+		if routeBackend.RouteKind == "HTTPRoute" && routeBackend.RouteName == routeMeta.Name && routeBackend.RouteNamespace == routeMeta.Namespace && routeBackend.Service == service {
+			return routeBackend.Permitted
+		}
+	}
+
+	return true
+}
+
+//resolveRoute follows backend to service port, target port and pods selected by service.
+func resolveRoute(backend routeBackend, mappedResource MappedResource) Route {
+	route := Route{
+		Host:     backend.host,
+		Path:     backend.path,
+		Source:   backend.source,
+		Name:     backend.name,
+		Service:  backend.service,
+		Problems: append([]string(nil), backend.problems...),
+	}
+	if backend.servicePort.IntValue() != 0 || backend.servicePort.Type == intstr.String {
+		route.ServicePort = backend.servicePort.String()
+	}
+
+	var service *core_v1.Service
+	for i := range mappedResource.Kube.Services {
+		if mappedResource.Kube.Services[i].Name == backend.service {
+			service = &mappedResource.Kube.Services[i]
+			break
+		}
+	}
+	if service == nil {
+		route.Problems = append(route.Problems, RouteProblemServiceNotFound)
+		return route
+	}
+
+	servicePort, ok := findServicePort(*service, backend.servicePort)
+	if !ok {
+		route.Problems = append(route.Problems, RouteProblemServicePortNotFound)
+		return route
+	}
+	route.Port = servicePort.Port
+
+	targetPort := servicePort.TargetPort
+	if targetPort.Type == intstr.Int && targetPort.IntVal == 0 {
+		targetPort = intstr.FromInt(int(servicePort.Port))
+	}
+	route.TargetPort = targetPort.String()
+
+	//Services without selector have endpoints managed by someone else and no pods of group to resolve.
+	if len(service.Spec.Selector) == 0 {
+		return route
+	}
+
+	selector := labels.SelectorFromSet(service.Spec.Selector)
+	namedPortMissing, ready := false, false
+	for _, pod := range mappedResource.Kube.Pods {
+		if !selector.Matches(labels.Set(pod.Labels)) || pod.Status.Phase == core_v1.PodSucceeded || pod.Status.Phase == core_v1.PodFailed {
+			continue
+		}
+
+		routePod := RoutePod{Name: pod.Name, Ready: isPodReady(pod), Port: containerPort(pod, targetPort)}
+		namedPortMissing = namedPortMissing || routePod.Port == 0
+		ready = ready || routePod.Ready
+		route.Pods = append(route.Pods, routePod)
+	}
+
+	switch {
+	case len(route.Pods) == 0:
+		route.Problems = append(route.Problems, RouteProblemNoPods)
+	case !ready:
+		route.Problems = append(route.Problems, RouteProblemNoReadyPods)
+	}
+	if namedPortMissing {
+		route.Problems = append(route.Problems, RouteProblemNamedPortNotFound)
+	}
+
+	return route
+}
+
+//findServicePort returns port of service given by number or name. Service with a single port serves backends
+//without port.
+func findServicePort(service core_v1.Service, port intstr.IntOrString) (core_v1.ServicePort, bool) {
+	for _, servicePort := range service.Spec.Ports {
+		if port.Type == intstr.String && port.StrVal != "" && servicePort.Name == port.StrVal {
+			return servicePort, true
+		}
+		if port.Type == intstr.Int && port.IntVal != 0 && servicePort.Port == port.IntVal {
+			return servicePort, true
+		}
+	}
+
+	if port.IntValue() == 0 && port.StrVal == "" && len(service.Spec.Ports) == 1 {
+		return service.Spec.Ports[0], true
+	}
+
+	return core_v1.ServicePort{}, false
+}
+
+//containerPort resolves target port against container ports of pod. Zero if named port is not defined.
+func containerPort(pod core_v1.Pod, targetPort intstr.IntOrString) int32 {
+	if targetPort.Type == intstr.Int {
+		return targetPort.IntVal
+	}
+
+	for _, container := range pod.Spec.Containers {
+		for _, port := range container.Ports {
+			if port.Name == targetPort.StrVal {
+				return port.ContainerPort
+			}
+		}
+	}
+
+	return 0
+}
+
+func isPodReady(pod core_v1.Pod) bool {
+	if pod.Status.Phase != core_v1.PodRunning {
+		return false
+	}
+
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == core_v1.PodReady {
+			return condition.Status == core_v1.ConditionTrue
+		}
+	}
+
+	return false
+}
+
+//MatchesRequest checks if route serves a request of host and path. Empty host or path matches any.
+//Paths match by prefix as most ingress controllers do.
+func (r Route) MatchesRequest(host, path string) bool {
+	if host != "" && r.Host != "*" && r.Host != host {
+		return false
+	}
+
+	if path == "" || r.Path == "" || r.Path == "/" {
+		return true
+	}
+
+	return len(path) >= len(r.Path) && path[:len(r.Path)] == r.Path && (len(path) == len(r.Path) || r.Path[len(r.Path)-1] == '/' || path[len(r.Path)] == '/')
+}
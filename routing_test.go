@@ -0,0 +1,163 @@
+package kubemap
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	core_v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+func TestRoutingTable(t *testing.T) {
+	tests := []struct {
+		name     string
+		modify   func(resources *KubeResources)
+		port     int32
+		target   string
+		pods     []RoutePod
+		problems []string
+	}{
+		{
+			name:     "pods are not ready",
+			modify:   func(resources *KubeResources) {},
+			port:     8085,
+			target:   "8085",
+			pods:     []RoutePod{{Name: "kube-map-644c5c58fc-ggdmn", Port: 8085}},
+			problems: []string{RouteProblemNoReadyPods},
+		},
+		{
+			name:   "pods are ready",
+			modify: helperReadyPods,
+			port:   8085,
+			target: "8085",
+			pods:   []RoutePod{{Name: "kube-map-644c5c58fc-ggdmn", Ready: true, Port: 8085}},
+		},
+		{
+			name: "named target port",
+			modify: func(resources *KubeResources) {
+				helperReadyPods(resources)
+				resources.Services[0].Spec.Ports[0].TargetPort = intstr.FromString("http")
+				resources.Pods[0].Spec.Containers[0].Ports = []core_v1.ContainerPort{{Name: "http", ContainerPort: 9000}}
+			},
+			port:   8085,
+			target: "http",
+			pods:   []RoutePod{{Name: "kube-map-644c5c58fc-ggdmn", Ready: true, Port: 9000}},
+		},
+		{
+			name: "named target port not defined by pod",
+			modify: func(resources *KubeResources) {
+				helperReadyPods(resources)
+				resources.Services[0].Spec.Ports[0].TargetPort = intstr.FromString("http")
+			},
+			port:     8085,
+			target:   "http",
+			pods:     []RoutePod{{Name: "kube-map-644c5c58fc-ggdmn", Ready: true}},
+			problems: []string{RouteProblemNamedPortNotFound},
+		},
+		{
+			name: "service port not found",
+			modify: func(resources *KubeResources) {
+				resources.Ingresses[0].Spec.Rules[0].HTTP.Paths[0].Backend.ServicePort = intstr.FromString("grpc")
+			},
+			problems: []string{RouteProblemServicePortNotFound},
+		},
+		{
+			name: "no pods",
+			modify: func(resources *KubeResources) {
+				resources.Pods[0].Status.Phase = core_v1.PodSucceeded
+			},
+			port:     8085,
+			target:   "8085",
+			problems: []string{RouteProblemNoPods},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resources := helperGetK8sResources()
+			tt.modify(&resources)
+
+			mappedResources, err := NewMapper().Map(resources)
+			assert.Nil(t, err)
+			assert.Len(t, mappedResources.MappedResource, 1)
+
+			routes := mappedResources.MappedResource[0].Routes
+			assert.Len(t, routes, 1)
+			assert.Equal(t, "some.dns.somecompany.com", routes[0].Host)
+			assert.Equal(t, "/", routes[0].Path)
+			assert.Equal(t, RouteSourceIngress, routes[0].Source)
+			assert.Equal(t, "kube-map", routes[0].Service)
+			assert.Equal(t, tt.port, routes[0].Port)
+			assert.Equal(t, tt.target, routes[0].TargetPort)
+			assert.Equal(t, tt.pods, routes[0].Pods)
+			assert.Equal(t, tt.problems, routes[0].Problems)
+		})
+	}
+}
+
+func TestRoutingTableServiceNotFound(t *testing.T) {
+	resources := helperGetK8sResources()
+	mappedResources, err := NewMapper().Map(resources)
+	assert.Nil(t, err)
+
+	mappedResource := mappedResources.MappedResource[0]
+	mappedResource.Kube.Services = nil
+
+	routes := RoutingTable(mappedResource)
+	assert.Len(t, routes, 1)
+	assert.Equal(t, []string{RouteProblemServiceNotFound}, routes[0].Problems)
+}
+
+func TestRoutingTableOfHTTPRoutes(t *testing.T) {
+	resources := helperGetGatewayResources()
+	helperReadyPods(&resources)
+
+	mappedResources, err := NewMapper().Map(resources)
+	assert.Nil(t, err)
+
+	var httpRoutes []Route
+	for _, route := range mappedResources.MappedResource[0].Routes {
+		if route.Source == RouteSourceHTTPRoute {
+			httpRoutes = append(httpRoutes, route)
+		}
+	}
+
+	assert.Len(t, httpRoutes, 1)
+	assert.Equal(t, "shop.example.com", httpRoutes[0].Host)
+	assert.Equal(t, "/", httpRoutes[0].Path)
+	assert.Equal(t, "web", httpRoutes[0].Name)
+	assert.Equal(t, "80", httpRoutes[0].ServicePort)
+	assert.Equal(t, []string{RouteProblemServicePortNotFound}, httpRoutes[0].Problems)
+}
+
+func TestRouteMatchesRequest(t *testing.T) {
+	tests := []struct {
+		route Route
+		host  string
+		path  string
+		want  bool
+	}{
+		{Route{Host: "a.com", Path: "/api"}, "", "", true},
+		{Route{Host: "a.com", Path: "/api"}, "a.com", "/api", true},
+		{Route{Host: "a.com", Path: "/api"}, "a.com", "/api/v1", true},
+		{Route{Host: "a.com", Path: "/api"}, "a.com", "/apiv1", false},
+		{Route{Host: "a.com", Path: "/api/"}, "a.com", "/api/v1", true},
+		{Route{Host: "a.com", Path: "/api"}, "b.com", "/api", false},
+		{Route{Host: "*", Path: "/api"}, "b.com", "/api", true},
+		{Route{Host: "a.com", Path: "/"}, "a.com", "/static", true},
+		{Route{Host: "a.com", Path: "/api"}, "a.com", "/", false},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, tt.route.MatchesRequest(tt.host, tt.path), "%+v %s%s", tt.route, tt.host, tt.path)
+	}
+}
+
+//helperReadyPods marks all pods of resources running and ready.
+func helperReadyPods(resources *KubeResources) {
+	for i := range resources.Pods {
+		resources.Pods[i].Status.Phase = core_v1.PodRunning
+		resources.Pods[i].Status.Conditions = []core_v1.PodCondition{{Type: core_v1.PodReady, Status: core_v1.ConditionTrue}}
+	}
+}
@@ -0,0 +1,123 @@
+package kubemap
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	//SnapshotFormatJSON writes snapshot as plain JSON.
+	SnapshotFormatJSON = "json"
+	//SnapshotFormatGzip writes snapshot as gzip compressed JSON.
+	SnapshotFormatGzip = "gzip"
+
+	//snapshotVersion is schema version of snapshots written by this package.
+	//Bump it whenever MappedResource changes in a way older readers can't handle.
+	snapshotVersion = 1
+)
+
+//snapshot is the envelope persisted by Snapshot and read by RestoreMapper.
+type snapshot struct {
+	Version         int              `json:"version"`
+	CreatedAt       time.Time        `json:"createdAt"`
+	MappedResources []MappedResource `json:"mappedResources,omitempty"`
+}
+
+//Snapshot writes all mapped resources in store to w.
+//Format is taken from MapOptions.Snapshot and defaults to JSON. Mapped resources are written as kept in store, so that
+//RestoreMapper restores them as they were. Hence snapshot holds literal env values, though never data of secrets, and
+//is to be stored as carefully as the cluster state it is taken from. Outputs of restored Mapper are redacted as usual.
+func (m *Mapper) Snapshot(w io.Writer) error {
+	snap := snapshot{
+		Version:   snapshotVersion,
+		CreatedAt: time.Now().UTC(),
+	}
+
+	keys := m.store.ListKeys()
+	sort.Strings(keys)
+	for _, key := range keys {
+		mappedResource, err := getObjectFromStore(key, m.store)
+		if err != nil {
+			return err
+		}
+		snap.MappedResources = append(snap.MappedResources, mappedResource)
+	}
+
+	switch strings.ToLower(m.options.Snapshot.Format) {
+	case "", SnapshotFormatJSON:
+		return json.NewEncoder(w).Encode(snap)
+	case SnapshotFormatGzip:
+		gzipWriter := gzip.NewWriter(w)
+		if err := json.NewEncoder(gzipWriter).Encode(snap); err != nil {
+			gzipWriter.Close()
+			return err
+		}
+		return gzipWriter.Close()
+	}
+
+	return fmt.Errorf("Cannot write snapshot. Invalid format %s provided. Accepted values are '%s' & '%s'", m.options.Snapshot.Format, SnapshotFormatJSON, SnapshotFormatGzip)
+}
+
+//RestoreMapper creates a Mapper with options whose store is populated from a snapshot.
+//Both JSON and gzip snapshots are accepted irrespective of MapOptions.Snapshot.
+func RestoreMapper(r io.Reader, options MapOptions) (*Mapper, error) {
+	mapper, err := NewMapperWithOptions(options)
+	if err != nil {
+		return nil, err
+	}
+
+	snap, err := readSnapshot(r)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, mappedResource := range snap.MappedResources {
+		if err := mapper.store.Add(mappedResource); err != nil {
+			return nil, fmt.Errorf("Cannot restore Common Label %s - %v", mappedResource.CommonLabel, err)
+		}
+		mapper.related.addAttached(mappedResource)
+	}
+
+	mapper.info(fmt.Sprintf("Restored %d mapped resources from snapshot created at %s", len(snap.MappedResources), snap.CreatedAt.Format(time.RFC3339)))
+	return mapper, nil
+}
+
+func readSnapshot(r io.Reader) (snapshot, error) {
+	var snap snapshot
+
+	bufferedReader := bufio.NewReader(r)
+	magic, err := bufferedReader.Peek(2)
+	if err != nil {
+		return snap, fmt.Errorf("Cannot read snapshot - %v", err)
+	}
+
+	var reader io.Reader = bufferedReader
+	if magic[0] == 0x1f && magic[1] == 0x8b {
+		gzipReader, err := gzip.NewReader(bufferedReader)
+		if err != nil {
+			return snap, fmt.Errorf("Cannot read gzip snapshot - %v", err)
+		}
+		defer gzipReader.Close()
+		reader = gzipReader
+	}
+
+	if err := json.NewDecoder(reader).Decode(&snap); err != nil {
+		return snap, fmt.Errorf("Cannot decode snapshot - %v", err)
+	}
+
+	if snap.Version == 0 {
+		return snap, fmt.Errorf("Cannot restore snapshot. Schema version is missing")
+	}
+
+	if snap.Version > snapshotVersion {
+		return snap, fmt.Errorf("Cannot restore snapshot. Schema version %d is newer than supported version %d", snap.Version, snapshotVersion)
+	}
+
+	return snap, nil
+}
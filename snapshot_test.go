@@ -0,0 +1,139 @@
+package kubemap
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	core_v1 "k8s.io/api/core/v1"
+)
+
+func TestSnapshotAndRestore(t *testing.T) {
+	snapshotTests := map[string]struct {
+		format string
+	}{
+		"With_Default_Format": {
+			format: "",
+		},
+		"With_JSON_Format": {
+			format: SnapshotFormatJSON,
+		},
+		"With_GZIP_Format": {
+			format: SnapshotFormatGzip,
+		},
+	}
+
+	for testName, test := range snapshotTests {
+		t.Run(testName, func(t *testing.T) {
+			options := MapOptions{
+				Snapshot: SnapshotOptions{
+					Format: test.format,
+				},
+			}
+
+			mapper, _ := NewMapperWithOptions(options)
+			mappedResources, _ := mapper.Map(helperGetK8sResources())
+
+			var buffer bytes.Buffer
+			err := mapper.Snapshot(&buffer)
+			assert.Nil(t, err)
+
+			restoredMapper, err := RestoreMapper(&buffer, options)
+			assert.Nil(t, err)
+			assert.NotNil(t, restoredMapper)
+
+			restoredResources := getAllMappedResources(restoredMapper.store)
+			assert.Equal(t, len(mappedResources.MappedResource), len(restoredResources.MappedResource))
+			assert.Equal(t, mappedResources.MappedResource[0].CommonLabel, restoredResources.MappedResource[0].CommonLabel)
+			assert.Equal(t, groupMembers(mappedResources.MappedResource[0]), groupMembers(restoredResources.MappedResource[0]))
+		})
+	}
+}
+
+func TestRestoredMapperKeepsMapping(t *testing.T) {
+	resources := helperGetK8sResources()
+
+	mapper, _ := NewMapperWithOptions(MapOptions{})
+	for _, event := range resourceEventsForMapping(KubeResources{Services: resources.Services, Deployments: resources.Deployments}) {
+		mapper.StoreMap(event)
+	}
+
+	var buffer bytes.Buffer
+	assert.Nil(t, mapper.Snapshot(&buffer))
+
+	restoredMapper, err := RestoreMapper(&buffer, MapOptions{})
+	assert.Nil(t, err)
+
+	results, err := restoredMapper.StoreMap(resourceEventsForMapping(KubeResources{Pods: resources.Pods})[0])
+	assert.Nil(t, err)
+	assert.Equal(t, "Updated", results[0].Action)
+	assert.Len(t, getAllMappedResources(restoredMapper.store).MappedResource, 1)
+}
+
+func TestRestoredMapperKeepsEnvValues(t *testing.T) {
+	resources := helperGetK8sResources()
+	resources.Pods = []core_v1.Pod{helperGetBloatedPod(resources.Pods[0].Name)}
+
+	mapper := NewMapper()
+	_, err := mapper.Map(resources)
+	assert.Nil(t, err)
+
+	var buffer bytes.Buffer
+	assert.Nil(t, mapper.Snapshot(&buffer))
+	content := buffer.String()
+
+	restoredMapper, err := RestoreMapper(strings.NewReader(content), MapOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, getAllMappedResources(mapper.store), getAllMappedResources(restoredMapper.store))
+
+	results, err := restoredMapper.StoreMap(resourceEventsForMapping(KubeResources{Services: resources.Services})[0])
+	assert.Nil(t, err)
+	output, _ := json.Marshal(results)
+	assert.NotContains(t, string(output), "hunter2")
+
+	loaded, err := LoadSnapshot(strings.NewReader(content))
+	assert.Nil(t, err)
+	output, _ = json.Marshal(loaded)
+	assert.NotContains(t, string(output), "hunter2")
+}
+
+func TestSnapshotWithInvalidFormat(t *testing.T) {
+	mapper, _ := NewMapperWithOptions(MapOptions{
+		Snapshot: SnapshotOptions{
+			Format: "xml",
+		},
+	})
+
+	var buffer bytes.Buffer
+	assert.NotNil(t, mapper.Snapshot(&buffer))
+}
+
+func TestRestoreMapperWithInvalidSnapshot(t *testing.T) {
+	restoreTests := map[string]struct {
+		snapshot string
+	}{
+		"With_Empty_Snapshot": {
+			snapshot: "",
+		},
+		"With_Missing_Version": {
+			snapshot: `{"mappedResources":[]}`,
+		},
+		"With_Newer_Version": {
+			snapshot: `{"version":999,"mappedResources":[]}`,
+		},
+		"With_Invalid_JSON": {
+			snapshot: `{"version":`,
+		},
+	}
+
+	for testName, test := range restoreTests {
+		t.Run(testName, func(t *testing.T) {
+			mapper, err := RestoreMapper(strings.NewReader(test.snapshot), MapOptions{})
+			assert.Nil(t, mapper)
+			assert.NotNil(t, err)
+		})
+	}
+}
@@ -3,8 +3,14 @@ package kubemap
 import (
 	"go.uber.org/zap"
 	apps_v1 "k8s.io/api/apps/v1"
+	autoscaling_v1 "k8s.io/api/autoscaling/v1"
+	autoscaling_v2beta2 "k8s.io/api/autoscaling/v2beta2"
 	core_v1 "k8s.io/api/core/v1"
+	network_v1 "k8s.io/api/networking/v1"
 	network_v1beta1 "k8s.io/api/networking/v1beta1"
+	policy_v1beta1 "k8s.io/api/policy/v1beta1"
+	rbac_v1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/util/workqueue"
 )
@@ -17,6 +23,38 @@ type KubeResources struct {
 	Deployments []apps_v1.Deployment
 	ReplicaSets []apps_v1.ReplicaSet
 	Pods        []core_v1.Pod
+	//HorizontalPodAutoscalers are attached to group of their scale target.
+	HorizontalPodAutoscalers   []autoscaling_v2beta2.HorizontalPodAutoscaler
+	HorizontalPodAutoscalersV1 []autoscaling_v1.HorizontalPodAutoscaler
+	//ConfigMaps, Secrets and PersistentVolumeClaims are attached to groups whose pod specs refer to them.
+	//Data of secrets is dropped.
+	ConfigMaps             []core_v1.ConfigMap
+	Secrets                []core_v1.Secret
+	PersistentVolumeClaims []core_v1.PersistentVolumeClaim
+	//PersistentVolumes are attached along with claims bound to them.
+	PersistentVolumes []core_v1.PersistentVolume
+	//Endpoints and discovery.k8s.io/v1 EndpointSlices are attached to group of their service.
+	Endpoints      []core_v1.Endpoints
+	EndpointSlices []unstructured.Unstructured
+	//PodDisruptionBudgets and NetworkPolicies are attached to groups whose pods they select.
+	PodDisruptionBudgets []policy_v1beta1.PodDisruptionBudget
+	NetworkPolicies      []network_v1.NetworkPolicy
+	//ServiceAccounts of pods are attached along with bindings naming them and roles of those bindings.
+	ServiceAccounts     []core_v1.ServiceAccount
+	Roles               []rbac_v1.Role
+	RoleBindings        []rbac_v1.RoleBinding
+	ClusterRoles        []rbac_v1.ClusterRole
+	ClusterRoleBindings []rbac_v1.ClusterRoleBinding
+	//CustomResources are attached to groups as per MapOptions.CustomResources.
+	CustomResources []unstructured.Unstructured
+	//HTTPRoutes and GRPCRoutes are attached to groups of their backend services, along with their Gateways
+	//and ReferenceGrants permitting backends of other namespaces.
+	Gateways        []unstructured.Unstructured
+	HTTPRoutes      []unstructured.Unstructured
+	GRPCRoutes      []unstructured.Unstructured
+	ReferenceGrants []unstructured.Unstructured
+	//Nodes which pods run on are attached to their groups. Placement of groups uses zone labels of nodes.
+	Nodes []core_v1.Node
 }
 
 //MappedResource is final mapped output of interlinked K8s resources
@@ -25,7 +63,13 @@ type MappedResource struct {
 	Namespace   string `json:"namespace,omitempty"`
 	CurrentType string `json:"currentType,omitempty"`
 	EventType   string `json:"eventType,omitempty"`
+	//Application is name of parent application when label based grouping is enabled.
+	Application string `json:"application,omitempty"`
 	Kube        Kube   `json:"kube,omitempty"`
+	//Routes from hosts and paths of ingresses and HTTPRoutes to pods of group. See RoutingTable.
+	Routes []Route `json:"routes,omitempty"`
+	//Rollouts are rollout states of deployments of group. See RolloutStatus.
+	Rollouts []Rollout `json:"rollouts,omitempty"`
 }
 
 //Kube ...
@@ -36,18 +80,58 @@ type Kube struct {
 	ReplicaSets []apps_v1.ReplicaSet      `json:"replicaSets,omitempty"`
 	Pods        []core_v1.Pod             `json:"pods,omitempty"`
 	Events      []core_v1.Event           `json:"events,omitempty"`
+	//HorizontalPodAutoscalers scaling deployments or replica sets of group. autoscaling/v1 objects are converted.
+	HorizontalPodAutoscalers []autoscaling_v2beta2.HorizontalPodAutoscaler `json:"horizontalPodAutoscalers,omitempty"`
+	ConfigMaps               []core_v1.ConfigMap                           `json:"configMaps,omitempty"`
+	//Secrets referenced by group. Only metadata and type are kept.
+	Secrets                []core_v1.Secret                `json:"secrets,omitempty"`
+	PersistentVolumeClaims []core_v1.PersistentVolumeClaim `json:"persistentVolumeClaims,omitempty"`
+	PersistentVolumes      []core_v1.PersistentVolume      `json:"persistentVolumes,omitempty"`
+	//MissingReferences are config maps, secrets and claims referenced by group which do not exist.
+	MissingReferences []MissingReference  `json:"missingReferences,omitempty"`
+	Endpoints         []core_v1.Endpoints `json:"endpoints,omitempty"`
+	EndpointSlices    []EndpointSlice     `json:"endpointSlices,omitempty"`
+	//ServiceEndpoints are addresses receiving traffic of services of group as per endpoints and slices.
+	ServiceEndpoints     []ServiceEndpoint                    `json:"serviceEndpoints,omitempty"`
+	PodDisruptionBudgets []policy_v1beta1.PodDisruptionBudget `json:"podDisruptionBudgets,omitempty"`
+	NetworkPolicies      []network_v1.NetworkPolicy           `json:"networkPolicies,omitempty"`
+	ServiceAccounts      []core_v1.ServiceAccount             `json:"serviceAccounts,omitempty"`
+	Roles                []rbac_v1.Role                       `json:"roles,omitempty"`
+	RoleBindings         []rbac_v1.RoleBinding                `json:"roleBindings,omitempty"`
+	ClusterRoles         []rbac_v1.ClusterRole                `json:"clusterRoles,omitempty"`
+	ClusterRoleBindings  []rbac_v1.ClusterRoleBinding         `json:"clusterRoleBindings,omitempty"`
+	//Access summarises permissions granted to service accounts of pods of group.
+	Access *Access `json:"access,omitempty"`
+	//CustomResources owning, selecting or routing to group, sorted by resource type and name.
+	CustomResources []unstructured.Unstructured `json:"customResources,omitempty"`
+	Gateways        []Gateway                   `json:"gateways,omitempty"`
+	HTTPRoutes      []HTTPRoute                 `json:"httpRoutes,omitempty"`
+	GRPCRoutes      []GRPCRoute                 `json:"grpcRoutes,omitempty"`
+	ReferenceGrants []ReferenceGrant            `json:"referenceGrants,omitempty"`
+	//RouteBackends are services of group referred by HTTPRoutes and GRPCRoutes.
+	RouteBackends []RouteBackend `json:"routeBackends,omitempty"`
+	//Nodes running pods of group. Images and volumes of their status are dropped.
+	Nodes []core_v1.Node `json:"nodes,omitempty"`
+	//Placement is spread of pods of group over nodes and zones.
+	Placement *Placement `json:"placement,omitempty"`
 }
 
 //MappedResources returns set of common labels consisting mapped k8s resources.
 type MappedResources struct {
 	MappedResource []MappedResource `json:"mappedResource,omitempty"`
+	Applications   []Application    `json:"applications,omitempty"`
 }
 
 // Mapper hold internal store and workqueue for mapping
 type Mapper struct {
-	queue workqueue.RateLimitingInterface
-	store cache.Store
-	log   Logger
+	queue      workqueue.RateLimitingInterface
+	store      cache.Store
+	log        Logger
+	options    MapOptions
+	projection *projection
+	redactor   *redactor
+	related    *relatedObjects
+	index      lookupIndex
 }
 
 //ResourceEvent ...
@@ -103,7 +187,15 @@ type ChildSet struct {
 
 //MapOptions allows to instantiate new Mapper with custom options
 type MapOptions struct {
-	Logging LoggingOptions
+	Logging    LoggingOptions
+	Snapshot   SnapshotOptions
+	Projection ProjectionOptions
+	Redaction  RedactionOptions
+	//Naming decides common label of mapped resources. Without it common label depends on order of events.
+	Naming   NamingStrategy
+	Grouping GroupingOptions
+	//CustomResources are rules to attach custom resources e.g. as loaded by LoadCustomResourceRules.
+	CustomResources []CustomResourceRule
 }
 
 //LoggingOptions ...
@@ -113,6 +205,47 @@ type LoggingOptions struct {
 	LogLevel string
 }
 
+//SnapshotOptions ...
+type SnapshotOptions struct {
+	//Format of snapshot written by Mapper viz 'json' or 'gzip'. Defaults to 'json'.
+	Format string
+}
+
+//ProjectionOptions sets which fields of k8s objects are dropped before they are stored.
+type ProjectionOptions struct {
+	//Preset viz 'full', 'standard' or 'minimal'. Defaults to 'full'.
+	Preset string
+	//Deny is list of additional field paths to drop e.g. 'spec.containers.env' or 'metadata.annotations[example.com/key]'.
+	Deny []string
+	//Allow is list of field paths which are kept even if Preset or Deny drops them.
+	Allow []string
+}
+
+//RedactionOptions sets how sensitive values are removed from everything Mapper returns and logs.
+//Redaction is enabled by default.
+type RedactionOptions struct {
+	//Disabled turns off redaction.
+	Disabled bool
+	//KeepEnvValues keeps literal env values of containers unless env name looks like a secret.
+	//By default all literal env values are redacted.
+	KeepEnvValues bool
+	//AnnotationPatterns are regular expressions of annotation keys to redact in addition to
+	//last applied configuration and keys matching SecretKeyPatterns.
+	AnnotationPatterns []string
+	//SecretKeyPatterns are regular expressions of env names, annotation keys, command line flags and
+	//'key=value' pairs to redact in addition to common ones like password, secret, token and api key.
+	SecretKeyPatterns []string
+	//Replacement for redacted values. Defaults to '[REDACTED]'.
+	Replacement string
+}
+
+//GroupingOptions enables grouping of mapped resources into applications by well known labels.
+type GroupingOptions struct {
+	//LabelKeys in order of preference e.g. 'app.kubernetes.io/part-of' or 'app.kubernetes.io/instance'.
+	//Grouping is disabled if empty.
+	LabelKeys []string
+}
+
 //Logger ...
 type Logger struct {
 	enabled bool
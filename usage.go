@@ -0,0 +1,284 @@
+package kubemap
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sort"
+	"strconv"
+	"strings"
+
+	apps_v1 "k8s.io/api/apps/v1"
+	core_v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"sigs.k8s.io/yaml"
+)
+
+//bytesPerGiB converts memory quantities to GiB priced by PriceTable.
+const bytesPerGiB = 1 << 30
+
+//PriceTable is price of requested resources per hour used to estimate cost of groups.
+type PriceTable struct {
+	Currency string `json:"currency,omitempty"`
+	//CPUCoreHour is price of one requested CPU core per hour.
+	CPUCoreHour float64 `json:"cpuCoreHour"`
+	//MemoryGiBHour is price of one requested GiB of memory per hour.
+	MemoryGiBHour float64 `json:"memoryGiBHour"`
+}
+
+//ResourceTotals are requests and limits summed over pods.
+type ResourceTotals struct {
+	Pods           int               `json:"pods"`
+	CPURequests    resource.Quantity `json:"cpuRequests"`
+	CPULimits      resource.Quantity `json:"cpuLimits"`
+	MemoryRequests resource.Quantity `json:"memoryRequests"`
+	MemoryLimits   resource.Quantity `json:"memoryLimits"`
+	//Cost is estimated cost per hour of requests. Zero if no price table is given.
+	Cost float64 `json:"cost,omitempty"`
+}
+
+//WorkloadUsage is resource usage of pods of a workload e.g. 'deployment/api'. Pods without owner are their own workload.
+type WorkloadUsage struct {
+	Workload string `json:"workload"`
+	ResourceTotals
+}
+
+//GroupUsage is resource usage of pods of a mapped resource split by workload.
+type GroupUsage struct {
+	Namespace   string `json:"namespace"`
+	CommonLabel string `json:"commonLabel"`
+	ResourceTotals
+	Workloads []WorkloadUsage `json:"workloads,omitempty"`
+}
+
+//NamespaceUsage is resource usage of all groups of a namespace.
+type NamespaceUsage struct {
+	Namespace string `json:"namespace"`
+	ResourceTotals
+}
+
+//UsageReport is resource usage of mapped resources per group, per namespace and in total.
+type UsageReport struct {
+	Currency   string           `json:"currency,omitempty"`
+	Groups     []GroupUsage     `json:"groups,omitempty"`
+	Namespaces []NamespaceUsage `json:"namespaces,omitempty"`
+	Total      ResourceTotals   `json:"total"`
+}
+
+//LoadPriceTable reads a price table from YAML or JSON e.g. 'cpuCoreHour: 0.03' and 'memoryGiBHour: 0.004'.
+func LoadPriceTable(r io.Reader) (PriceTable, error) {
+	var prices PriceTable
+
+	content, err := ioutil.ReadAll(r)
+	if err != nil {
+		return prices, fmt.Errorf("Cannot read price table - %v", err)
+	}
+
+	if err := yaml.UnmarshalStrict(content, &prices); err != nil {
+		return prices, fmt.Errorf("Cannot parse price table - %v", err)
+	}
+
+	if prices.CPUCoreHour < 0 || prices.MemoryGiBHour < 0 {
+		return prices, fmt.Errorf("Price table cannot have negative prices")
+	}
+
+	return prices, nil
+}
+
+//Usage aggregates resource usage of mapped resources in store. Prices are optional.
+func (m *Mapper) Usage(prices *PriceTable) UsageReport {
+	return AggregateUsage(getAllMappedResources(m.store), prices)
+}
+
+//AggregateUsage sums CPU and memory requests and limits of pods of mapped resources which are not terminated.
+//Requests of a pod are sum of its containers or largest of its init containers, whichever is greater, as scheduler
+//accounts them. Cost is estimated if prices are given.
+func AggregateUsage(mappedResources MappedResources, prices *PriceTable) UsageReport {
+	var report UsageReport
+	if prices != nil {
+		report.Currency = prices.Currency
+	}
+
+	namespaces := make(map[string]*NamespaceUsage)
+	for _, mappedResource := range mappedResources.MappedResource {
+		group := GroupUsage{Namespace: mappedResource.Namespace, CommonLabel: mappedResource.CommonLabel}
+		workloads := make(map[string]*WorkloadUsage)
+
+		for _, pod := range mappedResource.Kube.Pods {
+			if pod.Status.Phase == core_v1.PodSucceeded || pod.Status.Phase == core_v1.PodFailed {
+				continue
+			}
+
+			totals := podResourceTotals(pod)
+			workload := podWorkload(pod, mappedResource.Kube.ReplicaSets)
+			if workloads[workload] == nil {
+				workloads[workload] = &WorkloadUsage{Workload: workload}
+			}
+			workloads[workload].add(totals)
+			group.add(totals)
+		}
+
+		if group.Pods == 0 {
+			continue
+		}
+
+		for _, workload := range workloads {
+			workload.estimateCost(prices)
+			group.Workloads = append(group.Workloads, *workload)
+		}
+		sort.Slice(group.Workloads, func(i, j int) bool {
+			return group.Workloads[i].Workload < group.Workloads[j].Workload
+		})
+		group.estimateCost(prices)
+		report.Groups = append(report.Groups, group)
+
+		if namespaces[group.Namespace] == nil {
+			namespaces[group.Namespace] = &NamespaceUsage{Namespace: group.Namespace}
+		}
+		namespaces[group.Namespace].add(group.ResourceTotals)
+		report.Total.add(group.ResourceTotals)
+	}
+
+	sort.Slice(report.Groups, func(i, j int) bool {
+		if report.Groups[i].Namespace != report.Groups[j].Namespace {
+			return report.Groups[i].Namespace < report.Groups[j].Namespace
+		}
+		return report.Groups[i].CommonLabel < report.Groups[j].CommonLabel
+	})
+
+	for _, namespace := range namespaces {
+		namespace.estimateCost(prices)
+		report.Namespaces = append(report.Namespaces, *namespace)
+	}
+	sort.Slice(report.Namespaces, func(i, j int) bool {
+		return report.Namespaces[i].Namespace < report.Namespaces[j].Namespace
+	})
+	report.Total.estimateCost(prices)
+
+	return report
+}
+
+//WriteCSV writes a row for every workload and a 'total' row for every group. CPU is written in cores and
+//memory in bytes.
+func (r UsageReport) WriteCSV(w io.Writer) error {
+	writer := csv.NewWriter(w)
+
+	header := []string{"namespace", "common_label", "workload", "pods", "cpu_requests_cores", "cpu_limits_cores", "memory_requests_bytes", "memory_limits_bytes", "cost_per_hour"}
+	if err := writer.Write(header); err != nil {
+		return fmt.Errorf("Cannot write usage - %v", err)
+	}
+
+	for _, group := range r.Groups {
+		for _, workload := range group.Workloads {
+			if err := writer.Write(usageRecord(group.Namespace, group.CommonLabel, workload.Workload, workload.ResourceTotals)); err != nil {
+				return fmt.Errorf("Cannot write usage - %v", err)
+			}
+		}
+		if err := writer.Write(usageRecord(group.Namespace, group.CommonLabel, "total", group.ResourceTotals)); err != nil {
+			return fmt.Errorf("Cannot write usage - %v", err)
+		}
+	}
+
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return fmt.Errorf("Cannot write usage - %v", err)
+	}
+
+	return nil
+}
+
+func usageRecord(namespace, commonLabel, workload string, totals ResourceTotals) []string {
+	return []string{
+		namespace,
+		commonLabel,
+		workload,
+		strconv.Itoa(totals.Pods),
+		formatCores(totals.CPURequests),
+		formatCores(totals.CPULimits),
+		strconv.FormatInt(totals.MemoryRequests.Value(), 10),
+		strconv.FormatInt(totals.MemoryLimits.Value(), 10),
+		strconv.FormatFloat(totals.Cost, 'f', 4, 64),
+	}
+}
+
+func formatCores(quantity resource.Quantity) string {
+	return strconv.FormatFloat(float64(quantity.MilliValue())/1000, 'f', -1, 64)
+}
+
+func (t *ResourceTotals) add(other ResourceTotals) {
+	t.Pods += other.Pods
+	t.CPURequests.Add(other.CPURequests)
+	t.CPULimits.Add(other.CPULimits)
+	t.MemoryRequests.Add(other.MemoryRequests)
+	t.MemoryLimits.Add(other.MemoryLimits)
+}
+
+func (t *ResourceTotals) estimateCost(prices *PriceTable) {
+	if prices == nil {
+		return
+	}
+
+	cores := float64(t.CPURequests.MilliValue()) / 1000
+	gibs := float64(t.MemoryRequests.Value()) / bytesPerGiB
+	t.Cost = cores*prices.CPUCoreHour + gibs*prices.MemoryGiBHour
+}
+
+//podResourceTotals returns effective requests and limits of pod.
+func podResourceTotals(pod core_v1.Pod) ResourceTotals {
+	totals := ResourceTotals{Pods: 1}
+
+	for _, container := range pod.Spec.Containers {
+		addResourceList(&totals.CPURequests, &totals.MemoryRequests, container.Resources.Requests)
+		addResourceList(&totals.CPULimits, &totals.MemoryLimits, container.Resources.Limits)
+	}
+
+	//Init containers run one at a time before containers. Largest of them is reserved if it exceeds containers.
+	for _, container := range pod.Spec.InitContainers {
+		maxResourceList(&totals.CPURequests, &totals.MemoryRequests, container.Resources.Requests)
+		maxResourceList(&totals.CPULimits, &totals.MemoryLimits, container.Resources.Limits)
+	}
+
+	return totals
+}
+
+func addResourceList(cpu, memory *resource.Quantity, list core_v1.ResourceList) {
+	if quantity, ok := list[core_v1.ResourceCPU]; ok {
+		cpu.Add(quantity)
+	}
+	if quantity, ok := list[core_v1.ResourceMemory]; ok {
+		memory.Add(quantity)
+	}
+}
+
+func maxResourceList(cpu, memory *resource.Quantity, list core_v1.ResourceList) {
+	if quantity, ok := list[core_v1.ResourceCPU]; ok && quantity.Cmp(*cpu) > 0 {
+		*cpu = quantity.DeepCopy()
+	}
+	if quantity, ok := list[core_v1.ResourceMemory]; ok && quantity.Cmp(*memory) > 0 {
+		*memory = quantity.DeepCopy()
+	}
+}
+
+//podWorkload returns workload owning pod e.g. 'deployment/api' for pods of a ReplicaSet of a Deployment.
+func podWorkload(pod core_v1.Pod, replicaSets []apps_v1.ReplicaSet) string {
+	for _, ownerReference := range pod.OwnerReferences {
+		if ownerReference.Controller != nil && !*ownerReference.Controller {
+			continue
+		}
+
+		if ownerReference.Kind == "ReplicaSet" {
+			for _, replicaSet := range replicaSets {
+				if replicaSet.Name == ownerReference.Name {
+					if deployment := controllerName(replicaSet.ObjectMeta, "Deployment"); deployment != "" {
+						return "deployment/" + deployment
+					}
+				}
+			}
+		}
+
+		return strings.ToLower(ownerReference.Kind) + "/" + ownerReference.Name
+	}
+
+	return "pod/" + pod.Name
+}
@@ -0,0 +1,140 @@
+package kubemap
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	apps_v1 "k8s.io/api/apps/v1"
+	core_v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestAggregateUsage(t *testing.T) {
+	mappedResources := helperGetUsageMappedResources()
+	report := AggregateUsage(mappedResources, &PriceTable{Currency: "USD", CPUCoreHour: 0.04, MemoryGiBHour: 0.005})
+
+	assert.Equal(t, "USD", report.Currency)
+	assert.Len(t, report.Groups, 2)
+
+	api := report.Groups[1]
+	assert.Equal(t, "api", api.CommonLabel)
+	assert.Equal(t, 3, api.Pods)
+	assert.Equal(t, "1250m", api.CPURequests.String())
+	assert.Equal(t, "2500m", api.CPULimits.String())
+	assert.Equal(t, "1536Mi", api.MemoryRequests.String())
+	assert.InDelta(t, 1.25*0.04+1.5*0.005, api.Cost, 1e-9)
+
+	assert.Len(t, api.Workloads, 2)
+	assert.Equal(t, "deployment/api", api.Workloads[0].Workload)
+	assert.Equal(t, 2, api.Workloads[0].Pods)
+	assert.Equal(t, "1", api.Workloads[0].CPURequests.String())
+	assert.Equal(t, "pod/debug", api.Workloads[1].Workload)
+	//Init container requests more memory than containers of pod.
+	assert.Equal(t, "512Mi", api.Workloads[1].MemoryRequests.String())
+
+	assert.Equal(t, "worker", report.Groups[0].CommonLabel)
+	assert.Equal(t, "statefulset/worker", report.Groups[0].Workloads[0].Workload)
+
+	assert.Len(t, report.Namespaces, 2)
+	assert.Equal(t, "jobs", report.Namespaces[0].Namespace)
+	assert.Equal(t, "shop", report.Namespaces[1].Namespace)
+	assert.Equal(t, 4, report.Total.Pods)
+	assert.Equal(t, "1750m", report.Total.CPURequests.String())
+}
+
+func TestAggregateUsageWithoutPrices(t *testing.T) {
+	report := AggregateUsage(helperGetUsageMappedResources(), nil)
+
+	assert.Empty(t, report.Currency)
+	assert.Zero(t, report.Total.Cost)
+	assert.Zero(t, report.Groups[1].Cost)
+}
+
+func TestUsageOfMapper(t *testing.T) {
+	resources := helperGetK8sResources()
+	resources.Pods[0].Spec.Containers[0].Resources.Requests = core_v1.ResourceList{core_v1.ResourceCPU: resource.MustParse("100m")}
+
+	mapper := NewMapper()
+	_, err := mapper.Map(resources)
+	assert.Nil(t, err)
+
+	report := mapper.Usage(nil)
+	assert.Len(t, report.Groups, 1)
+	assert.Equal(t, "deployment/kube-map", report.Groups[0].Workloads[0].Workload)
+	assert.Equal(t, "100m", report.Groups[0].CPURequests.String())
+}
+
+func TestUsageCSV(t *testing.T) {
+	report := AggregateUsage(helperGetUsageMappedResources(), &PriceTable{CPUCoreHour: 0.04, MemoryGiBHour: 0.005})
+
+	var buffer bytes.Buffer
+	assert.Nil(t, report.WriteCSV(&buffer))
+	assert.Equal(t, strings.Join([]string{
+		"namespace,common_label,workload,pods,cpu_requests_cores,cpu_limits_cores,memory_requests_bytes,memory_limits_bytes,cost_per_hour",
+		"jobs,worker,statefulset/worker,1,0.5,0,0,0,0.0200",
+		"jobs,worker,total,1,0.5,0,0,0,0.0200",
+		"shop,api,deployment/api,2,1,2,1073741824,2147483648,0.0450",
+		"shop,api,pod/debug,1,0.25,0.5,536870912,0,0.0125",
+		"shop,api,total,3,1.25,2.5,1610612736,2147483648,0.0575",
+		"",
+	}, "\n"), buffer.String())
+}
+
+func TestLoadPriceTable(t *testing.T) {
+	prices, err := LoadPriceTable(strings.NewReader("currency: EUR\ncpuCoreHour: 0.03\nmemoryGiBHour: 0.004\n"))
+	assert.Nil(t, err)
+	assert.Equal(t, PriceTable{Currency: "EUR", CPUCoreHour: 0.03, MemoryGiBHour: 0.004}, prices)
+
+	for _, content := range []string{"cpu: 1", "cpuCoreHour: -1", "cpuCoreHour: [1]"} {
+		_, err := LoadPriceTable(strings.NewReader(content))
+		assert.NotNil(t, err, content)
+	}
+}
+
+//helperGetUsageMappedResources returns group 'api' with two pods of a deployment, a pod without owner whose init
+//container dominates and a completed pod, and group 'worker' with a pod of a statefulset.
+func helperGetUsageMappedResources() MappedResources {
+	controller := true
+	api := MappedResource{CommonLabel: "api", Namespace: "shop"}
+	api.Kube.ReplicaSets = []apps_v1.ReplicaSet{{ObjectMeta: meta_v1.ObjectMeta{
+		Name:            "api-1",
+		OwnerReferences: []meta_v1.OwnerReference{{Kind: "Deployment", Name: "api", Controller: &controller}},
+	}}}
+
+	for _, name := range []string{"api-1-a", "api-1-b"} {
+		api.Kube.Pods = append(api.Kube.Pods, helperUsagePod(name, []meta_v1.OwnerReference{{Kind: "ReplicaSet", Name: "api-1", Controller: &controller}},
+			core_v1.ResourceList{core_v1.ResourceCPU: resource.MustParse("500m"), core_v1.ResourceMemory: resource.MustParse("512Mi")},
+			core_v1.ResourceList{core_v1.ResourceCPU: resource.MustParse("1"), core_v1.ResourceMemory: resource.MustParse("1Gi")}))
+	}
+
+	debug := helperUsagePod("debug", nil,
+		core_v1.ResourceList{core_v1.ResourceCPU: resource.MustParse("250m"), core_v1.ResourceMemory: resource.MustParse("128Mi")},
+		core_v1.ResourceList{core_v1.ResourceCPU: resource.MustParse("500m")})
+	debug.Spec.InitContainers = []core_v1.Container{{Name: "init", Resources: core_v1.ResourceRequirements{
+		Requests: core_v1.ResourceList{core_v1.ResourceMemory: resource.MustParse("512Mi")},
+	}}}
+	completed := helperUsagePod("migrate", nil, core_v1.ResourceList{core_v1.ResourceCPU: resource.MustParse("4")}, nil)
+	completed.Status.Phase = core_v1.PodSucceeded
+	api.Kube.Pods = append(api.Kube.Pods, debug, completed)
+
+	worker := MappedResource{CommonLabel: "worker", Namespace: "jobs"}
+	worker.Kube.Pods = append(worker.Kube.Pods, helperUsagePod("worker-0", []meta_v1.OwnerReference{{Kind: "StatefulSet", Name: "worker", Controller: &controller}},
+		core_v1.ResourceList{core_v1.ResourceCPU: resource.MustParse("500m")}, nil))
+
+	return MappedResources{MappedResource: []MappedResource{api, worker, {CommonLabel: "empty", Namespace: "shop"}}}
+}
+
+func helperUsagePod(name string, ownerReferences []meta_v1.OwnerReference, requests, limits core_v1.ResourceList) core_v1.Pod {
+	return core_v1.Pod{
+		ObjectMeta: meta_v1.ObjectMeta{Name: name, OwnerReferences: ownerReferences},
+		Spec: core_v1.PodSpec{Containers: []core_v1.Container{{
+			Name:      "main",
+			Resources: core_v1.ResourceRequirements{Requests: requests, Limits: limits},
+		}}},
+		Status: core_v1.PodStatus{Phase: core_v1.PodRunning},
+	}
+}
@@ -9,11 +9,16 @@ import (
 	apps_v1beta1 "k8s.io/api/apps/v1beta1"
 	apps_v1beta2 "k8s.io/api/apps/v1beta2"
 	autoscaling_v1 "k8s.io/api/autoscaling/v1"
+	autoscaling_v2beta2 "k8s.io/api/autoscaling/v2beta2"
 	batch_v1 "k8s.io/api/batch/v1"
 	core_v1 "k8s.io/api/core/v1"
 	ext_v1beta1 "k8s.io/api/extensions/v1beta1"
+	network_v1 "k8s.io/api/networking/v1"
 	network_v1beta1 "k8s.io/api/networking/v1beta1"
+	policy_v1beta1 "k8s.io/api/policy/v1beta1"
+	rbac_v1 "k8s.io/api/rbac/v1"
 	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/client-go/tools/cache"
 )
 
@@ -50,6 +55,8 @@ func objectMetaData(obj interface{}) meta_v1.ObjectMeta {
 		return object.ObjectMeta
 	case *core_v1.Namespace:
 		return object.ObjectMeta
+	case *core_v1.Node:
+		return object.ObjectMeta
 	case *core_v1.Secret:
 		return object.ObjectMeta
 	case *ext_v1beta1.Ingress:
@@ -62,6 +69,44 @@ func objectMetaData(obj interface{}) meta_v1.ObjectMeta {
 		return object.ObjectMeta
 	case *autoscaling_v1.HorizontalPodAutoscaler:
 		return object.ObjectMeta
+	case *autoscaling_v2beta2.HorizontalPodAutoscaler:
+		return object.ObjectMeta
+	case *core_v1.Endpoints:
+		return object.ObjectMeta
+	case *policy_v1beta1.PodDisruptionBudget:
+		return object.ObjectMeta
+	case *network_v1.NetworkPolicy:
+		return object.ObjectMeta
+	case *core_v1.ServiceAccount:
+		return object.ObjectMeta
+	case *rbac_v1.Role:
+		return object.ObjectMeta
+	case *rbac_v1.RoleBinding:
+		return object.ObjectMeta
+	case *rbac_v1.ClusterRole:
+		return object.ObjectMeta
+	case *rbac_v1.ClusterRoleBinding:
+		return object.ObjectMeta
+	case *EndpointSlice:
+		return object.ObjectMeta
+	case *Gateway:
+		return object.ObjectMeta
+	case *HTTPRoute:
+		return object.ObjectMeta
+	case *GRPCRoute:
+		return object.ObjectMeta
+	case *ReferenceGrant:
+		return object.ObjectMeta
+	case *unstructured.Unstructured:
+		return meta_v1.ObjectMeta{
+			Name:            object.GetName(),
+			Namespace:       object.GetNamespace(),
+			UID:             object.GetUID(),
+			ResourceVersion: object.GetResourceVersion(),
+			Labels:          object.GetLabels(),
+			Annotations:     object.GetAnnotations(),
+			OwnerReferences: object.GetOwnerReferences(),
+		}
 	}
 	var objectMeta meta_v1.ObjectMeta
 	return objectMeta
@@ -113,8 +158,142 @@ func copyMappedResource(resource MappedResource) MappedResource {
 		copiedMappedResource.Kube.Pods = append(copiedMappedResource.Kube.Pods, *item.DeepCopy())
 	}
 
+	for _, item := range resource.Kube.Events {
+		copiedMappedResource.Kube.Events = append(copiedMappedResource.Kube.Events, *item.DeepCopy())
+	}
+
+	for _, item := range resource.Kube.HorizontalPodAutoscalers {
+		copiedMappedResource.Kube.HorizontalPodAutoscalers = append(copiedMappedResource.Kube.HorizontalPodAutoscalers, *item.DeepCopy())
+	}
+
+	for _, item := range resource.Kube.ConfigMaps {
+		copiedMappedResource.Kube.ConfigMaps = append(copiedMappedResource.Kube.ConfigMaps, *item.DeepCopy())
+	}
+
+	for _, item := range resource.Kube.Secrets {
+		copiedMappedResource.Kube.Secrets = append(copiedMappedResource.Kube.Secrets, *item.DeepCopy())
+	}
+
+	for _, item := range resource.Kube.PersistentVolumeClaims {
+		copiedMappedResource.Kube.PersistentVolumeClaims = append(copiedMappedResource.Kube.PersistentVolumeClaims, *item.DeepCopy())
+	}
+
+	for _, item := range resource.Kube.PersistentVolumes {
+		copiedMappedResource.Kube.PersistentVolumes = append(copiedMappedResource.Kube.PersistentVolumes, *item.DeepCopy())
+	}
+
+	for _, item := range resource.Kube.Endpoints {
+		copiedMappedResource.Kube.Endpoints = append(copiedMappedResource.Kube.Endpoints, *item.DeepCopy())
+	}
+
+	for _, item := range resource.Kube.EndpointSlices {
+		copiedMappedResource.Kube.EndpointSlices = append(copiedMappedResource.Kube.EndpointSlices, *item.DeepCopy())
+	}
+
+	for _, item := range resource.Kube.PodDisruptionBudgets {
+		copiedMappedResource.Kube.PodDisruptionBudgets = append(copiedMappedResource.Kube.PodDisruptionBudgets, *item.DeepCopy())
+	}
+
+	for _, item := range resource.Kube.NetworkPolicies {
+		copiedMappedResource.Kube.NetworkPolicies = append(copiedMappedResource.Kube.NetworkPolicies, *item.DeepCopy())
+	}
+
+	for _, item := range resource.Kube.ServiceAccounts {
+		copiedMappedResource.Kube.ServiceAccounts = append(copiedMappedResource.Kube.ServiceAccounts, *item.DeepCopy())
+	}
+
+	for _, item := range resource.Kube.Roles {
+		copiedMappedResource.Kube.Roles = append(copiedMappedResource.Kube.Roles, *item.DeepCopy())
+	}
+
+	for _, item := range resource.Kube.RoleBindings {
+		copiedMappedResource.Kube.RoleBindings = append(copiedMappedResource.Kube.RoleBindings, *item.DeepCopy())
+	}
+
+	for _, item := range resource.Kube.ClusterRoles {
+		copiedMappedResource.Kube.ClusterRoles = append(copiedMappedResource.Kube.ClusterRoles, *item.DeepCopy())
+	}
+
+	for _, item := range resource.Kube.ClusterRoleBindings {
+		copiedMappedResource.Kube.ClusterRoleBindings = append(copiedMappedResource.Kube.ClusterRoleBindings, *item.DeepCopy())
+	}
+
+	for _, item := range resource.Kube.CustomResources {
+		copiedMappedResource.Kube.CustomResources = append(copiedMappedResource.Kube.CustomResources, *item.DeepCopy())
+	}
+
+	for _, item := range resource.Kube.Gateways {
+		copiedMappedResource.Kube.Gateways = append(copiedMappedResource.Kube.Gateways, *item.DeepCopy())
+	}
+
+	for _, item := range resource.Kube.HTTPRoutes {
+		copiedMappedResource.Kube.HTTPRoutes = append(copiedMappedResource.Kube.HTTPRoutes, *item.DeepCopy())
+	}
+
+	for _, item := range resource.Kube.GRPCRoutes {
+		copiedMappedResource.Kube.GRPCRoutes = append(copiedMappedResource.Kube.GRPCRoutes, *item.DeepCopy())
+	}
+
+	for _, item := range resource.Kube.ReferenceGrants {
+		copiedMappedResource.Kube.ReferenceGrants = append(copiedMappedResource.Kube.ReferenceGrants, *item.DeepCopy())
+	}
+
+	copiedMappedResource.Kube.RouteBackends = append(copiedMappedResource.Kube.RouteBackends, resource.Kube.RouteBackends...)
+
+	for _, item := range resource.Kube.Nodes {
+		copiedMappedResource.Kube.Nodes = append(copiedMappedResource.Kube.Nodes, *item.DeepCopy())
+	}
+
+	if resource.Kube.Placement != nil {
+		placement := *resource.Kube.Placement
+		placement.Nodes = append([]NodePlacement(nil), placement.Nodes...)
+		placement.Zones = append([]ZonePlacement(nil), placement.Zones...)
+		copiedMappedResource.Kube.Placement = &placement
+	}
+
+	for _, item := range resource.Routes {
+		item.Pods = append([]RoutePod(nil), item.Pods...)
+		item.Problems = append([]string(nil), item.Problems...)
+		copiedMappedResource.Routes = append(copiedMappedResource.Routes, item)
+	}
+
+	for _, item := range resource.Rollouts {
+		history := item.History
+		item.History = nil
+		for _, revision := range history {
+			images := revision.Images
+			revision.Images = make(map[string]string)
+			for container, image := range images {
+				revision.Images[container] = image
+			}
+			item.History = append(item.History, revision)
+		}
+		copiedMappedResource.Rollouts = append(copiedMappedResource.Rollouts, item)
+	}
+
+	if resource.Kube.Access != nil {
+		access := *resource.Kube.Access
+		access.ServiceAccounts = append([]string(nil), access.ServiceAccounts...)
+		access.Permissions = nil
+		for _, permission := range resource.Kube.Access.Permissions {
+			permission.Verbs = append([]string(nil), permission.Verbs...)
+			permission.ResourceNames = append([]string(nil), permission.ResourceNames...)
+			access.Permissions = append(access.Permissions, permission)
+		}
+		copiedMappedResource.Kube.Access = &access
+	}
+
+	copiedMappedResource.Kube.ServiceEndpoints = append(copiedMappedResource.Kube.ServiceEndpoints, resource.Kube.ServiceEndpoints...)
+
+	for _, item := range resource.Kube.MissingReferences {
+		item.ReferencedBy = append([]string(nil), item.ReferencedBy...)
+		copiedMappedResource.Kube.MissingReferences = append(copiedMappedResource.Kube.MissingReferences, item)
+	}
+
 	copiedMappedResource.CommonLabel = resource.CommonLabel
 	copiedMappedResource.CurrentType = resource.CurrentType
+	copiedMappedResource.EventType = resource.EventType
+	copiedMappedResource.Application = resource.Application
 	copiedMappedResource.Namespace = resource.Namespace
 
 	return copiedMappedResource
@@ -245,8 +424,34 @@ func getObjectFromStore(key string, store cache.Store) (MappedResource, error) {
 	return MappedResource{}, fmt.Errorf("Object with key %s does not exist in store", key)
 }
 
+//finalizeMappedResource is called before a mapped resource with changed members is stored.
+//It attaches related objects and applies naming strategy, both of which depend on members.
+func (m *Mapper) finalizeMappedResource(mappedResource MappedResource) MappedResource {
+	mappedResource = m.attachRelatedObjects(mappedResource)
+	mappedResource.Routes = RoutingTable(mappedResource)
+	mappedResource.Rollouts = RolloutStatus(mappedResource)
+
+	if m.options.Naming != nil {
+		commonLabel := m.options.Naming.CommonLabel(mappedResource)
+		if commonLabel == "" {
+			commonLabel = defaultCommonLabel(mappedResource)
+		}
+		if commonLabel != "" {
+			mappedResource.CommonLabel = commonLabel
+		}
+	}
+
+	return mappedResource
+}
+
 func (m *Mapper) updateStore(results []MapResult, store cache.Store) error {
-	for _, result := range results {
+	for i := range results {
+		if results[i].IsMapped && !results[i].IsStoreUpdated && results[i].Action != "Deleted" {
+			//Members may have changed. Let mapper finalize what depends on them before storing.
+			results[i].MappedResource = m.finalizeMappedResource(results[i].MappedResource)
+		}
+
+		result := results[i]
 		if result.IsMapped && !result.IsStoreUpdated {
 			switch result.Action {
 			case "Added", "Updated":
@@ -261,14 +466,14 @@ func (m *Mapper) updateStore(results []MapResult, store cache.Store) error {
 					}
 
 					//Delete exiting resource from store
-					err = store.Delete(existingMappedResource)
+					err = m.deleteFromStore(existingMappedResource, store)
 					if err != nil {
 						m.warn(fmt.Sprintf("Error while deleting object from store - %v Key - %s", err, result.Key))
 						return err
 					}
 
 					//Add new mapped resource to store
-					err = store.Add(result.MappedResource)
+					err = m.addToStore(result.MappedResource, store)
 					if err != nil {
 						m.warn(fmt.Sprintf("Error while adding object from store - %v Key - %s", err, result.Key))
 						return err
@@ -285,7 +490,7 @@ func (m *Mapper) updateStore(results []MapResult, store cache.Store) error {
 						}
 
 						//Delete exiting resource from store
-						err = store.Delete(existingMappedResource)
+						err = m.deleteFromStore(existingMappedResource, store)
 						if err != nil {
 							m.warn(fmt.Sprintf("Error while deleting object from store - %v Key - %s", err, result.Key))
 							return err
@@ -293,7 +498,7 @@ func (m *Mapper) updateStore(results []MapResult, store cache.Store) error {
 					}
 
 					//Add new mapped resource to store
-					err := store.Add(result.MappedResource)
+					err := m.addToStore(result.MappedResource, store)
 					if err != nil {
 						m.warn(fmt.Sprintf("Error while adding object to store - %v Key - %s", err, result.Key))
 						return err
@@ -301,7 +506,7 @@ func (m *Mapper) updateStore(results []MapResult, store cache.Store) error {
 				} else {
 					//If key is not present then its new mapped resource.
 					//Add new individual mapped resource to store
-					err := store.Add(result.MappedResource)
+					err := m.addToStore(result.MappedResource, store)
 					if err != nil {
 						m.warn(fmt.Sprintf("Error while adding newly mapped object to store - %v Key - %s", err, result.Key))
 						return err
@@ -318,7 +523,7 @@ func (m *Mapper) updateStore(results []MapResult, store cache.Store) error {
 					}
 
 					//Delete existing resource from store
-					err = store.Delete(existingMappedResource)
+					err = m.deleteFromStore(existingMappedResource, store)
 					if err != nil {
 						m.warn(fmt.Sprintf("Error while deleting object from store - %v Key - %s", err, result.Key))
 						return err
@@ -331,3 +536,143 @@ func (m *Mapper) updateStore(results []MapResult, store cache.Store) error {
 	}
 	return nil
 }
+
+//addToStore adds mapped resource to store and lookup index.
+func (m *Mapper) addToStore(mappedResource MappedResource, store cache.Store) error {
+	if err := store.Add(mappedResource); err != nil {
+		return err
+	}
+
+	m.index.add(mappedResource)
+	return nil
+}
+
+//deleteFromStore deletes mapped resource from store and lookup index.
+func (m *Mapper) deleteFromStore(mappedResource MappedResource, store cache.Store) error {
+	if err := store.Delete(mappedResource); err != nil {
+		return err
+	}
+
+	m.index.remove(mappedResource)
+	return nil
+}
+
+//memberObject is kind and metadata of a resource belonging to a mapped resource.
+type memberObject struct {
+	Kind       string
+	ObjectMeta meta_v1.ObjectMeta
+}
+
+//memberObjects returns kind and metadata of every member of mapped resource.
+func memberObjects(mappedResource MappedResource) []memberObject {
+	var members []memberObject
+
+	for _, ingress := range mappedResource.Kube.Ingresses {
+		members = append(members, memberObject{Kind: "ingress", ObjectMeta: ingress.ObjectMeta})
+	}
+
+	for _, service := range mappedResource.Kube.Services {
+		members = append(members, memberObject{Kind: "service", ObjectMeta: service.ObjectMeta})
+	}
+
+	for _, deployment := range mappedResource.Kube.Deployments {
+		members = append(members, memberObject{Kind: "deployment", ObjectMeta: deployment.ObjectMeta})
+	}
+
+	for _, replicaSet := range mappedResource.Kube.ReplicaSets {
+		members = append(members, memberObject{Kind: "replicaset", ObjectMeta: replicaSet.ObjectMeta})
+	}
+
+	for _, pod := range mappedResource.Kube.Pods {
+		members = append(members, memberObject{Kind: "pod", ObjectMeta: pod.ObjectMeta})
+	}
+
+	for _, hpa := range mappedResource.Kube.HorizontalPodAutoscalers {
+		members = append(members, memberObject{Kind: "hpa", ObjectMeta: hpa.ObjectMeta})
+	}
+
+	for _, configMap := range mappedResource.Kube.ConfigMaps {
+		members = append(members, memberObject{Kind: "configmap", ObjectMeta: configMap.ObjectMeta})
+	}
+
+	for _, secret := range mappedResource.Kube.Secrets {
+		members = append(members, memberObject{Kind: "secret", ObjectMeta: secret.ObjectMeta})
+	}
+
+	for _, pvc := range mappedResource.Kube.PersistentVolumeClaims {
+		members = append(members, memberObject{Kind: "pvc", ObjectMeta: pvc.ObjectMeta})
+	}
+
+	for _, pv := range mappedResource.Kube.PersistentVolumes {
+		members = append(members, memberObject{Kind: "pv", ObjectMeta: pv.ObjectMeta})
+	}
+
+	for _, endpoints := range mappedResource.Kube.Endpoints {
+		members = append(members, memberObject{Kind: "endpoints", ObjectMeta: endpoints.ObjectMeta})
+	}
+
+	for _, endpointSlice := range mappedResource.Kube.EndpointSlices {
+		members = append(members, memberObject{Kind: "endpointslice", ObjectMeta: endpointSlice.ObjectMeta})
+	}
+
+	for _, pdb := range mappedResource.Kube.PodDisruptionBudgets {
+		members = append(members, memberObject{Kind: "pdb", ObjectMeta: pdb.ObjectMeta})
+	}
+
+	for _, networkPolicy := range mappedResource.Kube.NetworkPolicies {
+		members = append(members, memberObject{Kind: "networkpolicy", ObjectMeta: networkPolicy.ObjectMeta})
+	}
+
+	for _, serviceAccount := range mappedResource.Kube.ServiceAccounts {
+		members = append(members, memberObject{Kind: "serviceaccount", ObjectMeta: serviceAccount.ObjectMeta})
+	}
+
+	for _, role := range mappedResource.Kube.Roles {
+		members = append(members, memberObject{Kind: "role", ObjectMeta: role.ObjectMeta})
+	}
+
+	for _, roleBinding := range mappedResource.Kube.RoleBindings {
+		members = append(members, memberObject{Kind: "rolebinding", ObjectMeta: roleBinding.ObjectMeta})
+	}
+
+	for _, clusterRole := range mappedResource.Kube.ClusterRoles {
+		members = append(members, memberObject{Kind: "clusterrole", ObjectMeta: clusterRole.ObjectMeta})
+	}
+
+	for _, clusterRoleBinding := range mappedResource.Kube.ClusterRoleBindings {
+		members = append(members, memberObject{Kind: "clusterrolebinding", ObjectMeta: clusterRoleBinding.ObjectMeta})
+	}
+
+	for _, gateway := range mappedResource.Kube.Gateways {
+		members = append(members, memberObject{Kind: "gateway", ObjectMeta: gateway.ObjectMeta})
+	}
+
+	for _, httpRoute := range mappedResource.Kube.HTTPRoutes {
+		members = append(members, memberObject{Kind: "httproute", ObjectMeta: httpRoute.ObjectMeta})
+	}
+
+	for _, grpcRoute := range mappedResource.Kube.GRPCRoutes {
+		members = append(members, memberObject{Kind: "grpcroute", ObjectMeta: grpcRoute.ObjectMeta})
+	}
+
+	for _, referenceGrant := range mappedResource.Kube.ReferenceGrants {
+		members = append(members, memberObject{Kind: "referencegrant", ObjectMeta: referenceGrant.ObjectMeta})
+	}
+
+	for _, node := range mappedResource.Kube.Nodes {
+		members = append(members, memberObject{Kind: "node", ObjectMeta: node.ObjectMeta})
+	}
+
+	for i := range mappedResource.Kube.CustomResources {
+		customResource := &mappedResource.Kube.CustomResources[i]
+		members = append(members, memberObject{Kind: customResourceTypeOf(customResource), ObjectMeta: objectMetaData(customResource)})
+	}
+
+	return members
+}
+
+//copyThroughJSON deep copies in to out of same type through JSON form, for types which have no generated DeepCopy.
+func copyThroughJSON(in, out interface{}) {
+	content, _ := json.Marshal(in)
+	json.Unmarshal(content, out)
+}